@@ -3,8 +3,10 @@ package api
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
 	"time"
@@ -48,6 +50,15 @@ type Backend struct {
 
 	// Credentials to apply for backend requests.
 	credentialProvider CredentialProvider
+
+	// TLS configuration for connections to the backend, or nil.
+	tlsClientConfig *tls.Config
+
+	// Makes connections to the backend, or nil for the default.
+	dialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// Counts the credential provider's token requests, or nil.
+	tokenExchangeStats *TokenExchangeStats
 }
 
 // An HTTP client for interacting with the W&B backend.
@@ -139,6 +150,25 @@ type BackendOptions struct {
 
 	// Credentials to apply on every request.
 	CredentialProvider CredentialProvider
+
+	// TLS configuration for all clients, like a client certificate for
+	// servers behind an mTLS gateway.
+	//
+	// If nil, Go's defaults are used.
+	TLSClientConfig *tls.Config
+
+	// Makes connections for all clients, like one from NewDialContext.
+	//
+	// Clients still negotiate HTTP/2 when this is set.
+	//
+	// If nil, Go's default dialer is used.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// Counts the requests CredentialProvider makes to the token endpoint,
+	// if it was given to the provider.
+	//
+	// If nil, the requests are not counted.
+	TokenExchangeStats *TokenExchangeStats
 }
 
 // Creates a [Backend].
@@ -150,9 +180,21 @@ func New(opts BackendOptions) *Backend {
 		baseURL:            opts.BaseURL,
 		logger:             opts.Logger,
 		credentialProvider: opts.CredentialProvider,
+		tlsClientConfig:    opts.TLSClientConfig,
+		dialContext:        opts.DialContext,
+		tokenExchangeStats: opts.TokenExchangeStats,
 	}
 }
 
+// TokenExchangeStats returns the counts of the credential provider's token
+// requests, or nil if they're not counted or the backend is nil.
+func (backend *Backend) TokenExchangeStats() *TokenExchangeStats {
+	if backend == nil {
+		return nil
+	}
+	return backend.tokenExchangeStats
+}
+
 type ClientOptions struct {
 	// Maximum number of retries to make for retryable requests.
 	RetryMax int
@@ -230,8 +272,14 @@ func (backend *Backend) NewClient(opts ClientOptions) Client {
 	}
 
 	// Set the Proxy function on the HTTP client.
+	//
+	// Setting TLSClientConfig or DialContext turns off HTTP/2 unless it's
+	// forced.
 	transport := &http.Transport{
-		Proxy: opts.Proxy,
+		Proxy:             opts.Proxy,
+		TLSClientConfig:   backend.tlsClientConfig,
+		DialContext:       backend.dialContext,
+		ForceAttemptHTTP2: true,
 	}
 	// Set the "Proxy-Authorization" header for the CONNECT requests
 	// to the proxy server if the header is present in the extra headers.
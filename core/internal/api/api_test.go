@@ -24,7 +24,7 @@ func TestSend(t *testing.T) {
 	server := NewRecordingServer()
 	settings := wbsettings.From(&spb.Settings{
 		BaseUrl: &wrapperspb.StringValue{Value: server.URL + "/wandb"},
-		ApiKey:  &wrapperspb.StringValue{Value: "test_api_key"},
+		ApiKey:  &wrapperspb.StringValue{Value: testAPIKey},
 	})
 
 	{
@@ -57,7 +57,7 @@ func TestSend(t *testing.T) {
 	assert.Equal(t, "two", req.Header.Get("Header2"))
 	assert.Equal(t, "xyz", req.Header.Get("ClientHeader"))
 	assert.Equal(t, "wandb-core", req.Header.Get("User-Agent"))
-	assert.Equal(t, "Basic YXBpOnRlc3RfYXBpX2tleQ==",
+	assert.Equal(t, testAPIKeyAuthorization,
 		req.Header.Get("Authorization"))
 }
 
@@ -65,7 +65,7 @@ func TestDo_ToWandb_SetsAuth(t *testing.T) {
 	server := NewRecordingServer()
 	settings := wbsettings.From(&spb.Settings{
 		BaseUrl: &wrapperspb.StringValue{Value: server.URL + "/wandb"},
-		ApiKey:  &wrapperspb.StringValue{Value: "test_api_key"},
+		ApiKey:  &wrapperspb.StringValue{Value: testAPIKey},
 	})
 
 	{
@@ -86,11 +86,32 @@ func TestDo_ToWandb_SetsAuth(t *testing.T) {
 	assert.NotEmpty(t, server.Requests()[0].Header.Get("Authorization"))
 }
 
+func TestDo_ToWandb_SetsExtraHeadersWithAuth(t *testing.T) {
+	server := NewRecordingServer()
+	defer server.Close()
+	settings := wbsettings.From(&spb.Settings{
+		BaseUrl: &wrapperspb.StringValue{Value: server.URL + "/wandb"},
+		ApiKey:  &wrapperspb.StringValue{Value: testAPIKey},
+	})
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/wandb/xyz", nil)
+	require.NoError(t, err)
+
+	_, err = newClient(t, settings, api.ClientOptions{
+		ExtraHeaders: map[string]string{"X-Tenant-ID": "acme"},
+	}).Do(req)
+
+	require.NoError(t, err)
+	require.Len(t, server.Requests(), 1)
+	assert.Equal(t, testAPIKeyAuthorization,
+		server.Requests()[0].Header.Get("Authorization"))
+	assert.Equal(t, "acme", server.Requests()[0].Header.Get("X-Tenant-ID"))
+}
+
 func TestDo_NotToWandb_NoAuth(t *testing.T) {
 	server := NewRecordingServer()
 	clientSettings := wbsettings.From(&spb.Settings{
 		BaseUrl: &wrapperspb.StringValue{Value: server.URL + "/wandb"},
-		ApiKey:  &wrapperspb.StringValue{Value: "test_api_key"},
+		ApiKey:  &wrapperspb.StringValue{Value: testAPIKey},
 	})
 
 	{
@@ -119,7 +140,7 @@ func newClient(
 	baseURL, err := url.Parse(settings.GetBaseURL())
 	require.NoError(t, err)
 
-	credentialProvider, err := api.NewCredentialProvider(settings)
+	credentialProvider, err := api.NewCredentialProvider(settings, api.CredentialProviderOptions{})
 	require.NoError(t, err)
 
 	backend := api.New(api.BackendOptions{BaseURL: baseURL,
@@ -189,8 +210,8 @@ func TestNewClientWithProxy(t *testing.T) {
 	proxyParsedURL, _ := url.Parse(testServer.URL)
 
 	credentialProvider, err := api.NewCredentialProvider(wbsettings.From(&spb.Settings{
-		ApiKey: &wrapperspb.StringValue{Value: "test_api_key"},
-	}))
+		ApiKey: &wrapperspb.StringValue{Value: testAPIKey},
+	}), api.CredentialProviderOptions{})
 	require.NoError(t, err)
 
 	backend := api.New(api.BackendOptions{
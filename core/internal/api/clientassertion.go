@@ -0,0 +1,200 @@
+package api
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	// clientAssertionType is the client_assertion_type of the
+	// private_key_jwt token endpoint authentication method.
+	clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+	// clientAssertionLifetime is how long a client assertion is valid.
+	//
+	// A new assertion is signed for every request, so it only needs to
+	// outlive the request.
+	clientAssertionLifetime = time.Minute
+)
+
+// ClientAssertionSigner authenticates the client to the token endpoint
+// with the private_key_jwt method, for OIDC providers that require client
+// authentication in addition to the identity token.
+//
+// It signs a short-lived JWT, the client assertion, for every request to
+// the token endpoint. RSA keys sign with RS256 and P-256 ECDSA keys with
+// ES256.
+type ClientAssertionSigner struct {
+	// The client ID, used as the assertion's issuer and subject.
+	clientID string
+
+	// The ID of the signing key registered with the provider, sent as the
+	// "kid" header if not empty.
+	keyID string
+
+	// The signing key and the JWS algorithm it signs with.
+	key       crypto.Signer
+	algorithm string
+}
+
+// NewClientAssertionSigner returns a signer for the client that signs
+// with the PEM-encoded private key.
+//
+// The key may be in PKCS #8, PKCS #1 or SEC 1 form.
+func NewClientAssertionSigner(
+	clientID string,
+	keyID string,
+	keyPEM []byte,
+) (*ClientAssertionSigner, error) {
+	if clientID == "" {
+		return nil, errors.New("client assertion requires a client ID")
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("no PEM data found in client assertion key")
+	}
+
+	key, err := parsePrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client assertion key: %v", err)
+	}
+
+	signer := &ClientAssertionSigner{clientID: clientID, keyID: keyID}
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		signer.key, signer.algorithm = key, "RS256"
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf(
+				"unsupported client assertion key curve %s, expected P-256",
+				key.Curve.Params().Name)
+		}
+		signer.key, signer.algorithm = key, "ES256"
+	default:
+		return nil, fmt.Errorf("unsupported client assertion key type %T", key)
+	}
+
+	return signer, nil
+}
+
+// LoadClientAssertionSigner is NewClientAssertionSigner with the key read
+// from a file.
+func LoadClientAssertionSigner(
+	clientID string,
+	keyID string,
+	keyFile string,
+) (*ClientAssertionSigner, error) {
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client assertion key: %v", err)
+	}
+	return NewClientAssertionSigner(clientID, keyID, keyPEM)
+}
+
+// parsePrivateKey parses a DER-encoded private key in any supported form.
+func parsePrivateKey(der []byte) (any, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("not a PKCS #8, PKCS #1 or SEC 1 private key")
+}
+
+// Sign returns a client assertion for the token endpoint at audience,
+// valid from now for clientAssertionLifetime.
+func (s *ClientAssertionSigner) Sign(audience string, now time.Time) (string, error) {
+	header := map[string]string{"alg": s.algorithm, "typ": "JWT"}
+	if s.keyID != "" {
+		header["kid"] = s.keyID
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	claims := map[string]any{
+		"iss": s.clientID,
+		"sub": s.clientID,
+		"aud": audience,
+		"jti": hex.EncodeToString(jti),
+		"iat": now.Unix(),
+		"exp": now.Add(clientAssertionLifetime).Unix(),
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) +
+		"." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	signature, err := s.sign([]byte(signingInput))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign client assertion: %v", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// sign signs the JWS signing input with the key's algorithm.
+func (s *ClientAssertionSigner) sign(input []byte) ([]byte, error) {
+	digest := sha256.Sum256(input)
+
+	switch key := s.key.(type) {
+	case *ecdsa.PrivateKey:
+		// JWS uses the fixed-size concatenation of r and s rather than
+		// the ASN.1 form.
+		r, sig, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		sig.FillBytes(signature[32:])
+		return signature, nil
+	default:
+		return s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+}
+
+// addTo adds a client assertion for the token endpoint at tokenURL to a
+// token request's form.
+func (s *ClientAssertionSigner) addTo(
+	form url.Values,
+	tokenURL string,
+	now time.Time,
+) error {
+	assertion, err := s.Sign(tokenURL, now)
+	if err != nil {
+		return err
+	}
+
+	form.Set("client_id", s.clientID)
+	form.Set("client_assertion_type", clientAssertionType)
+	form.Set("client_assertion", assertion)
+	return nil
+}
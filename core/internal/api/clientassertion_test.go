@@ -0,0 +1,223 @@
+package api_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wandb/wandb/core/internal/api"
+	wbsettings "github.com/wandb/wandb/core/internal/settings"
+	spb "github.com/wandb/wandb/core/pkg/service_go_proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// pemEncodeKey PEM-encodes a private key in PKCS #8 form.
+func pemEncodeKey(t *testing.T, key any) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+// decodeJWT splits a JWT into its decoded header and claims, and returns
+// the signing input and the signature.
+func decodeJWT(t *testing.T, token string) (
+	header map[string]any,
+	claims map[string]any,
+	signingInput string,
+	signature []byte,
+) {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(headerJSON, &header))
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(claimsJSON, &claims))
+	signature, err = base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+
+	return header, claims, parts[0] + "." + parts[1], signature
+}
+
+// exchangeWithSigner obtains an access token using the signer and returns
+// the token request's form.
+func exchangeWithSigner(t *testing.T, signer *api.ClientAssertionSigner) (url.Values, string) {
+	t.Helper()
+	server := api.NewTokenServer(t)
+	credentialProvider, err := api.NewOAuth2CredentialProvider(
+		api.OAuth2CredentialProviderOptions{
+			BaseURL:             server.URL,
+			IdentityTokenFile:   writeIdentityToken(t, "jwt"),
+			InMemoryCredentials: true,
+			ClientAssertion:     signer,
+		},
+	)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	require.NoError(t, err)
+	require.NoError(t, credentialProvider.Apply(req))
+
+	requests := server.Requests()
+	require.Len(t, requests, 1)
+	return requests[0].Form, server.URL + "/oidc/token"
+}
+
+func TestClientAssertion_RSA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signer, err := api.NewClientAssertionSigner("my-client", "key-1", pemEncodeKey(t, key))
+	require.NoError(t, err)
+
+	form, tokenURL := exchangeWithSigner(t, signer)
+
+	assert.Equal(t, "jwt", form.Get("assertion"))
+	assert.Equal(t, "my-client", form.Get("client_id"))
+	assert.Equal(t,
+		"urn:ietf:params:oauth:client-assertion-type:jwt-bearer",
+		form.Get("client_assertion_type"))
+
+	header, claims, signingInput, signature := decodeJWT(t, form.Get("client_assertion"))
+	assert.Equal(t, map[string]any{"alg": "RS256", "typ": "JWT", "kid": "key-1"}, header)
+	assert.Equal(t, "my-client", claims["iss"])
+	assert.Equal(t, "my-client", claims["sub"])
+	assert.Equal(t, tokenURL, claims["aud"])
+	assert.NotEmpty(t, claims["jti"])
+	assert.InDelta(t, time.Now().Add(time.Minute).Unix(), claims["exp"], 5)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	assert.NoError(t,
+		rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+}
+
+func TestClientAssertion_ECDSA(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer, err := api.NewClientAssertionSigner("my-client", "", pemEncodeKey(t, key))
+	require.NoError(t, err)
+
+	form, _ := exchangeWithSigner(t, signer)
+
+	header, _, signingInput, signature := decodeJWT(t, form.Get("client_assertion"))
+	assert.Equal(t, map[string]any{"alg": "ES256", "typ": "JWT"}, header)
+	require.Len(t, signature, 64)
+	digest := sha256.Sum256([]byte(signingInput))
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	assert.True(t, ecdsa.Verify(&key.PublicKey, digest[:], r, s))
+}
+
+func TestClientAssertion_SignedForEveryAttempt(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer, err := api.NewClientAssertionSigner("my-client", "", pemEncodeKey(t, key))
+	require.NoError(t, err)
+
+	// The first request is rate limited.
+	var assertions []string
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.NoError(t, r.ParseForm())
+			assertions = append(assertions, r.PostForm.Get("client_assertion"))
+			if len(assertions) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token": "test-access-token", "expires_in": 3600}`))
+		}),
+	)
+	t.Cleanup(server.Close)
+	credentialProvider, err := api.NewOAuth2CredentialProvider(
+		api.OAuth2CredentialProviderOptions{
+			BaseURL:             server.URL,
+			IdentityTokenFile:   writeIdentityToken(t, "jwt"),
+			InMemoryCredentials: true,
+			ClientAssertion:     signer,
+		},
+	)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	require.NoError(t, err)
+	require.NoError(t, credentialProvider.Apply(req))
+
+	require.Len(t, assertions, 2)
+	_, first, _, _ := decodeJWT(t, assertions[0])
+	_, second, _, _ := decodeJWT(t, assertions[1])
+	assert.NotEqual(t, first["jti"], second["jti"])
+}
+
+func TestClientAssertion_NotSentByDefault(t *testing.T) {
+	form, _ := exchangeWithSigner(t, nil)
+
+	assert.Equal(t, "jwt", form.Get("assertion"))
+	assert.NotContains(t, form, "client_assertion")
+	assert.NotContains(t, form, "client_assertion_type")
+}
+
+func TestClientAssertion_FromSettings(t *testing.T) {
+	enableIdentityFederation(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "client.pem")
+	require.NoError(t, os.WriteFile(keyFile, pemEncodeKey(t, key), 0600))
+	server := api.NewTokenServer(t)
+	t.Setenv("WANDB_OIDC_CLIENT_ID", "my-client")
+	t.Setenv("WANDB_OIDC_CLIENT_ASSERTION_KEY_FILE", keyFile)
+	t.Setenv("WANDB_CREDENTIALS_IN_MEMORY", "true")
+	settings := wbsettings.From(&spb.Settings{
+		BaseUrl:           &wrapperspb.StringValue{Value: server.URL},
+		IdentityTokenFile: &wrapperspb.StringValue{Value: writeIdentityToken(t, "jwt")},
+	})
+	credentialProvider, err := api.NewCredentialProvider(settings, api.CredentialProviderOptions{})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	require.NoError(t, err)
+	require.NoError(t, credentialProvider.Apply(req))
+
+	requests := server.Requests()
+	require.Len(t, requests, 1)
+	form := requests[0].Form
+	assert.Equal(t, "my-client", form.Get("client_id"))
+	assert.NotEmpty(t, form.Get("client_assertion"))
+}
+
+func TestNewClientAssertionSigner_Invalid(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	_, err = api.NewClientAssertionSigner("", "", pemEncodeKey(t, rsaKey))
+	assert.ErrorContains(t, err, "requires a client ID")
+
+	_, err = api.NewClientAssertionSigner("my-client", "", []byte("not a key"))
+	assert.ErrorContains(t, err, "no PEM data")
+
+	_, err = api.NewClientAssertionSigner("my-client", "", pemEncodeKey(t, p384Key))
+	assert.ErrorContains(t, err, "expected P-256")
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+type TLSClientOptions struct {
+	// Path to the PEM-encoded client certificate to present to servers
+	// that request one, like an mTLS gateway in front of W&B.
+	CertFile string
+
+	// Path to the PEM-encoded private key of the client certificate.
+	KeyFile string
+
+	// Path to PEM-encoded CA certificates to trust in addition to the
+	// system's, for servers whose certificate is signed by a private CA.
+	CAFile string
+}
+
+// NewTLSClientConfig returns the TLS configuration for HTTP clients.
+//
+// Returns nil if no option is set, in which case Go's defaults apply.
+// The client certificate is reloaded when its files change, so that it
+// can be rotated without restarting the process.
+func NewTLSClientConfig(opts TLSClientOptions) (*tls.Config, error) {
+	if opts.CertFile == "" && opts.KeyFile == "" && opts.CAFile == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := NewClientCertificate(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = cert.GetClientCertificate
+	}
+
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %v", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", opts.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
+
+// ClientCertificate is a client certificate loaded from files.
+//
+// The files are checked for changes on every TLS handshake, and the
+// certificate is reloaded if they were modified.
+type ClientCertificate struct {
+	// Paths to the certificate and its private key.
+	certFile, keyFile string
+
+	// Protects the fields below.
+	mu sync.Mutex
+
+	// The certificate that was last loaded successfully.
+	cert *tls.Certificate
+
+	// The modification times of the files when they were last loaded.
+	certModTime, keyModTime time.Time
+}
+
+// NewClientCertificate loads a client certificate and its private key.
+func NewClientCertificate(certFile, keyFile string) (*ClientCertificate, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf(
+			"both a client certificate and a key are required, got %q and %q",
+			certFile, keyFile,
+		)
+	}
+
+	c := &ClientCertificate{certFile: certFile, keyFile: keyFile}
+	if err := c.reloadIfModified(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetClientCertificate returns the current certificate.
+//
+// It can be used as tls.Config.GetClientCertificate. If the files were
+// modified but can't be loaded, for example because the certificate was
+// rewritten before its key, the previous certificate is returned.
+func (c *ClientCertificate) GetClientCertificate(
+	_ *tls.CertificateRequestInfo,
+) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_ = c.reloadIfModified()
+	return c.cert, nil
+}
+
+// reloadIfModified loads the certificate if either file changed since it
+// was last loaded.
+//
+// The caller must hold mu, unless c is not yet shared.
+func (c *ClientCertificate) reloadIfModified() error {
+	certInfo, err := os.Stat(c.certFile)
+	if err != nil {
+		return fmt.Errorf("failed to read client certificate: %v", err)
+	}
+	keyInfo, err := os.Stat(c.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read client key: %v", err)
+	}
+
+	if c.cert != nil &&
+		certInfo.ModTime().Equal(c.certModTime) &&
+		keyInfo.ModTime().Equal(c.keyModTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load client certificate: %v", err)
+	}
+
+	c.cert = &cert
+	c.certModTime = certInfo.ModTime()
+	c.keyModTime = keyInfo.ModTime()
+	return nil
+}
@@ -0,0 +1,190 @@
+package api_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wandb/wandb/core/internal/api"
+)
+
+// testCA issues client certificates for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key}
+}
+
+// writeClientCert issues a client certificate with the common name and
+// writes it and its key to files in dir.
+func (ca *testCA) writeClientCert(
+	t *testing.T,
+	dir, commonName string,
+) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, "client.crt")
+	keyFile = filepath.Join(dir, "client.key")
+	require.NoError(t, os.WriteFile(certFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+// newMTLSServer starts a server that requires client certificates issued
+// by the CA, and writes the server's own certificate to a CA file.
+func newMTLSServer(t *testing.T, ca *testCA) (server *httptest.Server, caFile string) {
+	t.Helper()
+	server = httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+		}),
+	)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	caFile = filepath.Join(t.TempDir(), "ca.crt")
+	require.NoError(t, os.WriteFile(caFile,
+		pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: server.Certificate().Raw,
+		}), 0600))
+	return server, caFile
+}
+
+func newMTLSClient(t *testing.T, server *httptest.Server, tlsConfig *tls.Config) api.Client {
+	t.Helper()
+	baseURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	backend := api.New(api.BackendOptions{
+		BaseURL:            baseURL,
+		CredentialProvider: api.NewStaticAPIKeyCredentialProvider(testAPIKey),
+		TLSClientConfig:    tlsConfig,
+	})
+	return backend.NewClient(api.ClientOptions{})
+}
+
+func TestTLSClientConfig_PresentsClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	server, caFile := newMTLSServer(t, ca)
+	certFile, keyFile := ca.writeClientCert(t, t.TempDir(), "test-client")
+
+	tlsConfig, err := api.NewTLSClientConfig(api.TLSClientOptions{
+		CertFile: certFile,
+		KeyFile:  keyFile,
+		CAFile:   caFile,
+	})
+	require.NoError(t, err)
+	resp, err := newMTLSClient(t, server, tlsConfig).
+		Send(&api.Request{Method: http.MethodGet, Path: "/"})
+
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestTLSClientConfig_NoClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	server, caFile := newMTLSServer(t, ca)
+
+	tlsConfig, err := api.NewTLSClientConfig(api.TLSClientOptions{CAFile: caFile})
+	require.NoError(t, err)
+	_, err = newMTLSClient(t, server, tlsConfig).
+		Send(&api.Request{Method: http.MethodGet, Path: "/"})
+
+	assert.Error(t, err)
+}
+
+func TestTLSClientConfig_NoOptions(t *testing.T) {
+	tlsConfig, err := api.NewTLSClientConfig(api.TLSClientOptions{})
+
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+}
+
+func TestClientCertificate_ReloadsModifiedFiles(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := ca.writeClientCert(t, dir, "first")
+	cert, err := api.NewClientCertificate(certFile, keyFile)
+	require.NoError(t, err)
+
+	first, err := cert.GetClientCertificate(nil)
+	require.NoError(t, err)
+	ca.writeClientCert(t, dir, "second")
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+	second, err := cert.GetClientCertificate(nil)
+	require.NoError(t, err)
+
+	firstLeaf, err := x509.ParseCertificate(first.Certificate[0])
+	require.NoError(t, err)
+	secondLeaf, err := x509.ParseCertificate(second.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, "first", firstLeaf.Subject.CommonName)
+	assert.Equal(t, "second", secondLeaf.Subject.CommonName)
+}
+
+func TestClientCertificate_MissingKey(t *testing.T) {
+	_, err := api.NewClientCertificate("client.crt", "")
+
+	assert.ErrorContains(t, err, "both a client certificate and a key are required")
+}
@@ -1,11 +1,59 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/wandb/wandb/core/internal/settings"
+	wbsettings "github.com/wandb/wandb/core/internal/settings"
+)
+
+const (
+	// Access tokens are refreshed this long before they expire, give or
+	// take the refresh jitter.
+	tokenExpirationBuffer = 5 * time.Minute
+
+	// Default maximum random offset applied to tokenExpirationBuffer.
+	DefaultRefreshJitter = 60 * time.Second
+
+	// Timeout for the token exchange request if no HTTP client is provided.
+	DefaultTokenExchangeTimeout = 30 * time.Second
+
+	// Permissions of the credentials file unless configured otherwise.
+	DefaultCredentialsFileMode os.FileMode = 0600
+
+	// How many times reading or writing the credentials file is attempted
+	// before giving up on a transient error.
+	credentialsFileAttempts = 3
+
+	// The delay before the first retry of a credentials file operation,
+	// doubling for each subsequent one.
+	credentialsFileRetryDelay = 100 * time.Millisecond
+
+	// How many times a token request is attempted while the token endpoint
+	// responds 429 Too Many Requests with a Retry-After header.
+	tokenRateLimitAttempts = 3
+
+	// The longest a token request waits for a Retry-After delay.
+	maxTokenRetryAfter = 30 * time.Second
 )
 
 // CredentialProvider adds credentials to HTTP requests.
@@ -13,40 +61,466 @@ type CredentialProvider interface {
 	// Apply sets the appropriate authorization headers or parameters on the
 	// HTTP request.
 	Apply(req *http.Request) error
+
+	// Verify checks that the credentials can be used, so that problems are
+	// reported before the first request fails.
+	//
+	// It may contact the server. The context limits how long it takes.
+	Verify(ctx context.Context) error
+}
+
+// PersistentCredentialProvider is a CredentialProvider that stores the
+// credentials it obtains and may defer writing them.
+type PersistentCredentialProvider interface {
+	CredentialProvider
+
+	// Flush writes any credentials that haven't been stored yet.
+	Flush() error
+
+	// Close flushes the credentials. The provider may still be used
+	// afterward.
+	Close() error
 }
 
+// BatchCredentialProvider is a CredentialProvider that can authorize many
+// requests at once, more cheaply than applying it to each.
+type BatchCredentialProvider interface {
+	CredentialProvider
+
+	// ApplyAll sets the same credentials on every request.
+	ApplyAll(reqs []*http.Request) error
+}
+
+// ApplyAll applies the provider to every request.
+//
+// Providers that implement BatchCredentialProvider authorize the requests
+// in one step. Otherwise, the provider is applied to each request in turn,
+// stopping at the first error.
+func ApplyAll(provider CredentialProvider, reqs []*http.Request) error {
+	if batch, ok := provider.(BatchCredentialProvider); ok {
+		return batch.ApplyAll(reqs)
+	}
+
+	for _, req := range reqs {
+		if err := provider.Apply(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CredentialProviderOptions are the options for NewCredentialProvider
+// that don't come from the settings.
+type CredentialProviderOptions struct {
+	// The HTTP client for providers that need to talk to an auth server,
+	// like the OAuth2 provider.
+	//
+	// If nil, a default client is used.
+	HTTPClient *http.Client
+
+	// The logger for requests to the token endpoint.
+	//
+	// If nil, nothing is logged.
+	Logger *slog.Logger
+
+	// Counts the requests made to the token endpoint.
+	//
+	// If nil, the requests are not counted.
+	Stats *TokenExchangeStats
+}
+
+// NewCredentialProvider creates a credential provider based on the settings.
+//
+// If credentials files are disabled, the access token or API key in the
+// settings is used as is, and nothing else is considered.
+//
+// Otherwise, if identity federation is enabled and an identity token source, variable
+// or file is configured, the OAuth2 provider is tried first, falling back
+// to the API key if it can't be used. A source takes precedence over a
+// variable, and a variable over a file. If identity federation is disabled,
+// the default, an identity token file is an error.
+//
+// If extra credential headers are configured, the provider is wrapped in a
+// HeaderInjectingProvider.
 func NewCredentialProvider(
-	settings *settings.Settings,
+	settings *wbsettings.Settings,
+	opts CredentialProviderOptions,
+) (CredentialProvider, error) {
+	provider, err := newSettingsCredentialProvider(settings, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	headers, err := ParseHeaders(settings.GetCredentialHeaders())
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) == 0 {
+		return provider, nil
+	}
+
+	policy := HeaderConflictOverride
+	if settings.GetAppendCredentialHeaders() {
+		policy = HeaderConflictAppend
+	}
+	return NewHeaderInjectingProvider(provider, headers, policy), nil
+}
+
+// newSettingsCredentialProvider creates the provider that authorizes
+// requests based on the settings.
+func newSettingsCredentialProvider(
+	settings *wbsettings.Settings,
+	opts CredentialProviderOptions,
 ) (CredentialProvider, error) {
-	if settings.GetIdentityTokenFile() != "" {
-		return nil, fmt.Errorf("Identity federation via the wandb sdk " +
-			"is temporarily unavailable in wandb-core, or version 0.18.0 or " +
-			"later. Support for this feature will be reintroduced in an " +
-			"upcoming release. To continue using this feature, please " +
-			"downgrade to version 0.17.9 or lower using the following " +
-			"command: pip install wandb==0.17.9. Thank you for your patience.")
+	httpClient := opts.HTTPClient
+
+	if settings.GetDisableCredentialsFile() {
+		return newStaticCredentialProvider(settings, httpClient)
+	}
+
+	if !settings.IsIdentityFederationEnabled() {
+		if settings.GetIdentityTokenFile() != "" {
+			return nil, fmt.Errorf("Identity federation via the wandb sdk " +
+				"is temporarily unavailable in wandb-core, or version 0.18.0 or " +
+				"later. Support for this feature will be reintroduced in an " +
+				"upcoming release. To continue using this feature, please " +
+				"downgrade to version 0.17.9 or lower using the following " +
+				"command: pip install wandb==0.17.9. Thank you for your patience.")
+		}
+		return newAPIKeyCredentialProvider(settings, httpClient)
+	}
+
+	if source := settings.GetIdentityTokenSource(); source != "" {
+		return NewChainedCredentialProvider(
+			func() (CredentialProvider, error) {
+				clientAssertion, err := newClientAssertionSigner(settings)
+				if err != nil {
+					return nil, err
+				}
+				return NewMetadataTokenCredentialProvider(
+					MetadataTokenCredentialProviderOptions{
+						OAuth2CredentialProviderOptions: newOAuth2Options(
+							settings,
+							opts,
+							clientAssertion,
+						),
+						Source: source,
+					},
+				)
+			},
+			func() (CredentialProvider, error) {
+				return newAPIKeyCredentialProvider(settings, httpClient)
+			},
+		)
+	}
+	if settings.GetIdentityTokenFile() != "" || settings.GetIdentityTokenEnvVar() != "" {
+		return NewChainedCredentialProvider(
+			func() (CredentialProvider, error) {
+				clientAssertion, err := newClientAssertionSigner(settings)
+				if err != nil {
+					return nil, err
+				}
+				oauth2Opts := newOAuth2Options(settings, opts, clientAssertion)
+				oauth2Opts.IdentityTokenFile = settings.GetIdentityTokenFile()
+				oauth2Opts.IdentityTokenEnvVar = settings.GetIdentityTokenEnvVar()
+				return NewOAuth2CredentialProvider(oauth2Opts)
+			},
+			func() (CredentialProvider, error) {
+				return newAPIKeyCredentialProvider(settings, httpClient)
+			},
+		)
+	}
+	return newAPIKeyCredentialProvider(settings, httpClient)
+}
+
+// newOAuth2Options returns the OAuth2 provider options from the settings,
+// without an identity token source.
+func newOAuth2Options(
+	settings *wbsettings.Settings,
+	opts CredentialProviderOptions,
+	clientAssertion *ClientAssertionSigner,
+) OAuth2CredentialProviderOptions {
+	return OAuth2CredentialProviderOptions{
+		BaseURL:             settings.GetBaseURL(),
+		CredentialsFile:     settings.GetCredentialsFile(),
+		CredentialsFileMode: settings.GetCredentialsFileMode(),
+		InMemoryCredentials: settings.GetCredentialsInMemory(),
+		TokenEndpoint:       settings.GetOIDCTokenEndpoint(),
+		ExchangeAudience:    settings.GetOIDCAudience(),
+		ExchangeScope:       settings.GetOIDCScope(),
+		ClientAssertion:     clientAssertion,
+		ClockSkew:           settings.GetOIDCClockSkew(),
+		HTTPClient:          opts.HTTPClient,
+		InsecureSkipVerify:  settings.GetOIDCInsecureSkipVerify(),
+		Logger:              opts.Logger,
+		Stats:               opts.Stats,
+	}
+}
+
+// newStaticCredentialProvider creates a provider for the access token in
+// the settings, or for the API key if there is none, without reading any
+// files.
+func newStaticCredentialProvider(
+	settings *wbsettings.Settings,
+	httpClient *http.Client,
+) (CredentialProvider, error) {
+	if accessToken := settings.GetAccessToken(); accessToken != "" {
+		return NewStaticTokenCredentialProvider(
+			accessToken,
+			settings.GetAccessTokenExpiresAt(),
+		), nil
+	}
+
+	apiKey := settings.GetAPIKey()
+	if apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultNonRetryTimeout}
+	}
+
+	return &apiKeyCredentialProvider{
+		apiKey:     apiKey,
+		baseURL:    settings.GetBaseURL(),
+		httpClient: httpClient,
+	}, nil
+}
+
+// newClientAssertionSigner returns the client assertion signer configured
+// in the settings, or nil if the client doesn't authenticate to the token
+// endpoint.
+func newClientAssertionSigner(
+	settings *wbsettings.Settings,
+) (*ClientAssertionSigner, error) {
+	keyFile := settings.GetOIDCClientAssertionKeyFile()
+	if keyFile == "" {
+		return nil, nil
+	}
+
+	return LoadClientAssertionSigner(
+		settings.GetOIDCClientID(),
+		settings.GetOIDCClientAssertionKeyID(),
+		keyFile,
+	)
+}
+
+var _ BatchCredentialProvider = &ChainedCredentialProvider{}
+var _ PersistentCredentialProvider = &ChainedCredentialProvider{}
+
+// ChainedCredentialProvider tries a list of providers in order.
+//
+// Each request is authorized by the first provider that applies to it
+// successfully. Flush and Close are forwarded to every provider that
+// stores credentials.
+type ChainedCredentialProvider struct {
+	providers []CredentialProvider
+}
+
+// NewChainedCredentialProvider creates the providers returned by each
+// constructor, in order.
+//
+// Constructors that fail are skipped. It is an error if none succeed.
+func NewChainedCredentialProvider(
+	constructors ...func() (CredentialProvider, error),
+) (*ChainedCredentialProvider, error) {
+	var providers []CredentialProvider
+	var errs []error
+
+	for _, constructor := range constructors {
+		provider, err := constructor()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+
+	if len(providers) == 0 {
+		return nil, fmt.Errorf(
+			"no credential provider could be created: %v",
+			errors.Join(errs...),
+		)
+	}
+
+	return &ChainedCredentialProvider{providers: providers}, nil
+}
+
+// Apply applies the first provider that succeeds.
+func (c *ChainedCredentialProvider) Apply(req *http.Request) error {
+	var errs []error
+
+	for _, provider := range c.providers {
+		err := provider.Apply(req)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// ApplyAll applies the first provider that succeeds for all the requests.
+func (c *ChainedCredentialProvider) ApplyAll(reqs []*http.Request) error {
+	var errs []error
+
+	for _, provider := range c.providers {
+		err := ApplyAll(provider, reqs)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Verify succeeds if any of the providers can be verified.
+func (c *ChainedCredentialProvider) Verify(ctx context.Context) error {
+	var errs []error
+
+	for _, provider := range c.providers {
+		err := provider.Verify(ctx)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Flush flushes every provider that stores credentials.
+func (c *ChainedCredentialProvider) Flush() error {
+	var errs []error
+
+	for _, provider := range c.providers {
+		if persistent, ok := provider.(PersistentCredentialProvider); ok {
+			errs = append(errs, persistent.Flush())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close closes every provider that stores credentials.
+func (c *ChainedCredentialProvider) Close() error {
+	var errs []error
+
+	for _, provider := range c.providers {
+		if persistent, ok := provider.(PersistentCredentialProvider); ok {
+			errs = append(errs, persistent.Close())
+		}
 	}
-	return NewAPIKeyCredentialProvider(settings)
+
+	return errors.Join(errs...)
 }
 
 var _ CredentialProvider = &apiKeyCredentialProvider{}
 
 type apiKeyCredentialProvider struct {
 	apiKey string
+
+	// The W&B base URL used to verify the key, or empty if it can't be
+	// verified.
+	baseURL string
+
+	// The HTTP client used to verify the key.
+	httpClient *http.Client
 }
 
 func NewAPIKeyCredentialProvider(
-	settings *settings.Settings,
+	settings *wbsettings.Settings,
+) (CredentialProvider, error) {
+	return newAPIKeyCredentialProvider(settings, nil)
+}
+
+// newAPIKeyCredentialProvider creates a provider for the API key in the
+// settings that is verified using the HTTP client, or a default client if
+// it is nil.
+func newAPIKeyCredentialProvider(
+	settings *wbsettings.Settings,
+	httpClient *http.Client,
 ) (CredentialProvider, error) {
 	if err := settings.EnsureAPIKey(); err != nil {
-		return nil, fmt.Errorf("couldn't get API key: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrNoAPIKey, err)
+	}
+
+	// The format of the key isn't checked: the server decides which keys
+	// it accepts, and a key the Python SDK accepts must not stop wandb-core
+	// from starting.
+	apiKey := settings.GetAPIKey()
+	if apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultNonRetryTimeout}
 	}
 
 	return &apiKeyCredentialProvider{
-		apiKey: settings.GetAPIKey(),
+		apiKey:     apiKey,
+		baseURL:    settings.GetBaseURL(),
+		httpClient: httpClient,
 	}, nil
 }
 
+var (
+	// ErrNoAPIKey indicates that no API key is configured.
+	ErrNoAPIKey = errors.New("no API key configured")
+
+	// ErrMalformedAPIKey indicates that the API key has the wrong format.
+	ErrMalformedAPIKey = errors.New("malformed API key")
+
+	// ErrCredentialsRejected indicates that the server did not accept the
+	// credentials.
+	ErrCredentialsRejected = errors.New("credentials rejected")
+
+	// ErrCorruptCredentialsFile indicates that the credentials file can't
+	// be parsed and must be deleted.
+	ErrCorruptCredentialsFile = errors.New("credentials file corrupt, delete it")
+)
+
+// Length of the secret part of an API key.
+const apiKeyLength = 40
+
+// ValidateAPIKey checks that the API key is well-formed, exactly like the
+// Python SDK does.
+//
+// An API key consists of 40 characters, optionally preceded by a prefix
+// and a dash, like "local-" for keys issued by a local server. Everything
+// after the first dash must be 40 characters long.
+//
+// Providers don't require keys to pass this check, since a server may
+// accept other keys. Returns an error wrapping ErrNoAPIKey or
+// ErrMalformedAPIKey.
+func ValidateAPIKey(apiKey string) error {
+	if apiKey == "" {
+		return ErrNoAPIKey
+	}
+
+	_, secret, hasPrefix := strings.Cut(apiKey, "-")
+	if !hasPrefix {
+		secret = apiKey
+	}
+	if len(secret) != apiKeyLength {
+		return fmt.Errorf(
+			"%w: expected %d characters, got %d",
+			ErrMalformedAPIKey, apiKeyLength, len(secret),
+		)
+	}
+
+	return nil
+}
+
+// NewStaticAPIKeyCredentialProvider returns a provider for the given API key.
+//
+// Unlike NewAPIKeyCredentialProvider, it does not read the key from settings
+// or the .netrc file.
+func NewStaticAPIKeyCredentialProvider(apiKey string) CredentialProvider {
+	return &apiKeyCredentialProvider{apiKey: apiKey}
+}
+
 func (c *apiKeyCredentialProvider) Apply(req *http.Request) error {
 	req.Header.Set(
 		"Authorization",
@@ -55,3 +529,1442 @@ func (c *apiKeyCredentialProvider) Apply(req *http.Request) error {
 	)
 	return nil
 }
+
+// Verify makes a viewer query to check that the server accepts the key.
+//
+// Keys created with NewStaticAPIKeyCredentialProvider have no server to
+// check against and are only checked for the right format.
+func (c *apiKeyCredentialProvider) Verify(ctx context.Context) error {
+	if c.baseURL == "" {
+		return ValidateAPIKey(c.apiKey)
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		c.baseURL+"/graphql",
+		strings.NewReader(`{"query": "query Viewer { viewer { id } }"}`),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create viewer request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	_ = c.Apply(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %v", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized ||
+		resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf(
+			"%w: %s did not accept API key %s",
+			ErrCredentialsRejected, c.baseURL,
+			wbsettings.RedactSecret(c.apiKey))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to verify API key: %s", resp.Status)
+	}
+
+	var response struct {
+		Data struct {
+			Viewer *struct {
+				ID string `json:"id"`
+			} `json:"viewer"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("failed to parse viewer response: %v", err)
+	}
+	if response.Data.Viewer == nil {
+		return fmt.Errorf(
+			"%w: %s has no user for API key %s",
+			ErrCredentialsRejected, c.baseURL,
+			wbsettings.RedactSecret(c.apiKey))
+	}
+
+	return nil
+}
+
+var _ CredentialProvider = &staticTokenCredentialProvider{}
+
+// staticTokenCredentialProvider uses a fixed access token.
+//
+// It keeps the token in memory only and never touches the filesystem,
+// which makes it suitable for tests and short-lived jobs.
+type staticTokenCredentialProvider struct {
+	// The access token.
+	accessToken string
+
+	// When the token expires, or the zero time if it never does.
+	expiresAt time.Time
+}
+
+// NewStaticTokenCredentialProvider returns a provider for the given access
+// token.
+//
+// If expiresAt is not the zero time, Apply fails once it has passed.
+func NewStaticTokenCredentialProvider(
+	accessToken string,
+	expiresAt time.Time,
+) CredentialProvider {
+	return &staticTokenCredentialProvider{
+		accessToken: accessToken,
+		expiresAt:   expiresAt,
+	}
+}
+
+func (c *staticTokenCredentialProvider) Apply(req *http.Request) error {
+	if !c.expiresAt.IsZero() && !time.Now().Before(c.expiresAt) {
+		return fmt.Errorf("access token expired at %v", c.expiresAt)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	return nil
+}
+
+// Verify checks that the token has not expired.
+func (c *staticTokenCredentialProvider) Verify(_ context.Context) error {
+	if !c.expiresAt.IsZero() && !time.Now().Before(c.expiresAt) {
+		return fmt.Errorf("access token expired at %v", c.expiresAt)
+	}
+	return nil
+}
+
+var _ BatchCredentialProvider = &oauth2CredentialProvider{}
+
+// oauth2CredentialProvider exchanges an identity token (a JWT) for a
+// short-lived access token and uses it to authorize requests.
+//
+// Access tokens are cached in a credentials file shared with the Python SDK.
+type oauth2CredentialProvider struct {
+	// The W&B base URL.
+	baseURL string
+
+	// The key of the access token in the credentials file.
+	credentialsKey string
+
+	// The URL of the OIDC token endpoint.
+	tokenURL string
+
+	// How long before it expires the access token is refreshed.
+	refreshBuffer time.Duration
+
+	// Tells the time for checking and computing token expiry.
+	clock Clock
+
+	// Counts the token requests.
+	stats *TokenExchangeStats
+
+	// Logs token requests, if set.
+	logger *slog.Logger
+
+	// Provides the identity token exchanged for access tokens.
+	identityToken identityTokenSource
+
+	// The audience and scope requested in the identity token exchange,
+	// if any.
+	exchangeAudience string
+	exchangeScope    string
+
+	// Authenticates the client to the token endpoint, or nil if the
+	// client doesn't authenticate.
+	clientAssertion *ClientAssertionSigner
+
+	// Path to the file where access tokens are stored.
+	credentialsFilePath string
+
+	// The HTTP client used for the token exchange.
+	httpClient *http.Client
+
+	// Whether to write new expiration timestamps as RFC 3339.
+	rfc3339ExpiresAt bool
+
+	// Permissions of the credentials file.
+	credentialsFileMode os.FileMode
+
+	// Whether new access tokens are only written on Flush.
+	deferWrites bool
+
+	// Whether a corrupt credentials file is backed up and replaced.
+	recoverCorruptFile bool
+
+	// Reads the credentials file; replaced in tests.
+	readFile func(name string) ([]byte, error)
+
+	// The delay before the first retry of a credentials file operation.
+	fileRetryDelay time.Duration
+
+	// Waits for the duration or until the context is done, returning the
+	// context's error in that case; replaced in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+
+	// Whether access tokens are kept only in memory.
+	inMemory bool
+
+	// Whether the credentials file's directory has been checked.
+	checkedDir bool
+
+	// Checks whether the credentials file's directory is writable;
+	// replaced in tests.
+	dirWritable func(dir string) bool
+
+	// The current access token and its expiration.
+	token tokenInfo
+
+	// Whether token hasn't been written to the credentials file yet.
+	dirty bool
+
+	// Protects token, dirty, inMemory and checkedDir.
+	mu *sync.RWMutex
+}
+
+type OAuth2CredentialProviderOptions struct {
+	// The W&B base URL, like "https://api.wandb.ai".
+	BaseURL string
+
+	// Path to the file containing the identity token.
+	//
+	// The file is used unless IdentityToken or IdentityTokenEnvVar
+	// provides the token.
+	IdentityTokenFile string
+
+	// The name of an environment variable containing the identity token,
+	// for environments that inject the token rather than write it to disk.
+	//
+	// It is preferred over the file if the variable is set when the
+	// provider is created. The variable is read for every exchange.
+	IdentityTokenEnvVar string
+
+	// The identity token itself, for callers that obtain it another way,
+	// like from stdin.
+	//
+	// It is preferred over the environment variable and the file.
+	IdentityToken string
+
+	// Path to the file where access tokens are stored.
+	CredentialsFile string
+
+	// Distinguishes identities that share a base URL, like two
+	// organizations behind the same W&B server.
+	//
+	// Access tokens are stored in the credentials file under the base URL.
+	// If an audience is set, they are stored under "<base URL>#<audience>"
+	// instead, so that each identity keeps its own token. Tokens stored
+	// under the base URL alone are still read and migrated to the new key.
+	Audience string
+
+	// The audience to request for access tokens obtained with the identity
+	// token, for OIDC providers that require one.
+	//
+	// Unlike Audience, this is sent to the token endpoint. If empty, no
+	// audience is requested.
+	ExchangeAudience string
+
+	// The space-separated scopes to request for access tokens obtained
+	// with the identity token.
+	//
+	// If empty, no scope is requested.
+	ExchangeScope string
+
+	// Authenticates the client to the token endpoint with the
+	// private_key_jwt method, for OIDC providers that require it.
+	//
+	// A client assertion is signed and sent with every request to the
+	// token endpoint. If nil, the client doesn't authenticate.
+	ClientAssertion *ClientAssertionSigner
+
+	// The URL of the OIDC token endpoint, if the auth server isn't at
+	// "<base URL>/oidc/token".
+	//
+	// It must be an absolute HTTP or HTTPS URL.
+	TokenEndpoint string
+
+	// The clock used to check whether access tokens are expiring and to
+	// compute their expiration.
+	//
+	// If nil, the system clock is used.
+	Clock Clock
+
+	// How far the clock is behind the auth server's, added to the clock's
+	// time. A negative skew means the clock is ahead.
+	//
+	// This compensates for known drift, so that tokens are refreshed
+	// before the server considers them expired.
+	ClockSkew time.Duration
+
+	// The maximum random offset added to or subtracted from the refresh
+	// window, which starts 5 minutes before the access token expires.
+	//
+	// The offset is picked once per provider, so that processes started
+	// together don't all refresh at the same time. If zero,
+	// DefaultRefreshJitter is used. If negative, there is no jitter.
+	RefreshJitter time.Duration
+
+	// Counts the requests made to the token endpoint.
+	//
+	// This allows the caller to observe how often tokens are obtained and
+	// how long it takes. If nil, the requests are not counted.
+	Stats *TokenExchangeStats
+
+	// The logger for token requests.
+	//
+	// Each request is logged at the debug level, or as a warning if it
+	// fails. If nil, nothing is logged.
+	Logger *slog.Logger
+
+	// The HTTP client to use for the token exchange.
+	//
+	// This allows configuring a proxy, custom CA certificates or other
+	// TLS settings. If nil, a client with DefaultTokenExchangeTimeout
+	// is used.
+	HTTPClient *http.Client
+
+	// Whether to skip verifying the token endpoint's TLS certificate.
+	//
+	// INSECURE: this lets anyone who can intercept the connection steal
+	// the identity token. It's only meant for staging servers with
+	// self-signed certificates. Only the token exchange is affected, not
+	// the client passed in HTTPClient or any other client. Enabling it
+	// is logged as a warning, to Logger or the default logger.
+	InsecureSkipVerify bool
+
+	// Whether to write the expiration of new access tokens in RFC 3339
+	// format, which includes the timezone.
+	//
+	// By default, the format used by the Python SDK is written.
+	RFC3339ExpiresAt bool
+
+	// Whether to write new access tokens to the credentials file only when
+	// the provider is flushed or closed, rather than as soon as they're
+	// obtained.
+	//
+	// This avoids disk I/O on the request path. The caller must flush the
+	// provider, otherwise tokens are lost when the process exits.
+	DeferWrites bool
+
+	// Permissions of the credentials file, like 0640 to let a sidecar in
+	// the same group read it.
+	//
+	// The owner must be able to read and write the file. Since the file
+	// contains access tokens, a world-readable mode is logged as a warning,
+	// to Logger or the default logger. If zero, DefaultCredentialsFileMode
+	// is used.
+	CredentialsFileMode os.FileMode
+
+	// Whether to back up a corrupt credentials file and replace it, rather
+	// than failing.
+	//
+	// The corrupt file is renamed to "<file>.corrupt" and new credentials
+	// are obtained. By default, loading credentials fails with
+	// ErrCorruptCredentialsFile until the file is deleted.
+	RecoverCorruptCredentialsFile bool
+
+	// Whether to keep access tokens only in memory, never reading or
+	// writing the credentials file.
+	//
+	// This suits read-only file systems, like those of locked-down
+	// containers. Access tokens are obtained again when they expire, but
+	// aren't shared with other processes. Tokens are also kept in memory
+	// if the credentials file's directory isn't writable.
+	InMemoryCredentials bool
+}
+
+func NewOAuth2CredentialProvider(
+	opts OAuth2CredentialProviderOptions,
+) (PersistentCredentialProvider, error) {
+	var source identityTokenSource
+	switch {
+	case opts.IdentityToken != "":
+		source = identityTokenLiteral(opts.IdentityToken)
+	case opts.IdentityTokenEnvVar != "" && os.Getenv(opts.IdentityTokenEnvVar) != "":
+		source = identityTokenEnv(opts.IdentityTokenEnvVar)
+	default:
+		if _, err := os.Stat(opts.IdentityTokenFile); err != nil {
+			return nil, fmt.Errorf("invalid identity token file: %v", err)
+		}
+		source = identityTokenFile(opts.IdentityTokenFile)
+	}
+
+	return newOAuth2CredentialProvider(opts, source)
+}
+
+// newOAuth2CredentialProvider creates an OAuth2 provider that exchanges
+// identity tokens from the given source.
+func newOAuth2CredentialProvider(
+	opts OAuth2CredentialProviderOptions,
+	identityToken identityTokenSource,
+) (PersistentCredentialProvider, error) {
+	fileMode, err := credentialsFileMode(opts.CredentialsFileMode, opts.Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenURL := fmt.Sprintf("%s/oidc/token", opts.BaseURL)
+	if opts.TokenEndpoint != "" {
+		if err := validateTokenEndpoint(opts.TokenEndpoint); err != nil {
+			return nil, err
+		}
+		tokenURL = opts.TokenEndpoint
+	}
+
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultTokenExchangeTimeout}
+	}
+	if opts.InsecureSkipVerify {
+		httpClient, err = insecureTokenClient(httpClient, tokenURL, opts.Logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &oauth2CredentialProvider{
+		baseURL:             opts.BaseURL,
+		credentialsKey:      credentialsKey(opts.BaseURL, opts.Audience),
+		tokenURL:            tokenURL,
+		refreshBuffer:       jitteredRefreshBuffer(opts.RefreshJitter),
+		clock:               newSkewedClock(opts.Clock, opts.ClockSkew),
+		stats:               opts.Stats,
+		logger:              opts.Logger,
+		identityToken:       identityToken,
+		exchangeAudience:    opts.ExchangeAudience,
+		exchangeScope:       opts.ExchangeScope,
+		clientAssertion:     opts.ClientAssertion,
+		credentialsFilePath: opts.CredentialsFile,
+		httpClient:          httpClient,
+		rfc3339ExpiresAt:    opts.RFC3339ExpiresAt,
+		deferWrites:         opts.DeferWrites,
+		credentialsFileMode: fileMode,
+		recoverCorruptFile:  opts.RecoverCorruptCredentialsFile,
+		readFile:            os.ReadFile,
+		fileRetryDelay:      credentialsFileRetryDelay,
+		sleep:               sleepContext,
+		inMemory:            opts.InMemoryCredentials,
+		dirWritable:         isDirWritable,
+		mu:                  &sync.RWMutex{},
+	}, nil
+}
+
+// Clock tells the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the real clock.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// skewedClock is a clock offset by a fixed amount.
+type skewedClock struct {
+	clock Clock
+	skew  time.Duration
+}
+
+func (c skewedClock) Now() time.Time { return c.clock.Now().Add(c.skew) }
+
+// newSkewedClock returns the clock offset by skew, using the system clock
+// if clock is nil.
+func newSkewedClock(clock Clock, skew time.Duration) Clock {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	if skew == 0 {
+		return clock
+	}
+	return skewedClock{clock: clock, skew: skew}
+}
+
+// credentialsFileMode validates the requested credentials file mode,
+// applying the default if it is zero.
+func credentialsFileMode(
+	mode os.FileMode,
+	logger *slog.Logger,
+) (os.FileMode, error) {
+	if mode == 0 {
+		return DefaultCredentialsFileMode, nil
+	}
+
+	if mode&^os.ModePerm != 0 || mode&0600 != 0600 {
+		return 0, fmt.Errorf(
+			"invalid credentials file mode %#o: must be a permission mode"+
+				" that lets the owner read and write",
+			mode)
+	}
+
+	if mode&0004 != 0 {
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Warn(
+			"api: credentials file mode is world-readable, exposing access tokens",
+			"mode", fmt.Sprintf("%#o", mode),
+		)
+	}
+
+	return mode, nil
+}
+
+// insecureTokenClient returns a copy of the client that doesn't verify
+// TLS certificates, for exchanges with the token endpoint at tokenURL.
+//
+// The client's transport is cloned so that other users of the client
+// keep verifying certificates.
+func insecureTokenClient(
+	client *http.Client,
+	tokenURL string,
+	logger *slog.Logger,
+) (*http.Client, error) {
+	var transport *http.Transport
+	switch t := client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return nil, fmt.Errorf(
+			"cannot skip TLS verification for the token exchange:"+
+				" unsupported transport %T",
+			client.Transport)
+	}
+
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.InsecureSkipVerify = true
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.Warn(
+		"api: INSECURE: not verifying the TLS certificate of the token endpoint;"+
+			" identity tokens can be intercepted",
+		"token_url", tokenURL,
+	)
+
+	insecure := *client
+	insecure.Transport = transport
+	return &insecure, nil
+}
+
+// validateTokenEndpoint checks that the token endpoint is an absolute
+// HTTP or HTTPS URL.
+func validateTokenEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid token endpoint %q: %v", endpoint, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf(
+			"invalid token endpoint %q: must be an absolute HTTP or HTTPS URL",
+			endpoint,
+		)
+	}
+	return nil
+}
+
+// jitteredRefreshBuffer returns tokenExpirationBuffer offset by a random
+// amount of up to jitter in either direction.
+//
+// A zero jitter means DefaultRefreshJitter, and a negative one disables it.
+func jitteredRefreshBuffer(jitter time.Duration) time.Duration {
+	switch {
+	case jitter == 0:
+		jitter = DefaultRefreshJitter
+	case jitter < 0:
+		return tokenExpirationBuffer
+	}
+
+	// Never refresh after the token has expired.
+	jitter = min(jitter, tokenExpirationBuffer)
+
+	offset := time.Duration(rand.Int64N(2*int64(jitter)+1)) - jitter
+	return tokenExpirationBuffer + offset
+}
+
+// credentialsKey returns the key of an identity's access token in the
+// credentials file.
+func credentialsKey(baseURL, audience string) string {
+	if audience == "" {
+		return baseURL
+	}
+	return baseURL + "#" + audience
+}
+
+// Apply sets the access token as a Bearer token on the request, creating or
+// refreshing it first if necessary.
+//
+// Requests to the token endpoint use the request's context, so they are
+// abandoned if it is cancelled or its deadline passes.
+func (c *oauth2CredentialProvider) Apply(req *http.Request) error {
+	accessToken, err := c.currentAccessToken(req.Context())
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return nil
+}
+
+// ApplyAll sets the same access token on every request, checking whether
+// it must be refreshed only once.
+//
+// The first request's context limits how long obtaining a token takes.
+func (c *oauth2CredentialProvider) ApplyAll(reqs []*http.Request) error {
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	accessToken, err := c.currentAccessToken(reqs[0].Context())
+	if err != nil {
+		return err
+	}
+
+	for _, req := range reqs {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return nil
+}
+
+// currentAccessToken returns the access token, first loading or
+// refreshing it if it is missing or about to expire.
+func (c *oauth2CredentialProvider) currentAccessToken(
+	ctx context.Context,
+) (string, error) {
+	c.mu.RLock()
+	if c.token.AccessToken == "" || c.token.ExpiresWithin(c.clock.Now(), c.refreshBuffer) {
+		c.mu.RUnlock()
+		if err := c.loadCredentials(ctx); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return "", fmt.Errorf("%w: %v", ctxErr, err)
+			}
+			return "", err
+		}
+		c.mu.RLock()
+	}
+	defer c.mu.RUnlock()
+
+	return c.token.AccessToken, nil
+}
+
+// Verify obtains an access token like Apply.
+//
+// If there is no valid access token in memory or in the credentials file,
+// this exchanges the identity token, checking that the identity token is
+// readable and accepted and that the token endpoint is reachable.
+func (c *oauth2CredentialProvider) Verify(ctx context.Context) error {
+	if err := c.loadCredentials(ctx); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("%w: %v", ctxErr, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// TokenExchangeStats counts the requests an OAuth2 provider makes to the
+// token endpoint.
+//
+// It is safe for concurrent use. The zero value is ready to use.
+type TokenExchangeStats struct {
+	successes    atomic.Int64
+	failures     atomic.Int64
+	totalLatency atomic.Int64
+}
+
+// Successes returns the number of access tokens obtained.
+func (s *TokenExchangeStats) Successes() int64 {
+	return s.successes.Load()
+}
+
+// Failures returns the number of failed requests.
+func (s *TokenExchangeStats) Failures() int64 {
+	return s.failures.Load()
+}
+
+// TotalLatency returns the total duration of all requests.
+func (s *TokenExchangeStats) TotalLatency() time.Duration {
+	return time.Duration(s.totalLatency.Load())
+}
+
+func (s *TokenExchangeStats) record(latency time.Duration, err error) {
+	if err != nil {
+		s.failures.Add(1)
+	} else {
+		s.successes.Add(1)
+	}
+	s.totalLatency.Add(int64(latency))
+}
+
+// CredentialsFile is the format of the credentials file.
+//
+// Access tokens are keyed by the W&B base URL they were issued for.
+type CredentialsFile struct {
+	Credentials map[string]tokenInfo `json:"credentials"`
+}
+
+type tokenInfo struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   ExpiresAt `json:"expires_at"`
+
+	// A token for obtaining a new access token without the identity token.
+	//
+	// Only some OIDC providers issue refresh tokens.
+	RefreshToken string `json:"refresh_token,omitempty"`
+
+	// When the refresh token expires, if known.
+	RefreshExpiresAt *ExpiresAt `json:"refresh_expires_at,omitempty"`
+}
+
+// ExpiresWithin returns whether the token expires within the given time
+// from now.
+func (t *tokenInfo) ExpiresWithin(now time.Time, d time.Duration) bool {
+	return t.ExpiresAt.Time.Sub(now) <= d
+}
+
+// CanRefresh returns whether the token has a usable refresh token.
+func (t *tokenInfo) CanRefresh(now time.Time) bool {
+	if t.RefreshToken == "" {
+		return false
+	}
+	return t.RefreshExpiresAt == nil || now.Before(t.RefreshExpiresAt.Time)
+}
+
+// Layout of timestamps written by the Python SDK, in UTC.
+const legacyExpiresAtLayout = "2006-01-02 15:04:05"
+
+// ExpiresAt is the expiration timestamp of an access token.
+//
+// It is serialized as "2006-01-02 15:04:05" in UTC, which is the format
+// written by the Python SDK, or as RFC 3339 if RFC3339 is set. Both formats
+// are accepted when parsing, and the parsed format is preserved.
+type ExpiresAt struct {
+	time.Time
+
+	// Whether to serialize the timestamp as RFC 3339.
+	RFC3339 bool
+}
+
+func (e *ExpiresAt) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("invalid expires_at value %s: %v", b, err)
+	}
+
+	if t, err := time.Parse(legacyExpiresAtLayout, s); err == nil {
+		*e = ExpiresAt{Time: t}
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return fmt.Errorf(
+			"invalid expires_at value %q: expected %q or RFC 3339 format",
+			s, legacyExpiresAtLayout,
+		)
+	}
+	*e = ExpiresAt{Time: t, RFC3339: true}
+	return nil
+}
+
+func (e ExpiresAt) MarshalJSON() ([]byte, error) {
+	if e.RFC3339 {
+		return json.Marshal(e.Time.Format(time.RFC3339))
+	}
+	return json.Marshal(e.Time.UTC().Format(legacyExpiresAtLayout))
+}
+
+// loadCredentials sets the access token, reading it from the credentials file
+// or fetching a new one from the server.
+func (c *oauth2CredentialProvider) loadCredentials(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another goroutine may have refreshed the token while we were waiting.
+	if c.token.AccessToken != "" && !c.token.ExpiresWithin(c.clock.Now(), c.refreshBuffer) {
+		return nil
+	}
+
+	if !c.usesCredentialsFile() {
+		token, err := c.createAccessToken(ctx, &c.token)
+		if err != nil {
+			return err
+		}
+		c.token = *token
+		return nil
+	}
+
+	credentialsFile, err := c.readCredentialsFile(ctx)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return c.writeCredentialsFile(ctx)
+	case err != nil:
+		return err
+	}
+
+	return c.loadCredentialsFromFile(ctx, credentialsFile)
+}
+
+// usesCredentialsFile reports whether access tokens are stored in the
+// credentials file rather than only in memory.
+//
+// The first time, it checks that the file's directory is writable, and
+// logs a warning and keeps tokens in memory from then on if it isn't.
+//
+// The caller must hold the write lock.
+func (c *oauth2CredentialProvider) usesCredentialsFile() bool {
+	if c.inMemory {
+		return false
+	}
+	if c.checkedDir {
+		return true
+	}
+	c.checkedDir = true
+
+	dir := filepath.Dir(c.credentialsFilePath)
+	if c.dirWritable(dir) {
+		return true
+	}
+
+	c.inMemory = true
+	logger := c.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.Warn(
+		"api: credentials directory is not writable, keeping access tokens in memory",
+		"dir", dir,
+	)
+	return false
+}
+
+// isDirWritable reports whether files can be created in dir, or in its
+// closest existing parent if dir doesn't exist yet.
+func isDirWritable(dir string) bool {
+	for {
+		_, err := os.Stat(dir)
+		if err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if !errors.Is(err, fs.ErrNotExist) || parent == dir {
+			return false
+		}
+		dir = parent
+	}
+
+	probe, err := os.CreateTemp(dir, ".wandb-write-check-*")
+	if err != nil {
+		return false
+	}
+	_ = probe.Close()
+	_ = os.Remove(probe.Name())
+	return true
+}
+
+// writeCredentialsFile fetches a new access token and creates the
+// credentials file containing it.
+func (c *oauth2CredentialProvider) writeCredentialsFile(ctx context.Context) error {
+	token, err := c.createAccessToken(ctx, &c.token)
+	if err != nil {
+		return err
+	}
+
+	credentialsFile := CredentialsFile{
+		Credentials: make(map[string]tokenInfo),
+	}
+	return c.storeToken(*token, &credentialsFile)
+}
+
+// loadCredentialsFromFile takes the access token from the credentials file,
+// fetching and saving a new one if it is missing or expiring.
+//
+// If there is no token under the provider's key but there is one under the
+// base URL, as written before audiences were supported, it is used and
+// saved under the provider's key.
+func (c *oauth2CredentialProvider) loadCredentialsFromFile(
+	ctx context.Context,
+	credentialsFile *CredentialsFile,
+) error {
+	token, ok := credentialsFile.Credentials[c.credentialsKey]
+	migrated := false
+	if !ok && c.credentialsKey != c.baseURL {
+		token, ok = credentialsFile.Credentials[c.baseURL]
+		migrated = ok
+	}
+
+	if !ok || token.ExpiresWithin(c.clock.Now(), c.refreshBuffer) {
+		newToken, err := c.createAccessToken(ctx, &token)
+		if err != nil {
+			return err
+		}
+		token = *newToken
+	} else if !migrated {
+		c.token = token
+		return nil
+	}
+
+	return c.storeToken(token, credentialsFile)
+}
+
+// readCredentialsFile reads and parses the credentials file.
+//
+// Transient read errors, like those of a network file system during a
+// blip, are retried. If the file doesn't exist, the error wraps
+// fs.ErrNotExist. If it can't be parsed, it is backed up and replaced by
+// an empty one if the provider recovers corrupt files, and otherwise the
+// error wraps ErrCorruptCredentialsFile.
+func (c *oauth2CredentialProvider) readCredentialsFile(
+	ctx context.Context,
+) (*CredentialsFile, error) {
+	var data []byte
+	err := c.retryFileOperation(ctx, func() error {
+		var err error
+		data, err = c.readFile(c.credentialsFilePath)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+
+	credentialsFile := &CredentialsFile{}
+	if err := json.Unmarshal(data, credentialsFile); err != nil {
+		if !c.recoverCorruptFile {
+			return nil, fmt.Errorf(
+				"%w: %s: %v",
+				ErrCorruptCredentialsFile,
+				c.credentialsFilePath,
+				err,
+			)
+		}
+
+		backupPath := c.credentialsFilePath + ".corrupt"
+		if err := os.Rename(c.credentialsFilePath, backupPath); err != nil {
+			return nil, fmt.Errorf("failed to back up corrupt credentials file: %v", err)
+		}
+		if c.logger != nil {
+			c.logger.Warn(
+				"api: replacing corrupt credentials file",
+				"path", c.credentialsFilePath,
+				"backup", backupPath,
+				"error", err,
+			)
+		}
+		credentialsFile = &CredentialsFile{}
+	}
+
+	if credentialsFile.Credentials == nil {
+		credentialsFile.Credentials = make(map[string]tokenInfo)
+	}
+	return credentialsFile, nil
+}
+
+// retryFileOperation runs op, retrying it with jittered exponential backoff
+// while it fails with a transient error.
+func (c *oauth2CredentialProvider) retryFileOperation(
+	ctx context.Context,
+	op func() error,
+) error {
+	delay := c.fileRetryDelay
+
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= credentialsFileAttempts || !isTransientFileError(err) {
+			return err
+		}
+
+		// Wait between half and the full delay.
+		jittered := delay/2 + time.Duration(rand.Int64N(int64(delay/2)+1))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(jittered):
+		}
+		delay *= 2
+	}
+}
+
+// isTransientFileError reports whether a file operation that failed with
+// err may succeed if retried.
+//
+// Missing files and denied permissions won't resolve themselves.
+func isTransientFileError(err error) bool {
+	return !errors.Is(err, fs.ErrNotExist) &&
+		!errors.Is(err, fs.ErrPermission) &&
+		!errors.Is(err, fs.ErrInvalid)
+}
+
+// storeToken makes the token current and adds it to the credentials file,
+// unless writes are deferred until Flush.
+//
+// The caller must hold the write lock.
+func (c *oauth2CredentialProvider) storeToken(
+	token tokenInfo,
+	credentialsFile *CredentialsFile,
+) error {
+	if c.deferWrites {
+		c.token = token
+		c.dirty = true
+		return nil
+	}
+
+	credentialsFile.Credentials[c.credentialsKey] = token
+	if err := c.saveCredentialsFile(credentialsFile); err != nil {
+		return err
+	}
+
+	c.token = token
+	return nil
+}
+
+// Flush writes the current access token to the credentials file if it
+// hasn't been written yet.
+//
+// Other entries in the credentials file are preserved.
+func (c *oauth2CredentialProvider) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	credentialsFile, err := c.readCredentialsFile(context.Background())
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		credentialsFile = &CredentialsFile{Credentials: make(map[string]tokenInfo)}
+	case err != nil:
+		return err
+	}
+
+	credentialsFile.Credentials[c.credentialsKey] = c.token
+	if err := c.saveCredentialsFile(credentialsFile); err != nil {
+		return err
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// Close flushes the current access token.
+func (c *oauth2CredentialProvider) Close() error {
+	return c.Flush()
+}
+
+// saveCredentialsFile writes the credentials file, creating its directory
+// if necessary.
+//
+// The file is written atomically so that concurrent readers never observe
+// a partially written file.
+func (c *oauth2CredentialProvider) saveCredentialsFile(
+	credentialsFile *CredentialsFile,
+) error {
+	data, err := json.MarshalIndent(credentialsFile, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal credentials: %v", err)
+	}
+
+	// A new directory is made traversable by whoever may read the file.
+	dirMode := os.FileMode(0700)
+	if c.credentialsFileMode&0040 != 0 {
+		dirMode |= 0050
+	}
+	if c.credentialsFileMode&0004 != 0 {
+		dirMode |= 0005
+	}
+
+	dir := filepath.Dir(c.credentialsFilePath)
+	if err := os.MkdirAll(dir, dirMode); err != nil {
+		return fmt.Errorf("failed to create credentials directory: %v", err)
+	}
+
+	err = c.retryFileOperation(context.Background(), func() error {
+		return writeFileAtomic(c.credentialsFilePath, data, c.credentialsFileMode)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write credentials file: %v", err)
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory as
+// path and then renames it to path.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+
+	// Clean up the temporary file if anything goes wrong.
+	success := false
+	defer func() {
+		if !success {
+			_ = tmpFile.Close()
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmpFile.Write(data); err != nil {
+		return err
+	}
+	if err := tmpFile.Chmod(perm); err != nil {
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	success = true
+	return nil
+}
+
+// createAccessToken obtains a new access token.
+//
+// If the current token has a usable refresh token, it is exchanged for a
+// new access token. Otherwise, or if that fails, the identity token is
+// exchanged instead.
+func (c *oauth2CredentialProvider) createAccessToken(
+	ctx context.Context,
+	current *tokenInfo,
+) (*tokenInfo, error) {
+	if current.CanRefresh(c.clock.Now()) {
+		start := c.clock.Now()
+		token, err := c.refreshAccessToken(ctx, current)
+		c.recordExchange("refresh_token", start, err)
+		if err == nil {
+			return token, nil
+		}
+	}
+
+	start := c.clock.Now()
+	token, err := c.exchangeIdentityToken(ctx)
+	c.recordExchange("jwt_bearer", start, err)
+	return token, err
+}
+
+// recordExchange counts and logs a request to the token endpoint.
+func (c *oauth2CredentialProvider) recordExchange(
+	grant string,
+	start time.Time,
+	err error,
+) {
+	latency := c.clock.Now().Sub(start)
+
+	if c.stats != nil {
+		c.stats.record(latency, err)
+	}
+
+	if c.logger == nil {
+		return
+	}
+	if err != nil {
+		c.logger.Warn(
+			"api: token exchange failed",
+			"base_url", c.baseURL,
+			"grant", grant,
+			"latency", latency,
+			"error", err,
+		)
+	} else {
+		c.logger.Debug(
+			"api: token exchange",
+			"base_url", c.baseURL,
+			"grant", grant,
+			"latency", latency,
+		)
+	}
+}
+
+// exchangeIdentityToken exchanges the identity token for an access token
+// using the JWT bearer grant.
+func (c *oauth2CredentialProvider) exchangeIdentityToken(
+	ctx context.Context,
+) (*tokenInfo, error) {
+	token, err := c.identityToken.IdentityToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", token)
+	if c.exchangeAudience != "" {
+		form.Set("audience", c.exchangeAudience)
+	}
+	if c.exchangeScope != "" {
+		form.Set("scope", c.exchangeScope)
+	}
+
+	accessToken, err := c.requestAccessToken(ctx, form)
+	if err != nil {
+		return nil, redactError(err, token, url.QueryEscape(token))
+	}
+	return accessToken, nil
+}
+
+// identityTokenSource provides identity tokens to exchange for access
+// tokens.
+type identityTokenSource interface {
+	// IdentityToken returns the current identity token.
+	IdentityToken(ctx context.Context) (string, error)
+}
+
+// identityTokenFile reads the identity token from a file.
+//
+// The file is read for every exchange, since the token in it may be
+// rotated by the environment. Surrounding whitespace, like the trailing
+// newline written by echo, is not part of the token.
+type identityTokenFile string
+
+func (path identityTokenFile) IdentityToken(_ context.Context) (string, error) {
+	token, err := os.ReadFile(string(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to read identity token file: %v", err)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// identityTokenEnv reads the identity token from an environment variable.
+//
+// The variable is read for every exchange, like the file.
+type identityTokenEnv string
+
+func (name identityTokenEnv) IdentityToken(_ context.Context) (string, error) {
+	token := strings.TrimSpace(os.Getenv(string(name)))
+	if token == "" {
+		return "", fmt.Errorf("identity token variable %s is not set", string(name))
+	}
+	return token, nil
+}
+
+// identityTokenLiteral is an identity token passed in directly.
+type identityTokenLiteral string
+
+func (token identityTokenLiteral) IdentityToken(_ context.Context) (string, error) {
+	return string(token), nil
+}
+
+// refreshAccessToken uses the refresh token grant to obtain a new
+// access token.
+//
+// The current refresh token is kept if the server doesn't issue a new one.
+func (c *oauth2CredentialProvider) refreshAccessToken(
+	ctx context.Context,
+	current *tokenInfo,
+) (*tokenInfo, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", current.RefreshToken)
+
+	token, err := c.requestAccessToken(ctx, form)
+	if err != nil {
+		return nil, redactError(err, current.RefreshToken)
+	}
+
+	if token.RefreshToken == "" {
+		token.RefreshToken = current.RefreshToken
+		token.RefreshExpiresAt = current.RefreshExpiresAt
+	}
+	return token, nil
+}
+
+// authenticateClient adds the client's credentials, if it has any, to a
+// token request's form.
+func (c *oauth2CredentialProvider) authenticateClient(form url.Values) error {
+	if c.clientAssertion == nil {
+		return nil
+	}
+	return c.clientAssertion.addTo(form, c.tokenURL, c.clock.Now())
+}
+
+// requestAccessToken posts the form to the token endpoint, with the
+// client's credentials, and parses the response.
+//
+// If the token endpoint responds 429 Too Many Requests with a Retry-After
+// header, the request is retried after the requested delay, waiting at
+// most maxTokenRetryAfter each time. Every attempt authenticates the
+// client again, since a client assertion may only be used once and
+// expires soon after it's signed.
+func (c *oauth2CredentialProvider) requestAccessToken(
+	ctx context.Context,
+	form url.Values,
+) (*tokenInfo, error) {
+	for attempt := 1; ; attempt++ {
+		if err := c.authenticateClient(form); err != nil {
+			return nil, err
+		}
+
+		token, err := c.postTokenRequest(ctx, form.Encode())
+
+		var rateLimited *tokenRateLimitedError
+		if !errors.As(err, &rateLimited) || attempt >= tokenRateLimitAttempts {
+			return token, err
+		}
+
+		if c.logger != nil {
+			c.logger.Info(
+				"api: token endpoint rate limited, retrying",
+				"base_url", c.baseURL,
+				"retry_after", rateLimited.retryAfter,
+			)
+		}
+		if c.sleep(ctx, min(rateLimited.retryAfter, maxTokenRetryAfter)) != nil {
+			return nil, err
+		}
+	}
+}
+
+// tokenRateLimitedError is returned when the token endpoint responds 429
+// Too Many Requests with a Retry-After header.
+type tokenRateLimitedError struct {
+	// retryAfter is how long the server asked to wait before retrying.
+	retryAfter time.Duration
+
+	// message describes the response.
+	message string
+}
+
+func (e *tokenRateLimitedError) Error() string {
+	return e.message
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is
+// either a number of seconds or an HTTP date.
+//
+// Dates in the past give a zero delay.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds < 0 || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+			return 0, false
+		}
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+
+	return 0, false
+}
+
+// sleepContext waits for the duration or until the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// postTokenRequest makes one request to the token endpoint.
+func (c *oauth2CredentialProvider) postTokenRequest(
+	ctx context.Context,
+	data string,
+) (*tokenInfo, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		c.tokenURL,
+		bytes.NewBufferString(data),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve access token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		message := fmt.Sprintf(
+			"failed to retrieve access token: %s: %s",
+			resp.Status, body,
+		)
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			retryAfter, ok := parseRetryAfter(
+				resp.Header.Get("Retry-After"),
+				c.clock.Now(),
+			)
+			if ok {
+				return nil, &tokenRateLimitedError{
+					retryAfter: retryAfter,
+					message:    message,
+				}
+			}
+		}
+
+		return nil, errors.New(message)
+	}
+
+	var tokenResponse struct {
+		AccessToken      string  `json:"access_token"`
+		ExpiresIn        float64 `json:"expires_in"`
+		RefreshToken     string  `json:"refresh_token"`
+		RefreshExpiresIn float64 `json:"refresh_expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %v", err)
+	}
+
+	now := c.clock.Now().UTC()
+	token := &tokenInfo{
+		AccessToken: tokenResponse.AccessToken,
+		ExpiresAt: ExpiresAt{
+			Time:    now.Add(time.Duration(tokenResponse.ExpiresIn) * time.Second),
+			RFC3339: c.rfc3339ExpiresAt,
+		},
+		RefreshToken: tokenResponse.RefreshToken,
+	}
+	if tokenResponse.RefreshToken != "" && tokenResponse.RefreshExpiresIn > 0 {
+		token.RefreshExpiresAt = &ExpiresAt{
+			Time:    now.Add(time.Duration(tokenResponse.RefreshExpiresIn) * time.Second),
+			RFC3339: c.rfc3339ExpiresAt,
+		}
+	}
+
+	return token, nil
+}
+
+// redactError masks any occurrence of the secrets in the error's message.
+//
+// The token server may echo parts of the request, including the identity
+// or refresh token, in its error responses.
+func redactError(err error, secrets ...string) error {
+	message := err.Error()
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		message = strings.ReplaceAll(message, secret, wbsettings.RedactSecret(secret))
+	}
+	if message == err.Error() {
+		return err
+	}
+	return errors.New(message)
+}
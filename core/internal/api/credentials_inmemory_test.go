@@ -0,0 +1,63 @@
+package api
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuth2CredentialProvider_UnwritableDirKeepsTokenInMemory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"access_token": "short-lived-token", "expires_in": 1}`))
+		}),
+	)
+	t.Cleanup(server.Close)
+	dir := t.TempDir()
+	identityTokenFile := filepath.Join(dir, "jwt.txt")
+	require.NoError(t, os.WriteFile(identityTokenFile, []byte("jwt"), 0600))
+	var logs bytes.Buffer
+
+	provider, err := NewOAuth2CredentialProvider(OAuth2CredentialProviderOptions{
+		BaseURL:           server.URL,
+		IdentityTokenFile: identityTokenFile,
+		CredentialsFile:   filepath.Join(dir, "credentials.json"),
+		Logger:            slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	require.NoError(t, err)
+	c := provider.(*oauth2CredentialProvider)
+	checks := 0
+	c.dirWritable = func(string) bool {
+		checks++
+		return false
+	}
+
+	for range 2 {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		require.NoError(t, c.Apply(req))
+		assert.Equal(t, "Bearer short-lived-token", req.Header.Get("Authorization"))
+	}
+
+	assert.Equal(t, 1, checks)
+	assert.Equal(t, 1, bytes.Count(logs.Bytes(), []byte("not writable")))
+	assert.NoFileExists(t, filepath.Join(dir, "credentials.json"))
+}
+
+func TestIsDirWritable(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.True(t, isDirWritable(dir))
+	assert.True(t, isDirWritable(filepath.Join(dir, "missing", "subdir")))
+
+	// The check cleans up after itself.
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJitteredRefreshBuffer_Distributed(t *testing.T) {
+	buffers := make(map[time.Duration]struct{})
+
+	for range 100 {
+		buffer := jitteredRefreshBuffer(0)
+
+		assert.GreaterOrEqual(t, buffer, tokenExpirationBuffer-DefaultRefreshJitter)
+		assert.LessOrEqual(t, buffer, tokenExpirationBuffer+DefaultRefreshJitter)
+		buffers[buffer] = struct{}{}
+	}
+
+	// With nanosecond resolution, collisions are practically impossible.
+	assert.Greater(t, len(buffers), 90)
+}
+
+func TestJitteredRefreshBuffer_Custom(t *testing.T) {
+	for range 100 {
+		buffer := jitteredRefreshBuffer(time.Second)
+
+		assert.GreaterOrEqual(t, buffer, tokenExpirationBuffer-time.Second)
+		assert.LessOrEqual(t, buffer, tokenExpirationBuffer+time.Second)
+	}
+}
+
+func TestJitteredRefreshBuffer_Disabled(t *testing.T) {
+	assert.Equal(t, tokenExpirationBuffer, jitteredRefreshBuffer(-1))
+}
+
+func TestJitteredRefreshBuffer_NeverPastExpiry(t *testing.T) {
+	for range 100 {
+		assert.GreaterOrEqual(t, jitteredRefreshBuffer(time.Hour), time.Duration(0))
+	}
+}
+
+func TestNewOAuth2CredentialProvider_ThresholdsDiffer(t *testing.T) {
+	buffers := make(map[time.Duration]struct{})
+	for range 20 {
+		provider, err := NewOAuth2CredentialProvider(OAuth2CredentialProviderOptions{
+			BaseURL:           "https://api.wandb.ai",
+			IdentityTokenFile: "credentials_jitter_test.go",
+		})
+		assert.NoError(t, err)
+		buffers[provider.(*oauth2CredentialProvider).refreshBuffer] = struct{}{}
+	}
+
+	assert.Greater(t, len(buffers), 1)
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuth2CredentialProvider_RetriesIntermittentReadError(t *testing.T) {
+	c := newTestOAuth2Provider(t, NewTokenServer(t))
+	failures := 2
+	c.readFile = func(name string) ([]byte, error) {
+		if failures > 0 {
+			failures--
+			// What reading from an NFS mount returns during a blip.
+			return nil, &os.PathError{Op: "read", Path: name, Err: syscall.EIO}
+		}
+		return os.ReadFile(name)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	require.NoError(t, c.Apply(req))
+
+	assert.Equal(t, 0, failures)
+	assert.Equal(t, "Bearer test-access-token", req.Header.Get("Authorization"))
+}
+
+func TestOAuth2CredentialProvider_PersistentReadErrorFails(t *testing.T) {
+	c := newTestOAuth2Provider(t, NewTokenServer(t))
+	attempts := 0
+	c.readFile = func(name string) ([]byte, error) {
+		attempts++
+		return nil, &os.PathError{Op: "read", Path: name, Err: syscall.EIO}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	err := c.Apply(req)
+
+	assert.True(t, errors.Is(err, syscall.EIO))
+	assert.Equal(t, credentialsFileAttempts, attempts)
+}
+
+func TestOAuth2CredentialProvider_PermissionErrorNotRetried(t *testing.T) {
+	c := newTestOAuth2Provider(t, NewTokenServer(t))
+	attempts := 0
+	c.readFile = func(name string) ([]byte, error) {
+		attempts++
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrPermission}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	err := c.Apply(req)
+
+	assert.ErrorIs(t, err, os.ErrPermission)
+	assert.Equal(t, 1, attempts)
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordWaits makes the provider record how long it waits instead of
+// waiting.
+func recordWaits(c *oauth2CredentialProvider) *[]time.Duration {
+	var waits []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	return &waits
+}
+
+func TestOAuth2CredentialProvider_HonorsRetryAfterSeconds(t *testing.T) {
+	server := NewTokenServer(t,
+		WithTokenStatus(http.StatusTooManyRequests, 1),
+		WithTokenHeader("Retry-After", "2"),
+	)
+	c := newTestOAuth2Provider(t, server)
+	waits := recordWaits(c)
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	require.NoError(t, c.Apply(req))
+
+	assert.Equal(t, "Bearer test-access-token", req.Header.Get("Authorization"))
+	assert.Equal(t, []time.Duration{2 * time.Second}, *waits)
+}
+
+func TestOAuth2CredentialProvider_HonorsRetryAfterDate(t *testing.T) {
+	retryAt := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+	server := NewTokenServer(t,
+		WithTokenStatus(http.StatusTooManyRequests, 1),
+		WithTokenHeader("Retry-After", retryAt),
+	)
+	c := newTestOAuth2Provider(t, server)
+	waits := recordWaits(c)
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	require.NoError(t, c.Apply(req))
+
+	require.Len(t, *waits, 1)
+	// HTTP dates have a resolution of one second.
+	assert.InDelta(t, 10*time.Second, (*waits)[0], float64(2*time.Second))
+}
+
+func TestOAuth2CredentialProvider_RetryAfterIsBounded(t *testing.T) {
+	server := NewTokenServer(t,
+		WithTokenStatus(http.StatusTooManyRequests, 1),
+		WithTokenHeader("Retry-After", "3600"),
+	)
+	c := newTestOAuth2Provider(t, server)
+	waits := recordWaits(c)
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	require.NoError(t, c.Apply(req))
+
+	assert.Equal(t, []time.Duration{maxTokenRetryAfter}, *waits)
+}
+
+func TestOAuth2CredentialProvider_GivesUpWhenStillRateLimited(t *testing.T) {
+	server := NewTokenServer(t,
+		WithTokenStatus(http.StatusTooManyRequests, 100),
+		WithTokenHeader("Retry-After", "1"),
+	)
+	c := newTestOAuth2Provider(t, server)
+	waits := recordWaits(c)
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	err := c.Apply(req)
+
+	assert.ErrorContains(t, err, "429 Too Many Requests")
+	assert.Len(t, *waits, tokenRateLimitAttempts-1)
+}
+
+func TestOAuth2CredentialProvider_RetryAfterStopsWhenCanceled(t *testing.T) {
+	server := NewTokenServer(t,
+		WithTokenStatus(http.StatusTooManyRequests, 100),
+		WithTokenHeader("Retry-After", "1"),
+	)
+	c := newTestOAuth2Provider(t, server)
+	waits := 0
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		waits++
+		return context.Canceled
+	}
+
+	_, err := c.requestAccessToken(
+		context.Background(),
+		url.Values{"grant_type": {"refresh_token"}},
+	)
+
+	assert.ErrorContains(t, err, "429 Too Many Requests")
+	assert.Equal(t, 1, waits)
+}
+
+func TestOAuth2CredentialProvider_WaitsForRetryAfter(t *testing.T) {
+	server := NewTokenServer(t,
+		WithTokenStatus(http.StatusTooManyRequests, 1),
+		WithTokenHeader("Retry-After", "2"),
+	)
+	c := newTestOAuth2Provider(t, server)
+	c.sleep = sleepContext
+
+	start := time.Now()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	require.NoError(t, c.Apply(req))
+
+	assert.GreaterOrEqual(t, time.Since(start), 2*time.Second)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name     string
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"Seconds", "2", 2 * time.Second, true},
+		{"FractionalSeconds", " 0.5 ", 500 * time.Millisecond, true},
+		{"Date", "Mon, 01 Jan 2024 00:00:30 GMT", 30 * time.Second, true},
+		{"PastDate", "Sun, 31 Dec 2023 23:59:00 GMT", 0, true},
+		{"Negative", "-1", 0, false},
+		{"Empty", "", 0, false},
+		{"Invalid", "soon", 0, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			delay, ok := parseRetryAfter(tc.value, now)
+
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, delay)
+		})
+	}
+}
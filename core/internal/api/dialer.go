@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultConnectTimeout is how long establishing a connection may take
+// unless configured otherwise.
+const DefaultConnectTimeout = 30 * time.Second
+
+// IPPreference is which IP versions connections use for hosts that have
+// both IPv4 and IPv6 addresses.
+type IPPreference int
+
+const (
+	// IPPreferenceAny races IPv4 and IPv6 connections as Go does by
+	// default ("happy eyeballs"). This is the default.
+	IPPreferenceAny IPPreference = iota
+
+	// IPPreferenceIPv4 tries IPv4 first and falls back to IPv6.
+	IPPreferenceIPv4
+
+	// IPPreferenceIPv6 tries IPv6 first and falls back to IPv4.
+	IPPreferenceIPv6
+
+	// IPPreferenceIPv4Only never uses IPv6.
+	IPPreferenceIPv4Only
+
+	// IPPreferenceIPv6Only never uses IPv4, for IPv6-only clusters.
+	IPPreferenceIPv6Only
+)
+
+// ParseIPPreference parses an IP preference: "any", "ipv4", "ipv6",
+// "ipv4only" or "ipv6only".
+//
+// An empty string means IPPreferenceAny.
+func ParseIPPreference(value string) (IPPreference, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "any":
+		return IPPreferenceAny, nil
+	case "ipv4":
+		return IPPreferenceIPv4, nil
+	case "ipv6":
+		return IPPreferenceIPv6, nil
+	case "ipv4only":
+		return IPPreferenceIPv4Only, nil
+	case "ipv6only":
+		return IPPreferenceIPv6Only, nil
+	default:
+		return IPPreferenceAny, fmt.Errorf(
+			"api: invalid IP preference %q,"+
+				" expected any, ipv4, ipv6, ipv4only or ipv6only",
+			value,
+		)
+	}
+}
+
+// DialOptions configures the connections made by NewDialContext.
+type DialOptions struct {
+	// Which IP versions to use.
+	IPPreference IPPreference
+
+	// How long establishing a connection may take, including resolving
+	// the host. If zero, DefaultConnectTimeout is used.
+	//
+	// When one IP version is preferred, each version gets the full
+	// timeout.
+	ConnectTimeout time.Duration
+
+	// The resolver for looking up hosts. If nil, the default is used.
+	Resolver *net.Resolver
+}
+
+// NewDialContext returns a function that makes connections as configured,
+// for use as an http.Transport's DialContext.
+func NewDialContext(
+	opts DialOptions,
+) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	timeout := opts.ConnectTimeout
+	if timeout <= 0 {
+		timeout = DefaultConnectTimeout
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Resolver:  opts.Resolver,
+	}
+
+	var networks []string
+	switch opts.IPPreference {
+	case IPPreferenceIPv4:
+		networks = []string{"tcp4", "tcp6"}
+	case IPPreferenceIPv6:
+		networks = []string{"tcp6", "tcp4"}
+	case IPPreferenceIPv4Only:
+		networks = []string{"tcp4"}
+	case IPPreferenceIPv6Only:
+		networks = []string{"tcp6"}
+	default:
+		return dialer.DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// Only plain TCP leaves the IP version open.
+		if network != "tcp" {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		var firstErr error
+		for _, network := range networks {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, firstErr
+	}
+}
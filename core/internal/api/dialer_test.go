@@ -0,0 +1,152 @@
+package api_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wandb/wandb/core/internal/api"
+)
+
+func TestParseIPPreference(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected api.IPPreference
+	}{
+		{"", api.IPPreferenceAny},
+		{"any", api.IPPreferenceAny},
+		{"IPv4", api.IPPreferenceIPv4},
+		{" ipv6 ", api.IPPreferenceIPv6},
+		{"ipv4only", api.IPPreferenceIPv4Only},
+		{"ipv6only", api.IPPreferenceIPv6Only},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			preference, err := api.ParseIPPreference(tc.value)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, preference)
+		})
+	}
+}
+
+func TestParseIPPreference_Invalid(t *testing.T) {
+	_, err := api.ParseIPPreference("ipv5")
+
+	assert.ErrorContains(t, err, `invalid IP preference "ipv5"`)
+}
+
+// newDialingClient returns an HTTP client that connects as configured.
+func newDialingClient(opts api.DialOptions) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{DialContext: api.NewDialContext(opts)},
+	}
+}
+
+func TestNewDialContext_IPPreference(t *testing.T) {
+	// The test server only listens on IPv4.
+	server := api.NewTokenServer(t)
+
+	testCases := []struct {
+		name       string
+		preference api.IPPreference
+		connects   bool
+	}{
+		{"Any", api.IPPreferenceAny, true},
+		{"IPv4", api.IPPreferenceIPv4, true},
+		{"IPv6FallsBack", api.IPPreferenceIPv6, true},
+		{"IPv4Only", api.IPPreferenceIPv4Only, true},
+		{"IPv6Only", api.IPPreferenceIPv6Only, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newDialingClient(api.DialOptions{IPPreference: tc.preference})
+
+			resp, err := client.Post(server.URL+"/oidc/token", "", nil)
+
+			if tc.connects {
+				require.NoError(t, err)
+				_ = resp.Body.Close()
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestNewDialContext_UsesResolver(t *testing.T) {
+	var lookups atomic.Int32
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			lookups.Add(1)
+			return nil, errors.New("test resolver")
+		},
+	}
+	dial := api.NewDialContext(api.DialOptions{
+		IPPreference: api.IPPreferenceIPv6,
+		Resolver:     resolver,
+	})
+
+	_, err := dial(context.Background(), "tcp", "wandb.test:443")
+
+	assert.Error(t, err)
+	assert.Positive(t, lookups.Load())
+}
+
+func TestOAuth2CredentialProvider_UsesConfiguredDialer(t *testing.T) {
+	server := api.NewTokenServer(t)
+	credentialProvider, err := api.NewOAuth2CredentialProvider(
+		api.OAuth2CredentialProviderOptions{
+			BaseURL:           server.URL,
+			IdentityTokenFile: writeIdentityToken(t, "jwt"),
+			CredentialsFile:   filepath.Join(t.TempDir(), "credentials.json"),
+			// The token server is only reachable over IPv4.
+			HTTPClient: newDialingClient(
+				api.DialOptions{IPPreference: api.IPPreferenceIPv6Only}),
+		},
+	)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	require.NoError(t, err)
+	err = credentialProvider.Apply(req)
+
+	assert.ErrorContains(t, err, "failed to retrieve access token")
+}
+
+func TestNewClient_NegotiatesHTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(server.Certificate())
+	backend := api.New(api.BackendOptions{
+		BaseURL:            &url.URL{Scheme: "https", Host: "api.example.com"},
+		CredentialProvider: api.NewStaticAPIKeyCredentialProvider(testAPIKey),
+		TLSClientConfig:    &tls.Config{RootCAs: rootCAs},
+		DialContext:        api.NewDialContext(api.DialOptions{}),
+	})
+	client := backend.NewClient(api.ClientOptions{})
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, 2, resp.ProtoMajor)
+}
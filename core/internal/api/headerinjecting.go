@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HeaderConflictPolicy decides what happens to an injected header that the
+// request already has, for example because the wrapped provider set it.
+type HeaderConflictPolicy int
+
+const (
+	// HeaderConflictOverride replaces the request's values. This is the
+	// default.
+	HeaderConflictOverride HeaderConflictPolicy = iota
+
+	// HeaderConflictAppend adds the value after the request's values.
+	HeaderConflictAppend
+)
+
+var _ BatchCredentialProvider = &HeaderInjectingProvider{}
+var _ PersistentCredentialProvider = &HeaderInjectingProvider{}
+
+// HeaderInjectingProvider wraps a CredentialProvider and also sets static
+// headers on every request.
+//
+// This is for gateways that require an extra header, like a tenant ID or a
+// CDN auth token, in addition to the W&B credentials.
+type HeaderInjectingProvider struct {
+	provider CredentialProvider
+	headers  http.Header
+	policy   HeaderConflictPolicy
+}
+
+// NewHeaderInjectingProvider returns a provider that applies provider and
+// then sets the headers.
+func NewHeaderInjectingProvider(
+	provider CredentialProvider,
+	headers map[string]string,
+	policy HeaderConflictPolicy,
+) *HeaderInjectingProvider {
+	canonical := make(http.Header, len(headers))
+	for key, value := range headers {
+		canonical.Set(key, value)
+	}
+
+	return &HeaderInjectingProvider{
+		provider: provider,
+		headers:  canonical,
+		policy:   policy,
+	}
+}
+
+// ParseHeaders parses a comma-separated list of headers like
+// "X-Tenant-ID=acme,X-CDN-Token=secret".
+//
+// Values can't contain commas.
+func ParseHeaders(spec string) (map[string]string, error) {
+	headers := make(map[string]string)
+
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(pair, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("api: invalid header %q, expected key=value", pair)
+		}
+		headers[key] = value
+	}
+
+	return headers, nil
+}
+
+// Apply applies the wrapped provider and then sets the headers.
+func (p *HeaderInjectingProvider) Apply(req *http.Request) error {
+	if err := p.provider.Apply(req); err != nil {
+		return err
+	}
+
+	p.injectHeaders(req)
+	return nil
+}
+
+// ApplyAll applies the wrapped provider to every request and then sets the
+// headers on each.
+func (p *HeaderInjectingProvider) ApplyAll(reqs []*http.Request) error {
+	if err := ApplyAll(p.provider, reqs); err != nil {
+		return err
+	}
+
+	for _, req := range reqs {
+		p.injectHeaders(req)
+	}
+	return nil
+}
+
+// Verify verifies the wrapped provider.
+func (p *HeaderInjectingProvider) Verify(ctx context.Context) error {
+	return p.provider.Verify(ctx)
+}
+
+// Flush flushes the wrapped provider if it stores credentials.
+func (p *HeaderInjectingProvider) Flush() error {
+	if persistent, ok := p.provider.(PersistentCredentialProvider); ok {
+		return persistent.Flush()
+	}
+	return nil
+}
+
+// Close closes the wrapped provider if it stores credentials.
+func (p *HeaderInjectingProvider) Close() error {
+	if persistent, ok := p.provider.(PersistentCredentialProvider); ok {
+		return persistent.Close()
+	}
+	return nil
+}
+
+// injectHeaders sets the headers on the request.
+func (p *HeaderInjectingProvider) injectHeaders(req *http.Request) {
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	for key, values := range p.headers {
+		for _, value := range values {
+			switch p.policy {
+			case HeaderConflictAppend:
+				req.Header.Add(key, value)
+			default:
+				req.Header.Set(key, value)
+			}
+		}
+	}
+}
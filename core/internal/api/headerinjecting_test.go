@@ -0,0 +1,135 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wandb/wandb/core/internal/api"
+	wbsettings "github.com/wandb/wandb/core/internal/settings"
+	spb "github.com/wandb/wandb/core/pkg/service_go_proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// failingCredentialProvider fails to authorize any request.
+type failingCredentialProvider struct{}
+
+func (failingCredentialProvider) Apply(*http.Request) error {
+	return errors.New("no credentials")
+}
+
+func (failingCredentialProvider) Verify(context.Context) error {
+	return errors.New("no credentials")
+}
+
+func TestHeaderInjectingProvider_AddsHeaders(t *testing.T) {
+	provider := api.NewHeaderInjectingProvider(
+		api.NewStaticAPIKeyCredentialProvider(testAPIKey),
+		map[string]string{"x-tenant-id": "acme", "X-CDN-Token": "secret"},
+		api.HeaderConflictOverride,
+	)
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, provider.Apply(req))
+
+	assert.Equal(t, testAPIKeyAuthorization, req.Header.Get("Authorization"))
+	assert.Equal(t, "acme", req.Header.Get("X-Tenant-ID"))
+	assert.Equal(t, "secret", req.Header.Get("X-CDN-Token"))
+}
+
+func TestHeaderInjectingProvider_Conflicts(t *testing.T) {
+	testCases := []struct {
+		name     string
+		policy   api.HeaderConflictPolicy
+		expected []string
+	}{
+		{"Override", api.HeaderConflictOverride, []string{"gateway"}},
+		{"Append", api.HeaderConflictAppend, []string{"client", "gateway"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := api.NewHeaderInjectingProvider(
+				api.NewStaticAPIKeyCredentialProvider(testAPIKey),
+				map[string]string{"Via": "gateway"},
+				tc.policy,
+			)
+			req, err := http.NewRequest("GET", "http://example.com", nil)
+			require.NoError(t, err)
+			req.Header.Set("Via", "client")
+
+			require.NoError(t, provider.Apply(req))
+
+			assert.Equal(t, tc.expected, req.Header.Values("Via"))
+			assert.Equal(t, testAPIKeyAuthorization, req.Header.Get("Authorization"))
+		})
+	}
+}
+
+func TestHeaderInjectingProvider_ApplyAll(t *testing.T) {
+	provider := api.NewHeaderInjectingProvider(
+		api.NewStaticAPIKeyCredentialProvider(testAPIKey),
+		map[string]string{"X-Tenant-ID": "acme"},
+		api.HeaderConflictOverride,
+	)
+	reqs := make([]*http.Request, 3)
+	for i := range reqs {
+		var err error
+		reqs[i], err = http.NewRequest("GET", "http://example.com", nil)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, api.ApplyAll(provider, reqs))
+
+	for _, req := range reqs {
+		assert.Equal(t, testAPIKeyAuthorization, req.Header.Get("Authorization"))
+		assert.Equal(t, "acme", req.Header.Get("X-Tenant-ID"))
+	}
+}
+
+func TestHeaderInjectingProvider_ProviderError(t *testing.T) {
+	provider := api.NewHeaderInjectingProvider(
+		failingCredentialProvider{},
+		map[string]string{"X-Tenant-ID": "acme"},
+		api.HeaderConflictOverride,
+	)
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	require.NoError(t, err)
+
+	assert.ErrorContains(t, provider.Apply(req), "no credentials")
+	assert.Empty(t, req.Header.Get("X-Tenant-ID"))
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := api.ParseHeaders(" X-Tenant-ID = acme, X-Empty=,")
+
+	require.NoError(t, err)
+	assert.Equal(t,
+		map[string]string{"X-Tenant-ID": "acme", "X-Empty": ""},
+		headers)
+}
+
+func TestParseHeaders_Invalid(t *testing.T) {
+	_, err := api.ParseHeaders("X-Tenant-ID")
+
+	assert.ErrorContains(t, err, `invalid header "X-Tenant-ID"`)
+}
+
+func TestNewCredentialProvider_CredentialHeaders(t *testing.T) {
+	t.Setenv("WANDB_CREDENTIAL_HEADERS", "X-Tenant-ID=acme")
+	settings := wbsettings.From(&spb.Settings{
+		ApiKey: &wrapperspb.StringValue{Value: testAPIKey},
+	})
+	credentialProvider, err := api.NewCredentialProvider(settings, api.CredentialProviderOptions{})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	require.NoError(t, err)
+	require.NoError(t, credentialProvider.Apply(req))
+
+	assert.Equal(t, testAPIKeyAuthorization, req.Header.Get("Authorization"))
+	assert.Equal(t, "acme", req.Header.Get("X-Tenant-ID"))
+}
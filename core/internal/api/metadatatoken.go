@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// IdentityTokenSourceGCP fetches identity tokens from the GCP instance
+	// metadata server.
+	IdentityTokenSourceGCP = "gcp"
+
+	// IdentityTokenSourceAzure fetches identity tokens from the Azure
+	// Instance Metadata Service (IMDS).
+	IdentityTokenSourceAzure = "azure"
+
+	// Timeout for requests to the metadata server.
+	metadataRequestTimeout = 10 * time.Second
+)
+
+// defaultMetadataEndpoints are the identity token endpoints of each
+// source's metadata server.
+var defaultMetadataEndpoints = map[string]string{
+	IdentityTokenSourceGCP: "http://metadata.google.internal/computeMetadata/v1" +
+		"/instance/service-accounts/default/identity",
+	IdentityTokenSourceAzure: "http://169.254.169.254/metadata/identity/oauth2/token",
+}
+
+type MetadataTokenCredentialProviderOptions struct {
+	// Options for exchanging the identity token for access tokens.
+	//
+	// IdentityTokenFile is ignored.
+	OAuth2CredentialProviderOptions
+
+	// Which metadata server to fetch the identity token from, either
+	// IdentityTokenSourceGCP or IdentityTokenSourceAzure.
+	Source string
+
+	// The URL of the metadata server's identity token endpoint, if not the
+	// source's well-known address.
+	MetadataEndpoint string
+
+	// The audience of the requested identity token.
+	//
+	// Azure calls this the resource. If empty, the base URL is used.
+	TokenAudience string
+}
+
+// NewMetadataTokenCredentialProvider creates a provider for workload
+// identity on GCP or Azure.
+//
+// The identity token is fetched from the instance metadata server for
+// every exchange, rather than read from a file, and is then exchanged for
+// access tokens like in the OAuth2 provider.
+func NewMetadataTokenCredentialProvider(
+	opts MetadataTokenCredentialProviderOptions,
+) (PersistentCredentialProvider, error) {
+	endpoint, ok := defaultMetadataEndpoints[opts.Source]
+	if !ok {
+		return nil, fmt.Errorf("unknown identity token source %q", opts.Source)
+	}
+	if opts.MetadataEndpoint != "" {
+		endpoint = opts.MetadataEndpoint
+	}
+
+	audience := opts.TokenAudience
+	if audience == "" {
+		audience = opts.BaseURL
+	}
+	if audience == "" {
+		return nil, fmt.Errorf("no audience for the identity token")
+	}
+
+	return newOAuth2CredentialProvider(
+		opts.OAuth2CredentialProviderOptions,
+		&metadataIdentityToken{
+			source:   opts.Source,
+			endpoint: endpoint,
+			audience: audience,
+			// Metadata servers are link-local and must not be reached
+			// through a proxy.
+			httpClient: &http.Client{
+				Timeout:   metadataRequestTimeout,
+				Transport: &http.Transport{Proxy: nil},
+			},
+		},
+	)
+}
+
+// metadataIdentityToken fetches identity tokens from a cloud's instance
+// metadata server.
+type metadataIdentityToken struct {
+	// IdentityTokenSourceGCP or IdentityTokenSourceAzure.
+	source string
+
+	// The URL of the identity token endpoint.
+	endpoint string
+
+	// The audience of the identity token.
+	audience string
+
+	// The HTTP client used to contact the metadata server.
+	httpClient *http.Client
+}
+
+func (m *metadataIdentityToken) IdentityToken(
+	ctx context.Context,
+) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create identity token request: %v", err)
+	}
+
+	query := req.URL.Query()
+	switch m.source {
+	case IdentityTokenSourceGCP:
+		req.Header.Set("Metadata-Flavor", "Google")
+		query.Set("audience", m.audience)
+		query.Set("format", "full")
+	case IdentityTokenSourceAzure:
+		req.Header.Set("Metadata", "true")
+		query.Set("api-version", "2018-02-01")
+		query.Set("resource", m.audience)
+	}
+	req.URL.RawQuery = query.Encode()
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch identity token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read identity token response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf(
+			"failed to fetch identity token: %s: %s",
+			resp.Status, body,
+		)
+	}
+
+	var token string
+	switch m.source {
+	case IdentityTokenSourceGCP:
+		// GCP responds with the token itself.
+		token = strings.TrimSpace(string(body))
+	case IdentityTokenSourceAzure:
+		var tokenResponse struct {
+			AccessToken string `json:"access_token"`
+		}
+		if err := json.Unmarshal(body, &tokenResponse); err != nil {
+			return "", fmt.Errorf("failed to parse identity token response: %v", err)
+		}
+		token = tokenResponse.AccessToken
+	}
+
+	if token == "" {
+		return "", fmt.Errorf("metadata server returned an empty identity token")
+	}
+	return token, nil
+}
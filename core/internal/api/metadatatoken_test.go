@@ -0,0 +1,135 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wandb/wandb/core/internal/api"
+)
+
+// newAssertionTokenServer returns a token server that expects the given
+// identity token as the JWT bearer assertion.
+func newAssertionTokenServer(t *testing.T, identityToken string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/oidc/token", r.URL.Path)
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, identityToken, r.PostForm.Get("assertion"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token": "test-access-token", "expires_in": 3600}`))
+		}),
+	)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestMetadataTokenCredentialProvider_GCP(t *testing.T) {
+	tokenServer := newAssertionTokenServer(t, "gcp-jwt")
+	metadataServer := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+			assert.Equal(t, "https://wandb.example.com", r.URL.Query().Get("audience"))
+			assert.Equal(t, "full", r.URL.Query().Get("format"))
+			_, _ = w.Write([]byte("gcp-jwt"))
+		}),
+	)
+	t.Cleanup(metadataServer.Close)
+
+	credentialProvider, err := api.NewMetadataTokenCredentialProvider(
+		api.MetadataTokenCredentialProviderOptions{
+			OAuth2CredentialProviderOptions: api.OAuth2CredentialProviderOptions{
+				BaseURL:         tokenServer.URL,
+				CredentialsFile: filepath.Join(t.TempDir(), "credentials.json"),
+			},
+			Source:           api.IdentityTokenSourceGCP,
+			MetadataEndpoint: metadataServer.URL,
+			TokenAudience:    "https://wandb.example.com",
+		},
+	)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	require.NoError(t, err)
+	require.NoError(t, credentialProvider.Apply(req))
+
+	assert.Equal(t, "Bearer test-access-token", req.Header.Get("Authorization"))
+}
+
+func TestMetadataTokenCredentialProvider_Azure(t *testing.T) {
+	tokenServer := newAssertionTokenServer(t, "azure-jwt")
+	metadataServer := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "true", r.Header.Get("Metadata"))
+			assert.Equal(t, "2018-02-01", r.URL.Query().Get("api-version"))
+			// The audience defaults to the base URL.
+			assert.Equal(t, tokenServer.URL, r.URL.Query().Get("resource"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token": "azure-jwt", "expires_in": "3599"}`))
+		}),
+	)
+	t.Cleanup(metadataServer.Close)
+
+	credentialProvider, err := api.NewMetadataTokenCredentialProvider(
+		api.MetadataTokenCredentialProviderOptions{
+			OAuth2CredentialProviderOptions: api.OAuth2CredentialProviderOptions{
+				BaseURL:         tokenServer.URL,
+				CredentialsFile: filepath.Join(t.TempDir(), "credentials.json"),
+			},
+			Source:           api.IdentityTokenSourceAzure,
+			MetadataEndpoint: metadataServer.URL,
+		},
+	)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	require.NoError(t, err)
+	require.NoError(t, credentialProvider.Apply(req))
+
+	assert.Equal(t, "Bearer test-access-token", req.Header.Get("Authorization"))
+}
+
+func TestMetadataTokenCredentialProvider_MetadataServerError(t *testing.T) {
+	tokenServer := newAssertionTokenServer(t, "unused")
+	metadataServer := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "no service account", http.StatusNotFound)
+		}),
+	)
+	t.Cleanup(metadataServer.Close)
+
+	credentialProvider, err := api.NewMetadataTokenCredentialProvider(
+		api.MetadataTokenCredentialProviderOptions{
+			OAuth2CredentialProviderOptions: api.OAuth2CredentialProviderOptions{
+				BaseURL:         tokenServer.URL,
+				CredentialsFile: filepath.Join(t.TempDir(), "credentials.json"),
+			},
+			Source:           api.IdentityTokenSourceGCP,
+			MetadataEndpoint: metadataServer.URL,
+		},
+	)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	require.NoError(t, err)
+	err = credentialProvider.Apply(req)
+
+	assert.ErrorContains(t, err, "no service account")
+}
+
+func TestMetadataTokenCredentialProvider_UnknownSource(t *testing.T) {
+	_, err := api.NewMetadataTokenCredentialProvider(
+		api.MetadataTokenCredentialProviderOptions{
+			OAuth2CredentialProviderOptions: api.OAuth2CredentialProviderOptions{
+				BaseURL: "https://api.wandb.ai",
+			},
+			Source: "aws",
+		},
+	)
+
+	assert.ErrorContains(t, err, `unknown identity token source "aws"`)
+}
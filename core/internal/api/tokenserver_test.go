@@ -0,0 +1,163 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// This file is in package api so that both the internal and the external
+// tests of the package can use the fake token server.
+
+// TokenServer is a fake OIDC token endpoint that records the requests it
+// receives.
+type TokenServer struct {
+	*httptest.Server
+
+	useTLS bool
+
+	mu        sync.Mutex
+	requests  []TokenRequest
+	response  string
+	status    int
+	statusFor int
+	headers   http.Header
+}
+
+// TokenRequest is a request received by a TokenServer.
+type TokenRequest struct {
+	Header http.Header
+	Body   string
+	Form   url.Values
+}
+
+// TokenServerOption configures a TokenServer.
+type TokenServerOption func(*TokenServer)
+
+// WithTokenResponse sets the body of successful responses.
+func WithTokenResponse(body string) TokenServerOption {
+	return func(s *TokenServer) { s.response = body }
+}
+
+// WithTokenStatus makes the first count requests fail with the status.
+//
+// If count is negative, every request fails.
+func WithTokenStatus(status int, count int) TokenServerOption {
+	return func(s *TokenServer) {
+		s.status = status
+		s.statusFor = count
+	}
+}
+
+// WithTokenHeader sets a header on every response.
+func WithTokenHeader(key, value string) TokenServerOption {
+	return func(s *TokenServer) { s.headers.Set(key, value) }
+}
+
+// WithTokenServerTLS serves HTTPS with a self-signed certificate.
+func WithTokenServerTLS() TokenServerOption {
+	return func(s *TokenServer) { s.useTLS = true }
+}
+
+// NewTokenServer returns a server that responds to token exchange requests
+// at the default token endpoint.
+//
+// By default, every request gets an access token "test-access-token" that
+// expires in an hour.
+func NewTokenServer(t *testing.T, opts ...TokenServerOption) *TokenServer {
+	t.Helper()
+	s := &TokenServer{
+		response: `{"access_token": "test-access-token", "expires_in": 3600}`,
+		headers:  make(http.Header),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/oidc/token", r.URL.Path)
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		form, err := url.ParseQuery(string(body))
+		assert.NoError(t, err)
+
+		s.mu.Lock()
+		s.requests = append(s.requests, TokenRequest{
+			Header: r.Header.Clone(),
+			Body:   string(body),
+			Form:   form,
+		})
+		fail := s.status != 0 && s.statusFor != 0
+		if s.statusFor > 0 {
+			s.statusFor--
+		}
+		s.mu.Unlock()
+
+		for key, values := range s.headers {
+			w.Header()[key] = values
+		}
+		if fail {
+			w.WriteHeader(s.status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(s.response))
+	})
+
+	if s.useTLS {
+		s.Server = httptest.NewTLSServer(handler)
+	} else {
+		s.Server = httptest.NewServer(handler)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Requests returns the requests the server received so far.
+func (s *TokenServer) Requests() []TokenRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// Grants returns the grant type of each request the server received.
+func (s *TokenServer) Grants() []string {
+	var grants []string
+	for _, req := range s.Requests() {
+		grants = append(grants, req.Form.Get("grant_type"))
+	}
+	return grants
+}
+
+// newTestOAuth2Provider returns an OAuth2 provider for the token server
+// that doesn't wait long between attempts to read its credentials file.
+func newTestOAuth2Provider(
+	t *testing.T,
+	server *TokenServer,
+) *oauth2CredentialProvider {
+	t.Helper()
+	dir := t.TempDir()
+	identityTokenFile := filepath.Join(dir, "jwt.txt")
+	require.NoError(t, os.WriteFile(identityTokenFile, []byte("jwt"), 0600))
+
+	provider, err := NewOAuth2CredentialProvider(OAuth2CredentialProviderOptions{
+		BaseURL:           server.URL,
+		IdentityTokenFile: identityTokenFile,
+		CredentialsFile:   filepath.Join(dir, "credentials.json"),
+	})
+	require.NoError(t, err)
+
+	c := provider.(*oauth2CredentialProvider)
+	c.fileRetryDelay = time.Millisecond
+	return c
+}
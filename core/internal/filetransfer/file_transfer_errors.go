@@ -0,0 +1,46 @@
+package filetransfer
+
+// StorageErrorKind is a provider-independent classification of an error
+// returned by a cloud storage service.
+//
+// It lets callers decide whether to retry an operation without depending
+// on the error types of each provider's SDK.
+type StorageErrorKind int
+
+const (
+	// StorageErrorOther is any error that doesn't fit another kind.
+	StorageErrorOther StorageErrorKind = iota
+
+	// StorageErrorNotFound means the bucket or object doesn't exist.
+	StorageErrorNotFound
+
+	// StorageErrorForbidden means the credentials don't grant access.
+	StorageErrorForbidden
+
+	// StorageErrorThrottled means the service asked us to slow down.
+	StorageErrorThrottled
+
+	// StorageErrorTransient is a temporary failure that may succeed on retry.
+	StorageErrorTransient
+)
+
+func (k StorageErrorKind) String() string {
+	switch k {
+	case StorageErrorNotFound:
+		return "not found"
+	case StorageErrorForbidden:
+		return "forbidden"
+	case StorageErrorThrottled:
+		return "throttled"
+	case StorageErrorTransient:
+		return "transient"
+	default:
+		return "other"
+	}
+}
+
+// IsRetryable reports whether an operation that failed with this kind of
+// error may succeed if retried.
+func (k StorageErrorKind) IsRetryable() bool {
+	return k == StorageErrorThrottled || k == StorageErrorTransient
+}
@@ -2,12 +2,17 @@ package filetransfer
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
@@ -21,11 +26,36 @@ type S3Client interface {
 	GetObjectAttributes(ctx context.Context, params *s3.GetObjectAttributesInput, optFns ...func(*s3.Options)) (*s3.GetObjectAttributesOutput, error)
 	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
 	ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error)
+	RestoreObject(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error)
 }
 
 const maxS3Workers int = 500
 const s3Scheme string = "s3"
 
+const (
+	DefaultS3RestoreDays         = 1
+	DefaultS3RestorePollInterval = time.Minute
+)
+
+// S3RestoreOptions configures what happens when downloading an object that
+// is archived, for example in S3 Glacier.
+type S3RestoreOptions struct {
+	// Enabled makes the download request a restore of the archived object
+	// and wait for it to complete, instead of failing.
+	Enabled bool
+
+	// Days is how long the restored copy stays available.
+	//
+	// Ignored for objects archived by S3 Intelligent-Tiering.
+	Days int32
+
+	// PollInterval is how often to check whether the restore completed.
+	PollInterval time.Duration
+
+	// Timeout is how long to wait for the restore. Zero means no limit.
+	Timeout time.Duration
+}
+
 // S3FileTransfer uploads or downloads files to/from s3
 type S3FileTransfer struct {
 	// client is the HTTP client for the file transfer
@@ -42,6 +72,12 @@ type S3FileTransfer struct {
 
 	// S3Once ensures that we only set up the S3 Client once
 	S3Once *sync.Once
+
+	// Restore configures downloading archived objects.
+	//
+	// By default, downloading an archived object fails with an error
+	// saying that it must be restored first.
+	Restore S3RestoreOptions
 }
 
 // News3FileTransfer creates a new fileTransfer.
@@ -66,7 +102,10 @@ func (ft *S3FileTransfer) SetupClient() {
 		if ft.client != nil {
 			return
 		}
-		cfg, err := config.LoadDefaultConfig(ft.ctx)
+		cfg, err := config.LoadDefaultConfig(
+			ft.ctx,
+			config.WithRetryer(NewS3Retryer),
+		)
 		if err != nil {
 			ft.logger.Error("Unable to load config to set up S3 client", "err", err)
 			return
@@ -282,6 +321,11 @@ func (ft *S3FileTransfer) downloadFile(
 	localPath string,
 ) error {
 	object, err := ft.client.GetObject(ft.ctx, getObjInput)
+
+	var archived *types.InvalidObjectState
+	if errors.As(err, &archived) {
+		object, err = ft.restoreObject(getObjInput, archived)
+	}
 	if err != nil {
 		return err
 	}
@@ -290,6 +334,169 @@ func (ft *S3FileTransfer) downloadFile(
 	return fileutil.CopyReaderToFile(object.Body, localPath)
 }
 
+// restoreObject restores an archived object and returns its contents once
+// it is available, if restoring is enabled.
+func (ft *S3FileTransfer) restoreObject(
+	getObjInput *s3.GetObjectInput,
+	archived *types.InvalidObjectState,
+) (*s3.GetObjectOutput, error) {
+	tier := string(archived.AccessTier)
+	if tier == "" {
+		tier = string(archived.StorageClass)
+	}
+
+	if !ft.Restore.Enabled {
+		return nil, fmt.Errorf(
+			"object %s is archived (%s), restore required",
+			aws.ToString(getObjInput.Key), tier,
+		)
+	}
+
+	restoreInput := &s3.RestoreObjectInput{
+		Bucket:         getObjInput.Bucket,
+		Key:            getObjInput.Key,
+		VersionId:      getObjInput.VersionId,
+		RestoreRequest: &types.RestoreRequest{},
+	}
+	// Objects archived by Intelligent-Tiering are restored to the frequent
+	// access tier and don't accept a number of days.
+	if archived.AccessTier == "" {
+		days := ft.Restore.Days
+		if days <= 0 {
+			days = DefaultS3RestoreDays
+		}
+		restoreInput.RestoreRequest.Days = aws.Int32(days)
+	}
+
+	_, err := ft.client.RestoreObject(ft.ctx, restoreInput)
+	var apiErr interface{ ErrorCode() string }
+	if err != nil &&
+		!(errors.As(err, &apiErr) && apiErr.ErrorCode() == "RestoreAlreadyInProgress") {
+		return nil, fmt.Errorf(
+			"failed to restore archived object %s: %v",
+			aws.ToString(getObjInput.Key), err,
+		)
+	}
+
+	// The timeout only limits how long to wait for the restore. Requests
+	// use ft.ctx, so that the body of the restored object can be read
+	// after this returns.
+	ctx := ft.ctx
+	if ft.Restore.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ft.Restore.Timeout)
+		defer cancel()
+	}
+
+	pollInterval := ft.Restore.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = DefaultS3RestorePollInterval
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf(
+				"timed out waiting for archived object %s to be restored: %v",
+				aws.ToString(getObjInput.Key), ctx.Err(),
+			)
+		case <-ticker.C:
+		}
+
+		object, err := ft.client.GetObject(ft.ctx, getObjInput)
+		if errors.As(err, &archived) {
+			continue
+		}
+		return object, err
+	}
+}
+
 func (ft *S3FileTransfer) formatDownloadError(context string, err error) error {
 	return fmt.Errorf("S3FileTransfer: Download: %s: %v", context, err)
 }
+
+// NewS3Retryer returns the SDK's standard retryer, consulting
+// ClassifyS3Error before its default rules.
+//
+// Errors classified as retryable are retried and not-found or forbidden
+// errors are not; other errors are left to the SDK.
+func NewS3Retryer() aws.Retryer {
+	return retry.NewStandard(func(o *retry.StandardOptions) {
+		o.Retryables = append(
+			[]retry.IsErrorRetryable{
+				retry.IsErrorRetryableFunc(isS3ErrorRetryable),
+			},
+			o.Retryables...,
+		)
+	})
+}
+
+// isS3ErrorRetryable decides whether to retry an S3 request that failed
+// with the error based on its StorageErrorKind.
+func isS3ErrorRetryable(err error) aws.Ternary {
+	switch kind := ClassifyS3Error(err); {
+	case kind.IsRetryable():
+		return aws.TrueTernary
+	case kind == StorageErrorNotFound || kind == StorageErrorForbidden:
+		return aws.FalseTernary
+	default:
+		return aws.UnknownTernary
+	}
+}
+
+// ClassifyS3Error maps an error returned by the S3 client to a
+// StorageErrorKind.
+//
+// It recognizes the modeled S3 errors, the error codes of the S3 API, and
+// falls back to the HTTP status code of the response.
+func ClassifyS3Error(err error) StorageErrorKind {
+	if err == nil {
+		return StorageErrorOther
+	}
+
+	var noSuchKey *types.NoSuchKey
+	var noSuchBucket *types.NoSuchBucket
+	var notFound *types.NotFound
+	if errors.As(err, &noSuchKey) ||
+		errors.As(err, &noSuchBucket) ||
+		errors.As(err, &notFound) {
+		return StorageErrorNotFound
+	}
+
+	var apiErr interface{ ErrorCode() string }
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NoSuchKey", "NoSuchBucket", "NotFound":
+			return StorageErrorNotFound
+		case "AccessDenied", "Forbidden", "InvalidAccessKeyId", "SignatureDoesNotMatch":
+			return StorageErrorForbidden
+		case "SlowDown", "Throttling", "ThrottlingException", "RequestLimitExceeded":
+			return StorageErrorThrottled
+		case "InternalError", "ServiceUnavailable", "RequestTimeout":
+			return StorageErrorTransient
+		}
+	}
+
+	var respErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &respErr) {
+		switch status := respErr.HTTPStatusCode(); {
+		case status == http.StatusNotFound:
+			return StorageErrorNotFound
+		case status == http.StatusUnauthorized || status == http.StatusForbidden:
+			return StorageErrorForbidden
+		case status == http.StatusTooManyRequests:
+			return StorageErrorThrottled
+		case status == http.StatusRequestTimeout || status >= 500:
+			return StorageErrorTransient
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return StorageErrorTransient
+	}
+
+	return StorageErrorOther
+}
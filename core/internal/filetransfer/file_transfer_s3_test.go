@@ -4,13 +4,21 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	"github.com/stretchr/testify/assert"
 	"github.com/wandb/wandb/core/internal/filetransfer"
 	"github.com/wandb/wandb/core/internal/observability"
@@ -60,6 +68,20 @@ var file2 = mockS3File{
 
 var mockS3Files = []mockS3File{file1v0, file1Latest, file2}
 
+// contextReader fails reads once its context is done, like the body of a
+// response to a request whose context was canceled.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
 func (m mockS3Client) GetObject(
 	ctx context.Context,
 	params *s3.GetObjectInput,
@@ -76,7 +98,10 @@ func (m mockS3Client) GetObject(
 		if file.Bucket == *params.Bucket &&
 			file.Key == *params.Key &&
 			file.VersionId == *params.VersionId {
-			return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(file.Content))}, nil
+			return &s3.GetObjectOutput{Body: io.NopCloser(&contextReader{
+				ctx:    ctx,
+				reader: bytes.NewReader(file.Content),
+			})}, nil
 		}
 	}
 	return nil, errors.New("object does not exist")
@@ -161,6 +186,122 @@ func (m mockS3Client) ListObjectVersions(
 	}, nil
 }
 
+func (m mockS3Client) RestoreObject(
+	ctx context.Context,
+	params *s3.RestoreObjectInput,
+	optFns ...func(*s3.Options),
+) (*s3.RestoreObjectOutput, error) {
+	return nil, errors.New("objects are not archived")
+}
+
+// archivedS3Client serves file2 as an object archived in Glacier that
+// becomes available after being restored and polled a few times.
+type archivedS3Client struct {
+	mockS3Client
+
+	mu           sync.Mutex
+	restored     bool
+	restoreDays  *int32
+	pollsLeft    int
+	restoreCalls int
+}
+
+func (m *archivedS3Client) GetObject(
+	ctx context.Context,
+	params *s3.GetObjectInput,
+	optFns ...func(*s3.Options),
+) (*s3.GetObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.restored || m.pollsLeft > 0 {
+		m.pollsLeft--
+		return nil, &types.InvalidObjectState{StorageClass: types.StorageClassGlacier}
+	}
+	return m.mockS3Client.GetObject(ctx, params, optFns...)
+}
+
+func (m *archivedS3Client) RestoreObject(
+	ctx context.Context,
+	params *s3.RestoreObjectInput,
+	optFns ...func(*s3.Options),
+) (*s3.RestoreObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.restoreCalls++
+	m.restored = true
+	m.restoreDays = params.RestoreRequest.Days
+	return &s3.RestoreObjectOutput{}, nil
+}
+
+func archivedFileTask(t *testing.T) *filetransfer.ReferenceArtifactDownloadTask {
+	return &filetransfer.ReferenceArtifactDownloadTask{
+		FileKind:     filetransfer.RunFileKindArtifact,
+		PathOrPrefix: filepath.Join(t.TempDir(), file2.Key),
+		Reference:    file2.Reference,
+		Digest:       file2.ETag,
+		Size:         100,
+	}
+}
+
+func TestS3FileTransfer_DownloadArchivedFails(t *testing.T) {
+	client := &archivedS3Client{}
+	ft := filetransfer.NewS3FileTransfer(
+		client,
+		observability.NewNoOpLogger(),
+		filetransfer.NewFileTransferStats(),
+	)
+
+	err := ft.Download(archivedFileTask(t))
+
+	assert.ErrorContains(t, err, "is archived (GLACIER), restore required")
+	assert.Zero(t, client.restoreCalls)
+}
+
+func TestS3FileTransfer_DownloadArchivedRestores(t *testing.T) {
+	client := &archivedS3Client{pollsLeft: 2}
+	ft := filetransfer.NewS3FileTransfer(
+		client,
+		observability.NewNoOpLogger(),
+		filetransfer.NewFileTransferStats(),
+	)
+	ft.Restore = filetransfer.S3RestoreOptions{
+		Enabled:      true,
+		Days:         3,
+		PollInterval: time.Millisecond,
+		Timeout:      time.Minute,
+	}
+	task := archivedFileTask(t)
+
+	err := ft.Download(task)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, client.restoreCalls)
+	assert.Equal(t, int32(3), *client.restoreDays)
+	content, err := os.ReadFile(task.PathOrPrefix)
+	assert.NoError(t, err)
+	assert.Equal(t, file2.Content, content)
+}
+
+func TestS3FileTransfer_DownloadArchivedTimesOut(t *testing.T) {
+	client := &archivedS3Client{pollsLeft: 1_000_000}
+	ft := filetransfer.NewS3FileTransfer(
+		client,
+		observability.NewNoOpLogger(),
+		filetransfer.NewFileTransferStats(),
+	)
+	ft.Restore = filetransfer.S3RestoreOptions{
+		Enabled:      true,
+		PollInterval: time.Millisecond,
+		Timeout:      20 * time.Millisecond,
+	}
+
+	err := ft.Download(archivedFileTask(t))
+
+	assert.ErrorContains(t, err, "timed out waiting for archived object")
+}
+
 func TestS3FileTransfer_Download(t *testing.T) {
 	mockS3Client := &mockS3Client{}
 
@@ -294,3 +435,85 @@ func TestS3FileTransfer_Download(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, file2.Content, content)
 }
+
+// timeoutError is a net.Error that timed out.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func responseError(status int) error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{
+				Response: &http.Response{StatusCode: status},
+			},
+			Err: errors.New("response error"),
+		},
+	}
+}
+
+func TestClassifyS3Error(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected filetransfer.StorageErrorKind
+	}{
+		{"Nil", nil, filetransfer.StorageErrorOther},
+		{"NoSuchKey", &types.NoSuchKey{}, filetransfer.StorageErrorNotFound},
+		{"NoSuchBucket", &types.NoSuchBucket{}, filetransfer.StorageErrorNotFound},
+		{"NotFound", &types.NotFound{}, filetransfer.StorageErrorNotFound},
+		{
+			"WrappedNoSuchKey",
+			fmt.Errorf("download: %w", &types.NoSuchKey{}),
+			filetransfer.StorageErrorNotFound,
+		},
+		{
+			"AccessDenied",
+			&smithy.GenericAPIError{Code: "AccessDenied"},
+			filetransfer.StorageErrorForbidden,
+		},
+		{
+			"SlowDown",
+			&smithy.GenericAPIError{Code: "SlowDown"},
+			filetransfer.StorageErrorThrottled,
+		},
+		{
+			"InternalError",
+			&smithy.GenericAPIError{Code: "InternalError"},
+			filetransfer.StorageErrorTransient,
+		},
+		{"Status404", responseError(http.StatusNotFound), filetransfer.StorageErrorNotFound},
+		{"Status403", responseError(http.StatusForbidden), filetransfer.StorageErrorForbidden},
+		{"Status429", responseError(http.StatusTooManyRequests), filetransfer.StorageErrorThrottled},
+		{"Status503", responseError(http.StatusServiceUnavailable), filetransfer.StorageErrorTransient},
+		{"Status400", responseError(http.StatusBadRequest), filetransfer.StorageErrorOther},
+		{"Timeout", timeoutError{}, filetransfer.StorageErrorTransient},
+		{"Other", errors.New("boom"), filetransfer.StorageErrorOther},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, filetransfer.ClassifyS3Error(tc.err))
+		})
+	}
+}
+
+func TestStorageErrorKindIsRetryable(t *testing.T) {
+	assert.True(t, filetransfer.StorageErrorThrottled.IsRetryable())
+	assert.True(t, filetransfer.StorageErrorTransient.IsRetryable())
+	assert.False(t, filetransfer.StorageErrorNotFound.IsRetryable())
+	assert.False(t, filetransfer.StorageErrorForbidden.IsRetryable())
+	assert.False(t, filetransfer.StorageErrorOther.IsRetryable())
+}
+
+func TestS3Retryer(t *testing.T) {
+	retryer := filetransfer.NewS3Retryer()
+
+	assert.True(t, retryer.IsErrorRetryable(&smithy.GenericAPIError{Code: "SlowDown"}))
+	assert.True(t, retryer.IsErrorRetryable(responseError(http.StatusServiceUnavailable)))
+	assert.True(t, retryer.IsErrorRetryable(timeoutError{}))
+	assert.False(t, retryer.IsErrorRetryable(&types.NoSuchKey{}))
+	assert.False(t, retryer.IsErrorRetryable(&smithy.GenericAPIError{Code: "AccessDenied"}))
+	assert.False(t, retryer.IsErrorRetryable(responseError(http.StatusBadRequest)))
+}
@@ -28,14 +28,18 @@ type FileTransfers struct {
 }
 
 // NewFileTransfers creates a new fileTransfers
+//
+// s3Restore configures downloading archived S3 objects.
 func NewFileTransfers(
 	client *retryablehttp.Client,
 	logger *observability.CoreLogger,
 	fileTransferStats FileTransferStats,
+	s3Restore S3RestoreOptions,
 ) *FileTransfers {
 	defaultFileTransfer := NewDefaultFileTransfer(client, logger, fileTransferStats)
 	gcsFileTransfer := NewGCSFileTransfer(nil, logger, fileTransferStats)
 	s3FileTransfer := NewS3FileTransfer(nil, logger, fileTransferStats)
+	s3FileTransfer.Restore = s3Restore
 
 	return &FileTransfers{
 		Default: defaultFileTransfer,
@@ -1,6 +1,11 @@
 package runbranch
 
-import spb "github.com/wandb/wandb/core/pkg/service_go_proto"
+import (
+	"fmt"
+	"math"
+
+	spb "github.com/wandb/wandb/core/pkg/service_go_proto"
+)
 
 // ForkBranch is a used to manage the state of the changes that need to be
 // applied to a run when a fork from a previous run is requested.
@@ -57,6 +62,26 @@ func (fb *ForkBranch) ApplyChanges(
 		}
 	}
 
+	// the fork point is a history step, so it must be a whole,
+	// non-negative number that the next step still fits in an int64;
+	// anything else would be silently truncated or overflow
+	if fb.metricValue < 0 ||
+		math.IsInf(fb.metricValue, 0) ||
+		fb.metricValue >= math.MaxInt64 ||
+		fb.metricValue != math.Trunc(fb.metricValue) {
+		err := fmt.Errorf(
+			"fork_from step must be a non-negative integer, got %v",
+			fb.metricValue,
+		)
+		return nil, &BranchError{
+			Err: err,
+			Response: &spb.ErrorInfo{
+				Code:    spb.ErrorInfo_USAGE,
+				Message: err.Error(),
+			},
+		}
+	}
+
 	r := params.Clone()
 	r.Merge(
 		&RunParams{
@@ -1,6 +1,7 @@
 package runbranch_test
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -59,5 +60,77 @@ func TestForkGetUpdatesValid(t *testing.T) {
 	assert.Nil(t, err, "GetUpdates should not return an error")
 	assert.NotNil(t, params, "GetUpdates should return params")
 	assert.True(t, params.Forked, "GetUpdates should set Forked to true")
+	assert.Equal(t, runbranch.BranchFork, params.Type())
 	assert.Equal(t, int64(11), params.StartingStep, "GetUpdates should set StartingStep")
 }
+
+// Test that the fork step must be a non-negative integer
+func TestForkInvalidStep(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value float64
+	}{
+		{name: "Negative", value: -1},
+		{name: "Fractional", value: 10.5},
+		{name: "NaN", value: math.NaN()},
+		{name: "PositiveInfinity", value: math.Inf(1)},
+		{name: "NegativeInfinity", value: math.Inf(-1)},
+		{name: "TooLarge", value: 1e19},
+		{name: "OverflowsNextStep", value: math.Ldexp(1, 63)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params, err := runbranch.NewForkBranch(
+				"runid",
+				"_step",
+				tc.value,
+			).ApplyChanges(
+				&runbranch.RunParams{},
+				runbranch.RunPath{RunID: "other"},
+			)
+
+			assert.Nil(t, params, "GetUpdates should return nil params")
+			assert.IsType(t, &runbranch.BranchError{}, err, "GetUpdates should return a BranchError")
+			assert.NotNil(t, err.(*runbranch.BranchError).Response, "BranchError should have a response")
+		})
+	}
+}
+
+// Test that the largest step below the int64 limit is accepted
+func TestForkLargestStep(t *testing.T) {
+	step := math.Nextafter(math.MaxInt64, 0)
+
+	params, err := runbranch.NewForkBranch(
+		"runid",
+		"_step",
+		step,
+	).ApplyChanges(
+		&runbranch.RunParams{},
+		runbranch.RunPath{RunID: "other"},
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(step)+1, params.StartingStep)
+	assert.Positive(t, params.StartingStep)
+}
+
+// Test that forking keeps the existing run params
+func TestForkKeepsRunParams(t *testing.T) {
+
+	params, err := runbranch.NewForkBranch(
+		"runid",
+		"_step",
+		0,
+	).ApplyChanges(
+		&runbranch.RunParams{
+			RunID:  "other",
+			Config: map[string]any{"lr": 0.1},
+		},
+		runbranch.RunPath{RunID: "other"},
+	)
+
+	assert.Nil(t, err, "GetUpdates should not return an error")
+	assert.Equal(t, "other", params.RunID)
+	assert.Equal(t, map[string]any{"lr": 0.1}, params.Config)
+	assert.Equal(t, int64(1), params.StartingStep)
+}
@@ -1,22 +1,97 @@
 package runbranch
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 
 	"github.com/wandb/simplejsonext"
 	"github.com/wandb/wandb/core/internal/filestream"
 )
 
+// ResumeError reports the parts of a run's state that could not be
+// restored when resuming it.
+type ResumeError struct {
+	// Sections maps each section that failed, like "config" or "history",
+	// to the reason.
+	Sections map[string]error
+}
+
+func (e *ResumeError) Error() string {
+	var parts []string
+	for _, name := range e.SectionNames() {
+		parts = append(parts, fmt.Sprintf("%s: %v", name, e.Sections[name]))
+	}
+	return fmt.Sprintf("failed to restore run state: %s", strings.Join(parts, "; "))
+}
+
+func (e *ResumeError) Unwrap() []error {
+	var errs []error
+	for _, name := range e.SectionNames() {
+		errs = append(errs, e.Sections[name])
+	}
+	return errs
+}
+
+// SectionNames returns the names of the failed sections in sorted order.
+func (e *ResumeError) SectionNames() []string {
+	var names []string
+	for name := range e.Sections {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (e *ResumeError) add(section string, err error) {
+	if e.Sections == nil {
+		e.Sections = make(map[string]error)
+	}
+	e.Sections[section] = err
+}
+
 func processConfigResume(config *string) (map[string]any, error) {
 	if config == nil {
 		return nil, errors.New("no config found")
 	}
-	return processConfig(config)
+	return parseConfig(config)
 }
 
 func processConfig(config *string) (map[string]any, error) {
+	cfg, err := parseConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ConfigTypeError reports config values from the server that aren't
+// wrapped like {"value": ...}.
+type ConfigTypeError struct {
+	// Types maps each offending key to the type of its value.
+	Types map[string]string
+}
+
+func (e *ConfigTypeError) Error() string {
+	var parts []string
+	for _, key := range slices.Sorted(maps.Keys(e.Types)) {
+		parts = append(parts, fmt.Sprintf("%s (%s)", key, e.Types[key]))
+	}
+	return fmt.Sprintf(
+		"unexpected types for config keys: %s",
+		strings.Join(parts, ", "),
+	)
+}
+
+// parseConfig unwraps the config values from the server.
+//
+// If some values have the wrong type, the others are returned along with a
+// *ConfigTypeError listing them.
+func parseConfig(config *string) (map[string]any, error) {
 	// If we are unable to parse the config, we should fail if resume is set to
 	// must for any other case of resume status, it is fine to ignore it
 	cfgVal, err := simplejsonext.UnmarshalString(*config)
@@ -37,16 +112,20 @@ func processConfig(config *string) (map[string]any, error) {
 	}
 
 	result := make(map[string]any)
+	typeErr := &ConfigTypeError{Types: make(map[string]string)}
 	for key, value := range cfg {
 		valueDict, ok := value.(map[string]any)
 		if !ok {
-			return nil, fmt.Errorf("unexpected type %T for %s", value, key)
+			typeErr.Types[key] = fmt.Sprintf("%T", value)
 		} else if val, ok := valueDict["value"]; ok {
 			result[key] = val
 		}
 	}
-	return result, nil
 
+	if len(typeErr.Types) > 0 {
+		return result, typeErr
+	}
+	return result, nil
 }
 
 // processSummary extracts the summary metrics from the data we get from the server
@@ -79,50 +158,107 @@ func processEventsTail(events *string) (map[string]any, error) {
 		return nil, errors.New("no events tail found")
 	}
 
-	// Since we just expect a list of strings, we unmarshal using the
-	// standard JSON library.
-	var eventsTail []string
-	if err := json.Unmarshal([]byte(*events), &eventsTail); err != nil {
-		return nil, err
-	}
-
-	// if we don't have any events, we have nothing to process
-	if len(eventsTail) == 0 {
-		return nil, nil
-	}
-
 	// We only care about the last event in the list
-	eventTail, err := simplejsonext.UnmarshalObjectString(eventsTail[len(eventsTail)-1])
-	if err != nil {
-		return nil, err
-	}
-
-	return eventTail, nil
+	return lastTailRow(*events)
 }
 
+// processHistory extracts the latest row from the history tail we get from
+// the server.
+//
+// The tail may hold several rows, and they're not guaranteed to be in
+// order, so the latest row is the one with the largest _step. Its _runtime
+// is the largest _runtime of any row, in case rows were logged with steps
+// out of order.
 func processHistory(history *string) (map[string]any, error) {
 	if history == nil {
 		return nil, errors.New("no history tail found")
 	}
 
-	// Since we just expect a list of strings, we unmarshal using the
-	// standard JSON library.
-	var histories []string
-	if err := json.Unmarshal([]byte(*history), &histories); err != nil {
+	rows, err := tailRows(*history)
+	if err != nil || len(rows) == 0 {
 		return nil, err
 	}
 
-	if len(histories) == 0 {
-		return nil, nil
+	latest := rows[len(rows)-1]
+	latestStep, hasStep := latest["_step"].(int64)
+	for _, row := range rows {
+		if step, ok := row["_step"].(int64); ok && (!hasStep || step > latestStep) {
+			latest, latestStep, hasStep = row, step, true
+		}
 	}
 
-	historyTail, err := simplejsonext.UnmarshalObjectString(histories[len(histories)-1])
+	for _, row := range rows {
+		runtime, ok := row["_runtime"]
+		if !ok {
+			continue
+		}
+		if current, ok := latest["_runtime"]; !ok ||
+			extractRuntime(runtime) > extractRuntime(current) {
+			latest["_runtime"] = runtime
+		}
+	}
 
-	if err != nil {
+	return latest, nil
+}
+
+// lastTailRow parses the last row of a history or events tail.
+//
+// Returns nil if the tail has no rows.
+func lastTailRow(tail string) (map[string]any, error) {
+	rows, err := tailRows(tail)
+	if err != nil || len(rows) == 0 {
 		return nil, err
 	}
 
-	return historyTail, nil
+	return rows[len(rows)-1], nil
+}
+
+// tailRows parses the rows of a history or events tail.
+//
+// The tail is a JSON array of rows. Rows are usually JSON-encoded objects
+// inside strings, like ["{\"_step\": 1}"], but newer servers may return
+// the objects themselves, like [{"_step": 1}]. Both forms are accepted.
+// Empty strings and nulls are skipped.
+func tailRows(tail string) ([]map[string]any, error) {
+	var rawRows []json.RawMessage
+	if err := json.Unmarshal([]byte(tail), &rawRows); err != nil {
+		return nil, err
+	}
+
+	rows := make([]map[string]any, 0, len(rawRows))
+	for _, raw := range rawRows {
+		row, err := parseTailRow(bytes.TrimSpace(raw))
+		if err != nil {
+			return nil, err
+		}
+		if row != nil {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// parseTailRow parses one row of a history or events tail.
+//
+// Returns nil if the row is empty.
+func parseTailRow(row []byte) (map[string]any, error) {
+	switch {
+	case bytes.HasPrefix(row, []byte(`"`)):
+		var encoded string
+		if err := json.Unmarshal(row, &encoded); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(encoded) == "" {
+			return nil, nil
+		}
+		return simplejsonext.UnmarshalObjectString(encoded)
+	case bytes.HasPrefix(row, []byte("{")):
+		return simplejsonext.UnmarshalObject(row)
+	case bytes.Equal(row, []byte("null")):
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("expected a string or an object, got %s", row)
+	}
 }
 
 func extractRuntime(runtime any) float64 {
@@ -135,26 +271,50 @@ func extractRuntime(runtime any) float64 {
 	return 0
 }
 
+// processAllOffsets builds the file stream offsets from the line counts the
+// server reports for the run.
+//
+// Missing counts are treated as zero, which may happen with older servers.
+// Negative counts are rejected, since they would corrupt the offsets.
 func processAllOffsets(history, events, logs *int) (filestream.FileStreamOffsetMap, error) {
 	filestreamOffset := make(filestream.FileStreamOffsetMap)
 
-	if history != nil {
-		filestreamOffset[filestream.HistoryChunk] = *history
-	} else {
-		return nil, errors.New("no history line count found")
+	counts := []struct {
+		chunk filestream.ChunkTypeEnum
+		name  string
+		count *int
+	}{
+		{filestream.HistoryChunk, "history", history},
+		{filestream.EventsChunk, "events", events},
+		{filestream.OutputChunk, "log", logs},
 	}
-
-	if events != nil {
-		filestreamOffset[filestream.EventsChunk] = *events
-	} else {
-		return nil, errors.New("no events line count found")
-	}
-
-	if logs != nil {
-		filestreamOffset[filestream.OutputChunk] = *logs
-	} else {
-		return nil, errors.New("no log line count found")
+	for _, c := range counts {
+		switch {
+		case c.count == nil:
+			filestreamOffset[c.chunk] = 0
+		case *c.count < 0:
+			return nil, fmt.Errorf("negative %s line count: %d", c.name, *c.count)
+		default:
+			filestreamOffset[c.chunk] = *c.count
+		}
 	}
 
 	return filestreamOffset, nil
 }
+
+// validateOffsets checks that the offsets are non-negative and don't move
+// backwards from the previously known offsets.
+func validateOffsets(previous, next filestream.FileStreamOffsetMap) error {
+	for chunk, offset := range next {
+		if offset < 0 {
+			return fmt.Errorf("negative offset %d for chunk %v", offset, chunk)
+		}
+		if prev, ok := previous[chunk]; ok && offset < prev {
+			return fmt.Errorf(
+				"offset for chunk %v moved backwards from %d to %d",
+				chunk, prev, offset,
+			)
+		}
+	}
+	return nil
+}
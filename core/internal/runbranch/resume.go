@@ -5,25 +5,262 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/Khan/genqlient/graphql"
 	"github.com/wandb/wandb/core/internal/filestream"
 	"github.com/wandb/wandb/core/internal/gql"
 	"github.com/wandb/wandb/core/internal/nullify"
+	"github.com/wandb/wandb/core/internal/observability"
 	spb "github.com/wandb/wandb/core/pkg/service_go_proto"
 )
 
+// Values of the `resume` setting.
+const (
+	// ResumeModeMust resumes the run, failing if it doesn't exist.
+	ResumeModeMust = "must"
+
+	// ResumeModeNever starts a new run, failing if the run already exists.
+	ResumeModeNever = "never"
+
+	// ResumeModeAllow resumes the run if it exists and starts a new run
+	// otherwise.
+	//
+	// Failing to restore the state of an existing run is reported as an
+	// error.
+	ResumeModeAllow = "allow"
+
+	// ResumeModeAuto resumes the run if it exists and starts a new run
+	// otherwise. It is what the Python SDK sends for `resume=True`.
+	//
+	// It behaves like ResumeModeAllow. In particular, failing to restore
+	// the state of an existing run is an error: starting it over as a new
+	// run would overwrite its history on the server.
+	ResumeModeAuto = "auto"
+)
+
+// ErrUnknownResumeMode indicates a value of the `resume` setting that is not
+// one of the known modes.
+var ErrUnknownResumeMode = errors.New("unknown resume mode")
+
+// ValidateResumeMode checks that the mode is one of the known resume modes.
+//
+// The empty string is valid and means that the run is not resumed.
+//
+// Unknown modes are still accepted by ResumeBranch, which treats them like
+// ResumeModeAllow; this lets callers report likely typos. Returns an error
+// wrapping ErrUnknownResumeMode.
+func ValidateResumeMode(mode string) error {
+	switch mode {
+	case "", ResumeModeMust, ResumeModeNever, ResumeModeAllow, ResumeModeAuto:
+		return nil
+	}
+
+	for _, known := range []string{
+		ResumeModeMust,
+		ResumeModeNever,
+		ResumeModeAllow,
+		ResumeModeAuto,
+	} {
+		if strings.EqualFold(strings.TrimSpace(mode), known) {
+			return fmt.Errorf("%w: %q (did you mean %q?)",
+				ErrUnknownResumeMode, mode, known)
+		}
+	}
+
+	return fmt.Errorf("%w: %q (expected one of %q, %q, %q or %q)",
+		ErrUnknownResumeMode, mode,
+		ResumeModeMust, ResumeModeNever, ResumeModeAllow, ResumeModeAuto)
+}
+
+// IsMustLike reports whether an unknown resume mode was probably meant to
+// be ResumeModeMust, like "Must" or " must".
+//
+// Such a mode should fail rather than be treated leniently, because the
+// user asked for the run to fail if it can't be resumed.
+func IsMustLike(mode string) bool {
+	return mode != ResumeModeMust &&
+		strings.EqualFold(strings.TrimSpace(mode), ResumeModeMust)
+}
+
 type ResumeBranch struct {
 	ctx    context.Context
 	client graphql.Client
 	mode   string
+	logger *observability.CoreLogger
+
+	// How long to wait for a created but unstarted run to start, or zero
+	// to not wait.
+	startTimeout time.Duration
+
+	// The first interval between checks while waiting for the run to start.
+	startPollInterval time.Duration
+
+	// How the resumed run's summary combines with the local summary.
+	summaryPolicy SummaryMergePolicy
+
+	// Whether config values with unexpected types are dropped rather than
+	// failing to restore the config.
+	dropMismatchedConfig bool
+
+	// The file to write a resume snapshot to, or empty to not write one.
+	snapshotPath string
+
+	// What the server reported about the run, set by GetUpdates.
+	stats *ResumeStats
 }
 
+// ResumeStats is what the server reported about a run being resumed.
+//
+// It helps debug a resumed run that starts at an unexpected step.
+type ResumeStats struct {
+	// The number of lines in the run's history, events and output files,
+	// which are the file stream offsets. Missing counts are zero.
+	HistoryLineCount int
+	EventsLineCount  int
+	LogLineCount     int
+
+	// The step of the last row in the history tail, or nil if the tail
+	// has no step.
+	HistoryTailStep *int64
+
+	// Where the resumed runtime came from: "history" or "events" for the
+	// history or events tail, "summary" for the summary, or "none" if the
+	// server reported no runtime and it defaults to zero.
+	//
+	// If several report a runtime, the largest is used.
+	RuntimeSource string
+
+	// The config keys whose values were dropped because they didn't have
+	// the expected form, if DropMismatchedConfig is set.
+	DroppedConfigKeys []string
+}
+
+// newResumeStats extracts the stats from the server's state for a run.
+func newResumeStats(data *gql.RunResumeStatusModelProjectBucketRun) *ResumeStats {
+	stats := &ResumeStats{}
+	if count := data.GetHistoryLineCount(); count != nil {
+		stats.HistoryLineCount = *count
+	}
+	if count := data.GetEventsLineCount(); count != nil {
+		stats.EventsLineCount = *count
+	}
+	if count := data.GetLogLineCount(); count != nil {
+		stats.LogLineCount = *count
+	}
+
+	if history, err := processHistory(data.GetHistoryTail()); err == nil {
+		if step, ok := history["_step"].(int64); ok {
+			stats.HistoryTailStep = &step
+		}
+	}
+
+	return stats
+}
+
+const (
+	// The default first interval between checks for a run to start.
+	defaultStartPollInterval = time.Second
+
+	// The maximum interval between checks for a run to start.
+	maxStartPollInterval = 30 * time.Second
+)
+
 // NewResumeBranch creates a new ResumeBranch
-func NewResumeBranch(ctx context.Context, client graphql.Client, mode string) *ResumeBranch {
-	return &ResumeBranch{ctx: ctx, client: client, mode: mode}
+//
+// The logger records how the resume mode and the run's server-side state
+// were resolved. It may be nil, in which case nothing is logged.
+func NewResumeBranch(
+	ctx context.Context,
+	client graphql.Client,
+	mode string,
+	logger *observability.CoreLogger,
+) *ResumeBranch {
+	if logger == nil {
+		logger = observability.NewNoOpLogger()
+	}
+	return &ResumeBranch{ctx: ctx, client: client, mode: mode, logger: logger}
+}
+
+// WaitForStart makes GetUpdates wait for a run that was created ahead of
+// time but hasn't started, like a sweep run that an agent picks up.
+//
+// The run's status is checked with exponential backoff, starting at
+// interval, until the run starts or the timeout elapses. A run that starts
+// is resumed; otherwise it is treated as not existing, as without waiting.
+// If timeout is zero, GetUpdates does not wait. If interval is not
+// positive, a default is used.
+func (rb *ResumeBranch) WaitForStart(timeout, interval time.Duration) *ResumeBranch {
+	if interval <= 0 {
+		interval = defaultStartPollInterval
+	}
+	rb.startTimeout = timeout
+	rb.startPollInterval = interval
+	return rb
+}
+
+// MergeSummaryWith sets how GetUpdates combines the resumed run's summary
+// with the summary in the params.
+//
+// By default, the resumed run's summary replaces the local one.
+func (rb *ResumeBranch) MergeSummaryWith(policy SummaryMergePolicy) *ResumeBranch {
+	rb.summaryPolicy = policy
+	return rb
+}
+
+// DropMismatchedConfig sets whether GetUpdates drops config values that
+// don't have the expected {"value": ...} form.
+//
+// By default, such values make the config fail to restore, so that a
+// resumed run doesn't silently lose hyperparameters. Like other sections
+// that fail, this fails resuming in 'must' mode and resumes without the
+// server's config in 'allow' and 'auto' mode.
+// When dropped, the other values are restored and the dropped keys are
+// logged.
+func (rb *ResumeBranch) DropMismatchedConfig(drop bool) *ResumeBranch {
+	rb.dropMismatchedConfig = drop
+	return rb
+}
+
+// SnapshotTo makes GetUpdates write what it parsed from the server's state
+// for the run, and the state the run resumes with, to a JSON file at path.
+//
+// This is diagnostic output for debugging resumed runs. The snapshot is
+// only written when resuming an existing run. Failing to write it is
+// logged and doesn't affect resuming. If path is empty, no snapshot is
+// written, which is the default.
+func (rb *ResumeBranch) SnapshotTo(path string) *ResumeBranch {
+	rb.snapshotPath = path
+	return rb
+}
+
+// Stats returns what the server reported about the run during the last
+// GetUpdates.
+//
+// It is nil if GetUpdates hasn't found an existing run.
+func (rb *ResumeBranch) Stats() *ResumeStats {
+	return rb.stats
+}
+
+// logDecision logs the outcome of resolving the resume mode for a run.
+func (rb *ResumeBranch) logDecision(
+	runpath RunPath,
+	started bool,
+	decision string,
+	args ...any,
+) {
+	rb.logger.Info(
+		"runbranch: resume: "+decision,
+		append([]any{
+			"project", runpath.Project,
+			"runId", runpath.RunID,
+			"mode", rb.mode,
+			"started", started,
+		}, args...)...,
+	)
 }
 
 // GetUpdates updates the state based on the resume mode
@@ -33,13 +270,12 @@ func (rb *ResumeBranch) GetUpdates(
 	runpath RunPath,
 ) (*RunParams, error) {
 
-	response, err := gql.RunResumeStatus(
-		rb.ctx,
-		rb.client,
-		&runpath.Project,
-		nullify.NilIfZero(runpath.Entity),
-		runpath.RunID,
-	)
+	response, err := rb.fetchResumeStatus(runpath)
+
+	// a run created ahead of time may be about to start
+	if err == nil && rb.startTimeout > 0 && runCreated(response) && !runExists(response) {
+		response, err = rb.waitForStart(runpath, response)
+	}
 
 	// if we get an error we are in an unknown state and we should raise an error
 	if err != nil {
@@ -50,20 +286,109 @@ func (rb *ResumeBranch) GetUpdates(
 		return nil, &BranchError{Err: err, Response: info}
 	}
 
-	var data *gql.RunResumeStatusModelProjectBucketRun
-	if runExists(response) {
-		data = response.GetModel().GetBucket()
+	decision := rb.decide(params, runpath, response)
+
+	if decision.data != nil {
+		rb.stats = decision.stats
+		var historyTailStep any
+		if rb.stats.HistoryTailStep != nil {
+			historyTailStep = *rb.stats.HistoryTailStep
+		}
+		rb.logger.Debug(
+			"runbranch: resume: server state",
+			"runId", runpath.RunID,
+			"historyLineCount", rb.stats.HistoryLineCount,
+			"eventsLineCount", rb.stats.EventsLineCount,
+			"logLineCount", rb.stats.LogLineCount,
+			"historyTailStep", historyTailStep,
+		)
 	}
 
-	// if we are not in the resume mode MUST and we didn't get data, we can just
-	// return without error
-	if data == nil && rb.mode != "must" {
-		return nil, nil
+	if decision.restored {
+		if len(rb.stats.DroppedConfigKeys) > 0 {
+			rb.logger.Warn(
+				"runbranch: resume: dropped config values with unexpected types",
+				"runId", runpath.RunID,
+				"keys", rb.stats.DroppedConfigKeys,
+			)
+		}
+
+		if rb.snapshotPath != "" {
+			err := writeResumeSnapshot(
+				rb.snapshotPath,
+				decision.data,
+				decision.restoredParams,
+				decision.restoreErr,
+			)
+			if err != nil {
+				rb.logger.Warn(
+					"runbranch: resume: failed to write snapshot",
+					"path", rb.snapshotPath,
+					"error", err,
+				)
+			}
+		}
 	}
 
-	// if we are in the resume mode MUST and we don't have data (the run is not initialized),
-	// we need to return an error because we can't resume
-	if data == nil && rb.mode == "must" {
+	rb.logDecision(runpath, decision.data != nil, decision.outcome,
+		decision.logArgs...)
+	return decision.update, decision.err
+}
+
+// resumeDecision is how a run resumes, given its state on the server.
+type resumeDecision struct {
+	// update is the state the run resumes with, or nil if it starts as a
+	// new run or fails to start.
+	update *RunParams
+
+	// err is the error GetUpdates returns.
+	err error
+
+	// data is the run's state on the server, or nil if the run doesn't
+	// exist.
+	data *gql.RunResumeStatusModelProjectBucketRun
+
+	// stats describes data, if there is any.
+	stats *ResumeStats
+
+	// restored is whether the run's state was restored from data, which
+	// may have failed, in which case restoreErr is set.
+	restored bool
+
+	// restoredParams and restoreErr are the results of restoring the
+	// run's state, before the resume mode is applied.
+	restoredParams *RunParams
+	restoreErr     error
+
+	// outcome and logArgs describe the decision for logDecision.
+	outcome string
+	logArgs []any
+}
+
+// decide resolves the resume mode given the run's state on the server.
+//
+// It has no side effects: it doesn't query the server, log, write a
+// snapshot or modify params or the branch.
+func (rb *ResumeBranch) decide(
+	params *RunParams,
+	runpath RunPath,
+	response *gql.RunResumeStatusResponse,
+) *resumeDecision {
+	decision := &resumeDecision{}
+	if runExists(response) {
+		decision.data = response.GetModel().GetBucket()
+		decision.stats = newResumeStats(decision.data)
+	}
+
+	switch {
+	// if we are not in the resume mode MUST and we didn't get data, we can
+	// just return without error
+	case decision.data == nil && rb.mode != ResumeModeMust:
+		decision.outcome = "starting new run"
+
+	// if we are in the resume mode MUST and we don't have data (the run is
+	// not initialized), we need to return an error because we can't resume
+	case decision.data == nil:
 		info := &spb.ErrorInfo{
 			Code: spb.ErrorInfo_USAGE,
 			Message: fmt.Sprintf("You provided an invalid value for the `resume` argument."+
@@ -72,13 +397,13 @@ func (rb *ResumeBranch) GetUpdates(
 				" If you are trying to start a new run, please omit the `resume` argument or use `resume='allow'`.",
 				runpath.RunID),
 		}
-		err = errors.New("no data but must resume")
-		return nil, &BranchError{Err: err, Response: info}
-	}
+		err := errors.New("no data but must resume")
+		decision.err = &BranchError{Err: err, Response: info}
+		decision.outcome = "failing, run does not exist"
 
-	// if we have data and we are in a never resume mode we need to return an
-	// error because we are not allowed to resume
-	if data != nil && rb.mode == "never" {
+	// if we have data and we are in a never resume mode we need to return
+	// an error because we are not allowed to resume
+	case rb.mode == ResumeModeNever:
 		info := &spb.ErrorInfo{
 			Code: spb.ErrorInfo_USAGE,
 			Message: fmt.Sprintf("You provided an invalid value for the `resume` argument."+
@@ -86,28 +411,194 @@ func (rb *ResumeBranch) GetUpdates(
 				"  Please check your inputs and try again with a valid value for the `resume` argument.",
 				runpath.RunID),
 		}
-		err = errors.New("data but cannot resume")
-		return nil, &BranchError{Err: err, Response: info}
+		err := errors.New("data but cannot resume")
+		decision.err = &BranchError{Err: err, Response: info}
+		decision.outcome = "failing, run already exists"
+
+	// if we have data and we are in the MUST, ALLOW or AUTO resume mode, we
+	// can resume the run
+	default:
+		rb.decideResume(params, runpath, decision)
 	}
 
-	// if we have data and we are in the MUST or ALLOW resume mode, we can resume the run
-	if data != nil && rb.mode != "never" {
-		update, err := processResponse(params, data)
-		if err != nil && rb.mode == "must" {
-			info := &spb.ErrorInfo{
-				Code: spb.ErrorInfo_USAGE,
-				Message: fmt.Sprintf("The run (%s) failed to resume, and the `resume` argument is set to 'must'.",
-					runpath.RunID),
-			}
-			err = fmt.Errorf("could not resume run: %s", err)
-			return nil, &BranchError{Err: err, Response: info}
-		} else if err != nil {
+	return decision
+}
+
+// decideResume restores the state of a run that exists on the server and
+// applies the resume mode to the result.
+func (rb *ResumeBranch) decideResume(
+	params *RunParams,
+	runpath RunPath,
+	decision *resumeDecision,
+) {
+	update, err := processResponse(
+		params,
+		decision.data,
+		rb.summaryPolicy,
+		rb.dropMismatchedConfig,
+		decision.stats,
+	)
+	decision.restored = true
+	decision.restoredParams = update
+	decision.restoreErr = err
+
+	if err != nil && rb.mode == ResumeModeMust {
+		message := fmt.Sprintf("The run (%s) failed to resume, and the `resume` argument is set to 'must'.",
+			runpath.RunID)
+		var resumeErr *ResumeError
+		if errors.As(err, &resumeErr) {
+			message += fmt.Sprintf(" Could not restore: %s.",
+				strings.Join(resumeErr.SectionNames(), ", "))
+		}
+		info := &spb.ErrorInfo{
+			Code:    spb.ErrorInfo_USAGE,
+			Message: message,
+		}
+		err = fmt.Errorf("could not resume run: %s", err)
+		decision.err = &BranchError{Err: err, Response: info}
+		decision.outcome = "failing, could not resume"
+		decision.logArgs = []any{"error", err}
+		return
+	}
+
+	// in ALLOW and AUTO mode, a partially restored state is still returned
+	// so that the run resumes with whatever could be recovered
+	decision.update = update
+	decision.err = err
+	decision.logArgs = []any{
+		"startingStep", update.GetStartingStep(),
+		"runtime", update.GetRuntime(),
+		"runtimeSource", decision.stats.RuntimeSource,
+	}
+	if err != nil {
+		decision.outcome = "resuming run partially"
+		decision.logArgs = append(decision.logArgs, "error", err)
+	} else {
+		decision.outcome = "resuming run"
+	}
+}
+
+// ResumePreview describes how a run would be resumed.
+type ResumePreview struct {
+	// Resumes is whether the run would resume an existing run, rather
+	// than start as a new run.
+	Resumes bool
+
+	// StartingStep is the next step that would be written.
+	StartingStep int64
+
+	// Runtime is the runtime of the resumed run, in seconds.
+	Runtime int32
+
+	// FileStreamOffset is where each file stream chunk would continue.
+	FileStreamOffset filestream.FileStreamOffsetMap
+
+	// Tags are the run's tags after merging in the resumed run's tags.
+	Tags []string
+}
+
+// Preview reports how the run would be resumed given its state on the
+// server, the response to the RunResumeStatus query, without resuming it.
+//
+// It applies the same validation as GetUpdates and returns the same
+// errors, but has none of its side effects: it doesn't query the server,
+// wait for a created run to start, write a snapshot or log. Neither params
+// nor the branch is modified. The tags are merged according to tagPolicy,
+// as they would be when the run is resumed.
+//
+// If the run would start as a new run, the preview describes params
+// unchanged with Resumes set to false.
+func (rb *ResumeBranch) Preview(
+	params *RunParams,
+	runpath RunPath,
+	response *gql.RunResumeStatusResponse,
+	tagPolicy TagMergePolicy,
+) (*ResumePreview, error) {
+	decision := rb.decide(params, runpath, response)
+	update, err := decision.update, decision.err
+	if update == nil {
+		if err != nil {
 			return nil, err
 		}
-		return update, nil
+
+		preview := &ResumePreview{}
+		if params != nil {
+			preview.StartingStep = params.StartingStep
+			preview.Runtime = params.Runtime
+			preview.FileStreamOffset = maps.Clone(params.FileStreamOffset)
+			preview.Tags = slices.Clone(params.Tags)
+		}
+		return preview, nil
+	}
+
+	var localTags []string
+	if params != nil {
+		localTags = params.Tags
+	}
+
+	return &ResumePreview{
+		Resumes:          true,
+		StartingStep:     update.StartingStep,
+		Runtime:          update.Runtime,
+		FileStreamOffset: update.FileStreamOffset,
+		Tags:             MergeTags(tagPolicy, update.Tags, localTags),
+	}, err
+}
+
+// fetchResumeStatus queries the run's state on the server.
+func (rb *ResumeBranch) fetchResumeStatus(
+	runpath RunPath,
+) (*gql.RunResumeStatusResponse, error) {
+	return gql.RunResumeStatus(
+		rb.ctx,
+		rb.client,
+		&runpath.Project,
+		nullify.NilIfZero(runpath.Entity),
+		runpath.RunID,
+	)
+}
+
+// waitForStart polls the run's state until it starts or the start timeout
+// elapses, returning the last response.
+func (rb *ResumeBranch) waitForStart(
+	runpath RunPath,
+	response *gql.RunResumeStatusResponse,
+) (*gql.RunResumeStatusResponse, error) {
+	rb.logDecision(runpath, false, "waiting for created run to start",
+		"timeout", rb.startTimeout)
+
+	deadline := time.Now().Add(rb.startTimeout)
+	interval := rb.startPollInterval
+
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			rb.logDecision(runpath, false, "created run did not start in time")
+			return response, nil
+		}
+
+		select {
+		case <-rb.ctx.Done():
+			return nil, rb.ctx.Err()
+		case <-time.After(min(interval, remaining)):
+		}
+
+		var err error
+		response, err = rb.fetchResumeStatus(runpath)
+		if err != nil || runExists(response) {
+			return response, err
+		}
+
+		interval = min(2*interval, maxStartPollInterval)
 	}
+}
 
-	return nil, nil
+// runCreated checks if the server has a record of the run, whether or not
+// it has started.
+func runCreated(response *gql.RunResumeStatusResponse) bool {
+	return response != nil &&
+		response.GetModel() != nil &&
+		response.GetModel().GetBucket() != nil
 }
 
 // runExists checks if the run exists based on the response we get from the server
@@ -141,15 +632,31 @@ func runExists(response *gql.RunResumeStatusResponse) bool {
 
 // processResponse extracts the run state from the data we get from the server
 //
+// Sections of the state that fail to parse are skipped and reported in a
+// *ResumeError alongside the partial state. If the file stream offsets can't
+// be determined, nothing can be resumed safely and the returned state is nil.
+// Where the runtime came from, and which config values were dropped if
+// dropMismatchedConfig is set, is recorded in stats.
+//
 //gocyclo:ignore
-func processResponse(params *RunParams, data *gql.RunResumeStatusModelProjectBucketRun) (*RunParams, error) {
+func processResponse(
+	params *RunParams,
+	data *gql.RunResumeStatusModelProjectBucketRun,
+	summaryPolicy SummaryMergePolicy,
+	dropMismatchedConfig bool,
+	stats *ResumeStats,
+) (*RunParams, error) {
 	r := params.Clone()
+	resumeErr := &ResumeError{}
 
-	// Get Config information
-	if config, err := processConfigResume(data.GetConfig()); err != nil {
-		return nil, err
-	} else if config != nil {
-		r.Config = config
+	// The runtime is the largest one the server reports, so that the
+	// wall-clock time continues where the run left off.
+	stats.RuntimeSource = "none"
+	updateRuntime := func(runtime any, source string) {
+		if value := int32(extractRuntime(runtime)); value > r.Runtime {
+			r.Runtime = value
+			stats.RuntimeSource = source
+		}
 	}
 
 	if filestreamOffset, err := processAllOffsets(
@@ -157,26 +664,43 @@ func processResponse(params *RunParams, data *gql.RunResumeStatusModelProjectBuc
 		data.GetEventsLineCount(),
 		data.GetLogLineCount(),
 	); err != nil {
-		return nil, err
+		resumeErr.add("offsets", err)
+		return nil, resumeErr
+	} else if err := validateOffsets(r.FileStreamOffset, filestreamOffset); err != nil {
+		resumeErr.add("offsets", err)
+		return nil, resumeErr
 	} else if filestreamOffset != nil {
 		r.Merge(&RunParams{FileStreamOffset: filestreamOffset})
 	}
 
+	// Get Config information
+	config, err := processConfigResume(data.GetConfig())
+	var typeErr *ConfigTypeError
+	if dropMismatchedConfig && errors.As(err, &typeErr) {
+		stats.DroppedConfigKeys = slices.Sorted(maps.Keys(typeErr.Types))
+		err = nil
+	}
+	if err != nil {
+		resumeErr.add("config", err)
+	} else if config != nil {
+		r.Config = config
+	}
+
 	// extract runtime from the events tail if it exists we will use the maximal
 	// value of runtime that we find
 	if events, err := processEventsTail(data.GetEventsTail()); err != nil {
-		return nil, err
+		resumeErr.add("events", err)
 	} else if events != nil {
 		if runtime, ok := events["_runtime"]; ok {
-			r.Runtime = int32(math.Max(extractRuntime(runtime), float64(r.Runtime)))
+			updateRuntime(runtime, "events")
 		}
 	}
 
 	// Get Summary information
 	if summary, err := processSummary(data.GetSummaryMetrics()); err != nil {
-		return nil, err
+		resumeErr.add("summary", err)
 	} else if summary != nil {
-		r.Summary = summary
+		r.Summary = MergeSummary(summaryPolicy, summary, r.Summary)
 
 		if step, ok := summary["_step"]; ok {
 			// if we are resuming, we need to update the starting step
@@ -188,14 +712,14 @@ func processResponse(params *RunParams, data *gql.RunResumeStatusModelProjectBuc
 
 		// if summary["wandb"]["runtime"] exists it takes precedence over
 		// summary["_runtime"] for the runtime value
-		switch x := r.Summary["wandb"].(type) {
+		switch x := summary["wandb"].(type) {
 		case map[string]any:
 			if runtime, ok := x["runtime"]; ok {
-				r.Runtime = int32(math.Max(extractRuntime(runtime), float64(r.Runtime)))
+				updateRuntime(runtime, "summary")
 			}
 		default:
-			if runtime, ok := r.Summary["_runtime"]; ok {
-				r.Runtime = int32(math.Max(extractRuntime(runtime), float64(r.Runtime)))
+			if runtime, ok := summary["_runtime"]; ok {
+				updateRuntime(runtime, "summary")
 			}
 		}
 	}
@@ -203,7 +727,7 @@ func processResponse(params *RunParams, data *gql.RunResumeStatusModelProjectBuc
 	// TODO: do we need both history and summary? is it a legacy from old
 	// versions of the backend?
 	if history, err := processHistory(data.GetHistoryTail()); err != nil {
-		return nil, err
+		resumeErr.add("history", err)
 	} else if history != nil {
 		if step, ok := history["_step"]; ok {
 			// if we are resuming, we need to update the starting step
@@ -214,7 +738,7 @@ func processResponse(params *RunParams, data *gql.RunResumeStatusModelProjectBuc
 		}
 
 		if runtime, ok := history["_runtime"]; ok {
-			r.Runtime = int32(math.Max(extractRuntime(runtime), float64(r.Runtime)))
+			updateRuntime(runtime, "history")
 		}
 	}
 
@@ -237,5 +761,8 @@ func processResponse(params *RunParams, data *gql.RunResumeStatusModelProjectBuc
 
 	r.Resumed = true
 
+	if len(resumeErr.Sections) > 0 {
+		return r, resumeErr
+	}
 	return r, nil
 }
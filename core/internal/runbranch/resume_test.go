@@ -1,14 +1,21 @@
 package runbranch_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"log/slog"
+	"math"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wandb/simplejsonext"
 	"github.com/wandb/wandb/core/internal/filestream"
+	"github.com/wandb/wandb/core/internal/gql"
 	"github.com/wandb/wandb/core/internal/gqlmock"
+	"github.com/wandb/wandb/core/internal/observability"
 	"github.com/wandb/wandb/core/internal/runbranch"
 )
 
@@ -41,7 +48,8 @@ func TestNeverResumeEmptyResponse(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"never")
+		"never",
+		observability.NewNoOpLogger())
 	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 	assert.Nil(t, params, "GetUpdates should return nil when response is empty")
 	assert.Nil(t, err, "GetUpdates should not return an error")
@@ -56,7 +64,8 @@ func TestAllowResumeEmptyResponse(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"allow")
+		"allow",
+		observability.NewNoOpLogger())
 	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 	assert.Nil(t, params, "GetUpdates should return nil when response is empty")
 	assert.Nil(t, err, "GetUpdates should not return an error")
@@ -71,7 +80,8 @@ func TestMustResumeEmptyResponse(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"must")
+		"must",
+		observability.NewNoOpLogger())
 	updates, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 	assert.Nil(t, updates, "GetUpdates should return nil when response is invalid")
 	assert.NotNil(t, err, "GetUpdates should return an error")
@@ -89,7 +99,8 @@ func TestMustResumeNilResponse(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"must")
+		"must",
+		observability.NewNoOpLogger())
 	updates, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 	assert.Nil(t, updates, "GetUpdates should return nil when response is invalid")
 	assert.NotNil(t, err, "GetUpdates should return an error")
@@ -97,6 +108,34 @@ func TestMustResumeNilResponse(t *testing.T) {
 	assert.NotNil(t, err.(*runbranch.BranchError).Response, "BranchError should have a response")
 }
 
+func TestMustResumeMissingRunLogsDecision(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+	mockGQL.StubMatchOnce(
+		gqlmock.WithOpName("RunResumeStatus"),
+		`{}`,
+	)
+	var logs bytes.Buffer
+	logger := observability.NewCoreLogger(
+		slog.New(slog.NewTextHandler(&logs, nil)))
+
+	resumeState := runbranch.NewResumeBranch(
+		context.Background(),
+		mockGQL,
+		"must",
+		logger)
+	_, err := resumeState.GetUpdates(nil, runbranch.RunPath{
+		Project: "test-project",
+		RunID:   "test-run",
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, logs.String(), "runbranch: resume: failing, run does not exist")
+	assert.Contains(t, logs.String(), "project=test-project")
+	assert.Contains(t, logs.String(), "runId=test-run")
+	assert.Contains(t, logs.String(), "mode=must")
+	assert.Contains(t, logs.String(), "started=false")
+}
+
 func TestNeverResumeNoneEmptyResponse(t *testing.T) {
 	mockGQL := gqlmock.NewMockClient()
 	history := "[]"
@@ -124,7 +163,8 @@ func TestNeverResumeNoneEmptyResponse(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"never")
+		"never",
+		observability.NewNoOpLogger())
 	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 	assert.Nil(t, params, "GetUpdates should return nil when response is empty")
 	assert.NotNil(t, err, "GetUpdates should return an error")
@@ -159,7 +199,8 @@ func TestMustResumeNoTelemetryInConfig(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"must")
+		"must",
+		observability.NewNoOpLogger())
 	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 	assert.Nil(t, params, "GetUpdates should return nil when response is empty")
 	assert.NotNil(t, err, "GetUpdates should return an error")
@@ -201,12 +242,71 @@ func TestAllowResumeNoneEmptyResponse(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"allow")
+		"allow",
+		observability.NewNoOpLogger())
 	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 	assert.NotNil(t, params, "GetUpdates should return nil when response is empty")
 	assert.Nil(t, err, "GetUpdates should not return an error")
 }
 
+func TestAutoResumeEmptyResponse(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+	mockGQL.StubMatchOnce(
+		gqlmock.WithOpName("RunResumeStatus"),
+		`{}`,
+	)
+	resumeState := runbranch.NewResumeBranch(
+		context.Background(),
+		mockGQL,
+		runbranch.ResumeModeAuto,
+		observability.NewNoOpLogger())
+	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
+	assert.Nil(t, params, "GetUpdates should return nil when response is empty")
+	assert.Nil(t, err, "GetUpdates should not return an error")
+}
+
+func TestAutoResumeNoneEmptyResponse(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+
+	historyLineCount := 0
+	eventsLineCount := 0
+	logLineCount := 0
+	history := "[]"
+	config := "{}"
+	summary := "{}"
+	rr := ResumeResponse{
+		Model: Model{
+			Bucket: Bucket{
+				Name:             "FakeName",
+				HistoryLineCount: &historyLineCount,
+				EventsLineCount:  &eventsLineCount,
+				LogLineCount:     &logLineCount,
+				HistoryTail:      &history,
+				SummaryMetrics:   &summary,
+				Config:           &config,
+				EventsTail:       "[]",
+				WandbConfig:      `{"t": 1}`,
+			},
+		},
+	}
+
+	jsonData, err := json.MarshalIndent(rr, "", "    ")
+	assert.Nil(t, err, "Failed to marshal json data")
+
+	mockGQL.StubMatchOnce(
+		gqlmock.WithOpName("RunResumeStatus"),
+		string(jsonData),
+	)
+	resumeState := runbranch.NewResumeBranch(
+		context.Background(),
+		mockGQL,
+		runbranch.ResumeModeAuto,
+		observability.NewNoOpLogger())
+	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
+	assert.NotNil(t, params, "GetUpdates should return updates for an existing run")
+	assert.Nil(t, err, "GetUpdates should not return an error")
+}
+
 func TestMustResumeNoneEmptyResponse(t *testing.T) {
 	mockGQL := gqlmock.NewMockClient()
 
@@ -241,7 +341,8 @@ func TestMustResumeNoneEmptyResponse(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"must")
+		"must",
+		observability.NewNoOpLogger())
 	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 	assert.NotNil(t, params, "GetUpdates should return nil when response is empty")
 	assert.Nil(t, err, "GetUpdates should not return an error")
@@ -282,15 +383,48 @@ func TestMustResumeValidHistory(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"must")
+		"must",
+		observability.NewNoOpLogger())
 	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 	assert.NotNil(t, params, "GetUpdates should return nil when response is empty")
 	assert.Equal(t, int64(2), params.StartingStep, "GetUpdates should return correct starting step")
 	assert.Equal(t, int32(50), params.Runtime, "GetUpdates should return correct runtime")
 	assert.True(t, params.Resumed, "GetUpdates should return correct resumed state")
+	assert.Equal(t, runbranch.BranchResume, params.Type())
 	assert.Nil(t, err, "GetUpdates should not return an error")
 }
 
+func TestResumedRunStartingStepAndRuntime(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+
+	history := `["{\"_step\":4,\"_runtime\":30}"]`
+	config := "{}"
+	summary := `{"_step": 4, "_runtime": 30}`
+	historyLineCount := 5
+	rr := ResumeResponse{
+		Model: Model{
+			Bucket: Bucket{
+				Name:             "FakeName",
+				HistoryLineCount: &historyLineCount,
+				HistoryTail:      &history,
+				SummaryMetrics:   &summary,
+				Config:           &config,
+				EventsTail:       "[]",
+				WandbConfig:      `{"t": 1}`,
+			},
+		},
+	}
+	stubResumeResponse(t, mockGQL, rr)
+
+	params, err := runbranch.NewResumeBranch(context.Background(), mockGQL, "must", observability.NewNoOpLogger()).
+		GetUpdates(nil, runbranch.RunPath{})
+
+	assert.NoError(t, err)
+	// the last step written was 4, so the next one is 5
+	assert.Equal(t, int64(5), params.GetStartingStep())
+	assert.Equal(t, int32(30), params.GetRuntime())
+}
+
 func TestMustResumeZeroHisotry(t *testing.T) {
 	mockGQL := gqlmock.NewMockClient()
 
@@ -326,7 +460,8 @@ func TestMustResumeZeroHisotry(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"must")
+		"must",
+		observability.NewNoOpLogger())
 	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 	assert.NotNil(t, params, "GetUpdates should return nil when response is empty")
 	assert.Equal(t, int64(0), params.StartingStep, "GetUpdates should return correct starting step")
@@ -370,7 +505,8 @@ func TestMustResumeHistoryTailStepZero(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"must")
+		"must",
+		observability.NewNoOpLogger())
 
 	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 	assert.NotNil(t, params, "GetUpdates should return nil when response is empty")
@@ -415,7 +551,8 @@ func TestMustResumeValidSummary(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"must")
+		"must",
+		observability.NewNoOpLogger())
 
 	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 	assert.NotNil(t, params, "GetUpdates should return nil when response is empty")
@@ -432,6 +569,66 @@ func TestMustResumeValidSummary(t *testing.T) {
 	assert.Nil(t, err, "GetUpdates should not return an error")
 }
 
+func TestMustResumeNonFiniteValues(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+
+	history := `["{\"_step\":1}"]`
+	config := `{"lr": {"value": Infinity}, "momentum": {"value": NaN}}`
+	summary := `{"nan": NaN, "inf": Infinity, "neg_inf": -Infinity, "_step": 1}`
+	historyLineCount := 1
+	eventsLineCount := 0
+	logLineCount := 0
+	rr := ResumeResponse{
+		Model: Model{
+			Bucket: Bucket{
+				Name:             "FakeName",
+				HistoryLineCount: &historyLineCount,
+				EventsLineCount:  &eventsLineCount,
+				LogLineCount:     &logLineCount,
+				HistoryTail:      &history,
+				SummaryMetrics:   &summary,
+				Config:           &config,
+				EventsTail:       "[]",
+				WandbConfig:      `{"t": 1}`,
+			},
+		},
+	}
+
+	jsonData, err := json.MarshalIndent(rr, "", "    ")
+	assert.Nil(t, err, "Failed to marshal json data")
+
+	mockGQL.StubMatchOnce(
+		gqlmock.WithOpName("RunResumeStatus"),
+		string(jsonData),
+	)
+	resumeState := runbranch.NewResumeBranch(
+		context.Background(),
+		mockGQL,
+		"must",
+		observability.NewNoOpLogger())
+
+	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
+	assert.Nil(t, err, "GetUpdates should not return an error")
+	assert.NotNil(t, params, "GetUpdates should return params")
+
+	assert.True(t, math.IsNaN(params.Summary["nan"].(float64)))
+	assert.True(t, math.IsInf(params.Summary["inf"].(float64), 1))
+	assert.True(t, math.IsInf(params.Summary["neg_inf"].(float64), -1))
+	assert.True(t, math.IsInf(params.Config["lr"].(float64), 1))
+	assert.True(t, math.IsNaN(params.Config["momentum"].(float64)))
+
+	// the special values must survive being serialized again
+	encoded, err := simplejsonext.Marshal(params.Summary["inf"])
+	assert.Nil(t, err)
+	assert.Equal(t, "Infinity", string(encoded))
+	encoded, err = simplejsonext.Marshal(params.Summary["neg_inf"])
+	assert.Nil(t, err)
+	assert.Equal(t, "-Infinity", string(encoded))
+	encoded, err = simplejsonext.Marshal(params.Summary["nan"])
+	assert.Nil(t, err)
+	assert.Equal(t, "NaN", string(encoded))
+}
+
 func TestMustResumeValidConfig(t *testing.T) {
 
 	mockGQL := gqlmock.NewMockClient()
@@ -468,7 +665,8 @@ func TestMustResumeValidConfig(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"must")
+		"must",
+		observability.NewNoOpLogger())
 
 	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 	assert.Nil(t, err, "GetUpdates should not return an error")
@@ -516,7 +714,8 @@ func TestMustResumeValidTags(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"must")
+		"must",
+		observability.NewNoOpLogger())
 
 	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 	assert.Nil(t, err, "GetUpdates should not return an error")
@@ -565,7 +764,8 @@ func TestMustResumeValidStorageId(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"must")
+		"must",
+		observability.NewNoOpLogger())
 
 	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 	assert.Nil(t, err, "GetUpdates should not return an error")
@@ -613,7 +813,8 @@ func TestMustResumeValidEvents(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"must")
+		"must",
+		observability.NewNoOpLogger())
 
 	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 	assert.Nil(t, err, "GetUpdates should not return an error")
@@ -703,7 +904,8 @@ func TestMustResumeNullValue(t *testing.T) {
 			resumeState := runbranch.NewResumeBranch(
 				context.Background(),
 				mockGQL,
-				"must")
+				"must",
+				observability.NewNoOpLogger())
 
 			params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 			assert.NotNil(t, err, "GetUpdates should return an error")
@@ -780,7 +982,8 @@ func TestAllowResumeNullValue(t *testing.T) {
 			resumeState := runbranch.NewResumeBranch(
 				context.Background(),
 				mockGQL,
-				"allow")
+				"allow",
+				observability.NewNoOpLogger())
 
 			params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 			assert.NotNil(t, err, "GetUpdates should return an error")
@@ -788,7 +991,11 @@ func TestAllowResumeNullValue(t *testing.T) {
 				t.Errorf("expected a BranchError but got %T", err)
 			}
 
-			assert.Nil(t, params, "GetUpdates should return nil when response is empty")
+			var resumeErr *runbranch.ResumeError
+			assert.ErrorAs(t, err, &resumeErr, "GetUpdates should return a ResumeError")
+			assert.NotNil(t, params, "GetUpdates should return the partial state")
+			assert.Equal(t, 0, params.FileStreamOffset[filestream.HistoryChunk],
+				"missing line counts should be treated as zero")
 		})
 	}
 }
@@ -843,7 +1050,8 @@ func TestMustResumeInvalidHistory(t *testing.T) {
 			resumeState := runbranch.NewResumeBranch(
 				context.Background(),
 				mockGQL,
-				"must")
+				"must",
+				observability.NewNoOpLogger())
 
 			params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 			assert.NotNil(t, err, "GetUpdates should return an error")
@@ -854,6 +1062,159 @@ func TestMustResumeInvalidHistory(t *testing.T) {
 	}
 }
 
+func TestAutoResumeInvalidHistoryIsAnError(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+
+	config := "{}"
+	summary := "{}"
+	history := `["invalid_history"]`
+	historyLineCount := 0
+	eventsLineCount := 0
+	logLineCount := 0
+	rr := ResumeResponse{
+		Model: Model{
+			Bucket: Bucket{
+				Name:             "FakeName",
+				HistoryLineCount: &historyLineCount,
+				EventsLineCount:  &eventsLineCount,
+				LogLineCount:     &logLineCount,
+				HistoryTail:      &history,
+				SummaryMetrics:   &summary,
+				Config:           &config,
+				EventsTail:       `[]`,
+				WandbConfig:      `{"t": 1}`,
+			},
+		},
+	}
+
+	jsonData, err := json.MarshalIndent(rr, "", "    ")
+	assert.Nil(t, err, "Failed to marshal json data")
+
+	mockGQL.StubMatchOnce(
+		gqlmock.WithOpName("RunResumeStatus"),
+		string(jsonData),
+	)
+	resumeState := runbranch.NewResumeBranch(
+		context.Background(),
+		mockGQL,
+		runbranch.ResumeModeAuto,
+		observability.NewNoOpLogger())
+
+	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
+
+	// Starting the existing run over would overwrite its history.
+	var resumeErr *runbranch.ResumeError
+	assert.ErrorAs(t, err, &resumeErr)
+	assert.Equal(t, []string{"history"}, resumeErr.SectionNames())
+	assert.NotNil(t, params, "GetUpdates should return the partial state")
+	assert.True(t, params.Resumed)
+}
+
+func TestAllowResumePartialFailure(t *testing.T) {
+	invalid := `not json`
+	testCases := []struct {
+		section string
+		modify  func(*Bucket)
+	}{
+		{"config", func(b *Bucket) { b.Config = &invalid }},
+		{"events", func(b *Bucket) { b.EventsTail = invalid }},
+		{"history", func(b *Bucket) { b.HistoryTail = &invalid }},
+		{"summary", func(b *Bucket) { b.SummaryMetrics = &invalid }},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.section, func(t *testing.T) {
+			mockGQL := gqlmock.NewMockClient()
+
+			history := `["{\"_step\":1}"]`
+			config := `{"lr": {"value": 0.1}}`
+			summary := `{"loss": 0.5}`
+			historyLineCount := 2
+			eventsLineCount := 0
+			logLineCount := 0
+			bucket := Bucket{
+				Name:             "FakeName",
+				HistoryLineCount: &historyLineCount,
+				EventsLineCount:  &eventsLineCount,
+				LogLineCount:     &logLineCount,
+				HistoryTail:      &history,
+				SummaryMetrics:   &summary,
+				Config:           &config,
+				EventsTail:       "[]",
+				WandbConfig:      `{"t": 1}`,
+			}
+			tc.modify(&bucket)
+
+			jsonData, err := json.Marshal(ResumeResponse{Model: Model{Bucket: bucket}})
+			assert.Nil(t, err, "Failed to marshal json data")
+
+			mockGQL.StubMatchOnce(
+				gqlmock.WithOpName("RunResumeStatus"),
+				string(jsonData),
+			)
+			resumeState := runbranch.NewResumeBranch(
+				context.Background(),
+				mockGQL,
+				runbranch.ResumeModeAllow,
+				observability.NewNoOpLogger())
+
+			params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
+
+			var resumeErr *runbranch.ResumeError
+			assert.ErrorAs(t, err, &resumeErr)
+			assert.Equal(t, []string{tc.section}, resumeErr.SectionNames())
+			assert.NotNil(t, params, "GetUpdates should return the partial state")
+			assert.True(t, params.Resumed)
+			assert.Equal(t, 2, params.FileStreamOffset[filestream.HistoryChunk])
+		})
+	}
+}
+
+func TestMustResumePartialFailureReportsSections(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+
+	invalid := `not json`
+	historyLineCount := 0
+	eventsLineCount := 0
+	logLineCount := 0
+	rr := ResumeResponse{
+		Model: Model{
+			Bucket: Bucket{
+				Name:             "FakeName",
+				HistoryLineCount: &historyLineCount,
+				EventsLineCount:  &eventsLineCount,
+				LogLineCount:     &logLineCount,
+				HistoryTail:      &invalid,
+				SummaryMetrics:   &invalid,
+				Config:           &invalid,
+				EventsTail:       "[]",
+				WandbConfig:      `{"t": 1}`,
+			},
+		},
+	}
+
+	jsonData, err := json.Marshal(rr)
+	assert.Nil(t, err, "Failed to marshal json data")
+
+	mockGQL.StubMatchOnce(
+		gqlmock.WithOpName("RunResumeStatus"),
+		string(jsonData),
+	)
+	resumeState := runbranch.NewResumeBranch(
+		context.Background(),
+		mockGQL,
+		runbranch.ResumeModeMust,
+		observability.NewNoOpLogger())
+
+	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
+
+	assert.Nil(t, params)
+	assert.IsType(t, &runbranch.BranchError{}, err)
+	assert.Contains(t,
+		err.(*runbranch.BranchError).Response.Message,
+		"Could not restore: config, history, summary.",
+	)
+}
+
 func TestMustResumeInvalidSummary(t *testing.T) {
 
 	mockGQL := gqlmock.NewMockClient()
@@ -890,7 +1251,8 @@ func TestMustResumeInvalidSummary(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"must")
+		"must",
+		observability.NewNoOpLogger())
 
 	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 	assert.NotNil(t, err, "GetUpdates should return an error")
@@ -952,7 +1314,8 @@ func TestMustResumeInvalidConfig(t *testing.T) {
 			resumeState := runbranch.NewResumeBranch(
 				context.Background(),
 				mockGQL,
-				"must")
+				"must",
+				observability.NewNoOpLogger())
 
 			params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 			assert.NotNil(t, err, "GetUpdates should return an error")
@@ -1014,7 +1377,8 @@ func TestNotNeverResumeFileStreamOffset(t *testing.T) {
 			resumeState := runbranch.NewResumeBranch(
 				context.Background(),
 				mockGQL,
-				tc.value)
+				tc.value,
+				observability.NewNoOpLogger())
 			params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
 			assert.Nil(t, err, "GetUpdates should not return an error")
 			assert.NotNil(t, params, "GetUpdates should return nil when response is empty")
@@ -1067,7 +1431,8 @@ func TestExtractRunState(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"allow")
+		"allow",
+		observability.NewNoOpLogger())
 
 	runPath := runbranch.RunPath{
 		Entity:  "test-entity",
@@ -1116,6 +1481,7 @@ func TestExtractRunStateNilCases(t *testing.T) {
 	historyLineCount := 5
 	eventsLineCount := 10
 	logLineCount := 15
+	negativeLineCount := -1
 	history := `["{\"_step\":4,\"_runtime\":100}"]`
 	summary := `{"loss": 0.5, "_runtime": 120, "wandb": {"runtime": 130}}`
 	config := `{"lr": {"value": 0.001}, "batch_size": {"value": 32}}`
@@ -1141,8 +1507,7 @@ func TestExtractRunStateNilCases(t *testing.T) {
 					},
 				},
 			},
-			expectError:   true,
-			errorContains: "no history line count found",
+			expectError: false,
 		},
 		{
 			name: "Nil EventsLineCount",
@@ -1160,8 +1525,7 @@ func TestExtractRunStateNilCases(t *testing.T) {
 					},
 				},
 			},
-			expectError:   true,
-			errorContains: "no events line count found",
+			expectError: false,
 		},
 		{
 			name: "Nil LogLineCount",
@@ -1179,8 +1543,27 @@ func TestExtractRunStateNilCases(t *testing.T) {
 					},
 				},
 			},
+			expectError: false,
+		},
+		{
+			name: "Negative HistoryLineCount",
+			response: ResumeResponse{
+				Model: Model{
+					Bucket: Bucket{
+						Name:             "TestRun",
+						HistoryLineCount: &negativeLineCount,
+						EventsLineCount:  &eventsLineCount,
+						LogLineCount:     &logLineCount,
+						HistoryTail:      &history,
+						SummaryMetrics:   &summary,
+						EventsTail:       "[]",
+						Config:           &config,
+						WandbConfig:      `{"t": 1}`,
+					},
+				},
+			},
 			expectError:   true,
-			errorContains: "no log line count found",
+			errorContains: "negative history line count: -1",
 		},
 		{
 			name: "Nil HistoryTail",
@@ -1256,7 +1639,8 @@ func TestExtractRunStateNilCases(t *testing.T) {
 			resumeState := runbranch.NewResumeBranch(
 				context.Background(),
 				mockGQL,
-				"must") // Use "must" to ensure errors are returned
+				"must",
+				observability.NewNoOpLogger()) // Use "must" to ensure errors are returned
 
 			runPath := runbranch.RunPath{
 				Entity:  "test-entity",
@@ -1278,14 +1662,65 @@ func TestExtractRunStateNilCases(t *testing.T) {
 	}
 }
 
-func TestExtractRunStateAdjustsStartTime(t *testing.T) {
+func TestMustResumeOffsetsMovingBackwards(t *testing.T) {
 	mockGQL := gqlmock.NewMockClient()
 
+	history := "[]"
+	config := "{}"
+	summary := "{}"
 	historyLineCount := 5
-	eventsLineCount := 10
-	logLineCount := 15
-	history := `["{\"_step\":4,\"_runtime\":100}"]`
-	summary := `{"_runtime": 120, "wandb": {"runtime": 130}}`
+	eventsLineCount := 0
+	logLineCount := 0
+	rr := ResumeResponse{
+		Model: Model{
+			Bucket: Bucket{
+				Name:             "FakeName",
+				HistoryLineCount: &historyLineCount,
+				EventsLineCount:  &eventsLineCount,
+				LogLineCount:     &logLineCount,
+				HistoryTail:      &history,
+				SummaryMetrics:   &summary,
+				Config:           &config,
+				EventsTail:       "[]",
+				WandbConfig:      `{"t": 1}`,
+			},
+		},
+	}
+
+	jsonData, err := json.Marshal(rr)
+	assert.Nil(t, err, "Failed to marshal json data")
+
+	mockGQL.StubMatchOnce(
+		gqlmock.WithOpName("RunResumeStatus"),
+		string(jsonData),
+	)
+	resumeState := runbranch.NewResumeBranch(
+		context.Background(),
+		mockGQL,
+		runbranch.ResumeModeMust,
+		observability.NewNoOpLogger())
+
+	params, err := resumeState.GetUpdates(
+		&runbranch.RunParams{
+			FileStreamOffset: filestream.FileStreamOffsetMap{
+				filestream.HistoryChunk: 10,
+			},
+		},
+		runbranch.RunPath{},
+	)
+
+	assert.Nil(t, params)
+	assert.ErrorContains(t, err, "moved backwards from 10 to 5")
+}
+
+func TestExtractRunStateAdjustsStartTime(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+
+	historyLineCount := 5
+	eventsLineCount := 10
+	logLineCount := 15
+	history := `["{\"_step\":4,\"_runtime\":100}"]`
+	summary := `{"_runtime": 120, "wandb": {"runtime": 130}}`
 	config := `{}`
 	eventsTail := `["{\"_runtime\":110}", "{\"_runtime\":120}"]`
 
@@ -1316,7 +1751,8 @@ func TestExtractRunStateAdjustsStartTime(t *testing.T) {
 	resumeState := runbranch.NewResumeBranch(
 		context.Background(),
 		mockGQL,
-		"must")
+		"must",
+		observability.NewNoOpLogger())
 
 	runPath := runbranch.RunPath{
 		Entity:  "test-entity",
@@ -1343,3 +1779,692 @@ func TestExtractRunStateAdjustsStartTime(t *testing.T) {
 	assert.Equal(t, int32(130), params.Runtime, "Runtime should be set to the maximum value")
 	assert.True(t, params.Resumed, "Resumed flag should be set to true")
 }
+
+func TestValidateResumeMode(t *testing.T) {
+	for _, mode := range []string{"", "must", "never", "allow", "auto"} {
+		assert.NoError(t, runbranch.ValidateResumeMode(mode), "mode %q", mode)
+	}
+}
+
+func TestValidateResumeModeUnknown(t *testing.T) {
+	err := runbranch.ValidateResumeMode("true")
+
+	assert.ErrorIs(t, err, runbranch.ErrUnknownResumeMode)
+	assert.ErrorContains(t, err, `"true"`)
+	assert.ErrorContains(t, err, "expected one of")
+}
+
+func TestValidateResumeModeSuggestsKnownMode(t *testing.T) {
+	err := runbranch.ValidateResumeMode("Allow")
+
+	assert.ErrorIs(t, err, runbranch.ErrUnknownResumeMode)
+	assert.ErrorContains(t, err, `did you mean "allow"?`)
+}
+
+func TestIsMustLike(t *testing.T) {
+	assert.True(t, runbranch.IsMustLike("Must"))
+	assert.True(t, runbranch.IsMustLike(" must "))
+	assert.False(t, runbranch.IsMustLike("must"))
+	assert.False(t, runbranch.IsMustLike("allowed"))
+}
+
+func stubResumeResponse(t *testing.T, mockGQL *gqlmock.MockClient, rr ResumeResponse) {
+	t.Helper()
+	jsonData, err := json.Marshal(rr)
+	assert.NoError(t, err)
+	mockGQL.StubMatchOnce(
+		gqlmock.WithOpName("RunResumeStatus"),
+		string(jsonData),
+	)
+}
+
+// resumeStatus returns the RunResumeStatus response with the JSON form of
+// the response data.
+func resumeStatus(t *testing.T, data string) *gql.RunResumeStatusResponse {
+	t.Helper()
+	response := &gql.RunResumeStatusResponse{}
+	require.NoError(t, json.Unmarshal([]byte(data), response))
+	return response
+}
+
+func TestPreviewAgreesWithGetUpdates(t *testing.T) {
+	history := `["{\"_step\":9,\"_runtime\":50}"]`
+	config := "{}"
+	summary := `{"_step": 9, "_runtime": 50}`
+	historyLineCount := 10
+	eventsLineCount := 3
+	logLineCount := 7
+	rr := ResumeResponse{
+		Model: Model{
+			Bucket: Bucket{
+				Name:             "FakeName",
+				HistoryLineCount: &historyLineCount,
+				EventsLineCount:  &eventsLineCount,
+				LogLineCount:     &logLineCount,
+				HistoryTail:      &history,
+				SummaryMetrics:   &summary,
+				Config:           &config,
+				EventsTail:       "[]",
+				Tags:             []string{"resumed"},
+				WandbConfig:      `{"t": 1}`,
+			},
+		},
+	}
+	params := &runbranch.RunParams{Tags: []string{"local"}}
+
+	jsonData, err := json.Marshal(rr)
+	require.NoError(t, err)
+	mockGQL := gqlmock.NewMockClient()
+	stubResumeResponse(t, mockGQL, rr)
+	branch := runbranch.NewResumeBranch(context.Background(), mockGQL, "must", observability.NewNoOpLogger())
+
+	preview, err := branch.Preview(
+		params,
+		runbranch.RunPath{},
+		resumeStatus(t, string(jsonData)),
+		runbranch.TagMergeAppend,
+	)
+	assert.NoError(t, err)
+	assert.Empty(t, mockGQL.AllRequests(), "Preview doesn't query the server")
+	assert.Nil(t, branch.Stats(), "Preview doesn't modify the branch")
+	update, err := branch.GetUpdates(params, runbranch.RunPath{})
+	assert.NoError(t, err)
+
+	assert.True(t, preview.Resumes)
+	assert.Equal(t, update.StartingStep, preview.StartingStep)
+	assert.Equal(t, int64(10), preview.StartingStep)
+	assert.Equal(t, update.Runtime, preview.Runtime)
+	assert.Equal(t, update.FileStreamOffset, preview.FileStreamOffset)
+	assert.Equal(t, []string{"resumed", "local"}, preview.Tags)
+	assert.Equal(t, []string{"local"}, params.Tags, "params are not modified")
+	assert.False(t, params.Resumed, "params are not modified")
+}
+
+func TestPreviewNewRun(t *testing.T) {
+	branch := runbranch.NewResumeBranch(context.Background(), gqlmock.NewMockClient(), "allow", observability.NewNoOpLogger())
+
+	preview, err := branch.Preview(
+		&runbranch.RunParams{Tags: []string{"local"}},
+		runbranch.RunPath{},
+		resumeStatus(t, `{"model": {"bucket": null}}`),
+		runbranch.TagMergeReplace,
+	)
+
+	assert.NoError(t, err)
+	assert.False(t, preview.Resumes)
+	assert.Equal(t, int64(0), preview.StartingStep)
+	assert.Equal(t, []string{"local"}, preview.Tags)
+}
+
+func TestPreviewNeverResumeExistingRun(t *testing.T) {
+	config := "{}"
+	rr := ResumeResponse{
+		Model: Model{
+			Bucket: Bucket{
+				Name:        "FakeName",
+				Config:      &config,
+				EventsTail:  "[]",
+				WandbConfig: `{"t": 1}`,
+			},
+		},
+	}
+	jsonData, err := json.Marshal(rr)
+	require.NoError(t, err)
+	branch := runbranch.NewResumeBranch(context.Background(), gqlmock.NewMockClient(), "never", observability.NewNoOpLogger())
+
+	preview, err := branch.Preview(
+		nil,
+		runbranch.RunPath{},
+		resumeStatus(t, string(jsonData)),
+		runbranch.TagMergeReplace,
+	)
+
+	assert.Nil(t, preview)
+	assert.IsType(t, &runbranch.BranchError{}, err)
+}
+
+func TestResumeHistoryTailFormats(t *testing.T) {
+	testCases := []struct {
+		name        string
+		historyTail string
+		eventsTail  string
+	}{
+		{
+			name:        "string-encoded rows",
+			historyTail: `["{\"_step\":1}", "{\"_step\":7,\"_runtime\":40}"]`,
+			eventsTail:  `["{\"_runtime\":45.5}"]`,
+		},
+		{
+			name:        "object rows",
+			historyTail: `[{"_step":1}, {"_step":7,"_runtime":40}]`,
+			eventsTail:  `[{"_runtime":45.5}]`,
+		},
+		{
+			name:        "mixed rows",
+			historyTail: `["{\"_step\":1}", {"_step":7,"_runtime":40}]`,
+			eventsTail:  `[ {"_runtime":45.5} ]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockGQL := gqlmock.NewMockClient()
+			config := "{}"
+			summary := "{}"
+			historyLineCount := 8
+			rr := ResumeResponse{
+				Model: Model{
+					Bucket: Bucket{
+						Name:             "FakeName",
+						HistoryLineCount: &historyLineCount,
+						HistoryTail:      &tc.historyTail,
+						SummaryMetrics:   &summary,
+						Config:           &config,
+						EventsTail:       tc.eventsTail,
+						WandbConfig:      `{"t": 1}`,
+					},
+				},
+			}
+			stubResumeResponse(t, mockGQL, rr)
+
+			params, err := runbranch.NewResumeBranch(
+				context.Background(),
+				mockGQL,
+				"must",
+				observability.NewNoOpLogger(),
+			).GetUpdates(nil, runbranch.RunPath{})
+
+			assert.NoError(t, err)
+			assert.Equal(t, int64(8), params.StartingStep)
+			assert.Equal(t, int32(45), params.Runtime)
+		})
+	}
+}
+
+func TestResumeHistoryTailInvalidRow(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+	config := "{}"
+	summary := "{}"
+	history := `[42]`
+	historyLineCount := 1
+	rr := ResumeResponse{
+		Model: Model{
+			Bucket: Bucket{
+				Name:             "FakeName",
+				HistoryLineCount: &historyLineCount,
+				HistoryTail:      &history,
+				SummaryMetrics:   &summary,
+				Config:           &config,
+				EventsTail:       "[]",
+				WandbConfig:      `{"t": 1}`,
+			},
+		},
+	}
+	stubResumeResponse(t, mockGQL, rr)
+
+	params, err := runbranch.NewResumeBranch(
+		context.Background(),
+		mockGQL,
+		"allow",
+		observability.NewNoOpLogger(),
+	).GetUpdates(nil, runbranch.RunPath{})
+
+	var resumeErr *runbranch.ResumeError
+	assert.ErrorAs(t, err, &resumeErr)
+	assert.Equal(t, []string{"history"}, resumeErr.SectionNames())
+	assert.NotNil(t, params)
+}
+
+// createdRunResponse returns a RunResumeStatus response for a run that was
+// created ahead of time and, if started, has since logged a step.
+func createdRunResponse(t *testing.T, started bool) string {
+	t.Helper()
+	history := `["{\"_step\":1,\"_runtime\":50}"]`
+	config := "{}"
+	summary := `{"_step": 1, "_runtime": 50}`
+	lineCount := 0
+	historyLineCount := 1
+	wandbConfig := "{}"
+	if started {
+		wandbConfig = `{"t": 1}`
+	}
+
+	jsonData, err := json.Marshal(ResumeResponse{
+		Model: Model{
+			Bucket: Bucket{
+				Name:             "FakeName",
+				HistoryLineCount: &historyLineCount,
+				EventsLineCount:  &lineCount,
+				LogLineCount:     &lineCount,
+				HistoryTail:      &history,
+				SummaryMetrics:   &summary,
+				Config:           &config,
+				EventsTail:       "[]",
+				WandbConfig:      wandbConfig,
+			},
+		},
+	})
+	assert.Nil(t, err, "Failed to marshal json data")
+	return string(jsonData)
+}
+
+func TestWaitForStartResumesStartedRun(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+	mockGQL.StubMatchOnce(
+		gqlmock.WithOpName("RunResumeStatus"),
+		createdRunResponse(t, false),
+	)
+	mockGQL.StubMatchOnce(
+		gqlmock.WithOpName("RunResumeStatus"),
+		createdRunResponse(t, false),
+	)
+	mockGQL.StubMatchOnce(
+		gqlmock.WithOpName("RunResumeStatus"),
+		createdRunResponse(t, true),
+	)
+	resumeState := runbranch.NewResumeBranch(
+		context.Background(),
+		mockGQL,
+		"must",
+		observability.NewNoOpLogger(),
+	).WaitForStart(time.Second, time.Millisecond)
+
+	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
+
+	assert.Nil(t, err)
+	assert.True(t, params.Resumed)
+	assert.Equal(t, int64(2), params.StartingStep)
+	assert.Len(t, mockGQL.AllRequests(), 3)
+}
+
+func TestWaitForStartTimesOut(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+	for range 2 {
+		mockGQL.StubMatchOnce(
+			gqlmock.WithOpName("RunResumeStatus"),
+			createdRunResponse(t, false),
+		)
+	}
+	resumeState := runbranch.NewResumeBranch(
+		context.Background(),
+		mockGQL,
+		"allow",
+		observability.NewNoOpLogger(),
+	).WaitForStart(10*time.Millisecond, time.Second)
+
+	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
+
+	assert.Nil(t, err)
+	assert.Nil(t, params, "an unstarted run should be treated as new")
+	assert.True(t, mockGQL.AllStubsUsed())
+}
+
+func TestResumeStatsRecordsServerCounts(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+
+	history := `["{\"_step\":7,\"_runtime\":50}"]`
+	config := "{}"
+	summary := `{"_step": 7, "_runtime": 50}`
+	historyLineCount := 8
+	eventsLineCount := 3
+	logLineCount := 12
+	rr := ResumeResponse{
+		Model: Model{
+			Bucket: Bucket{
+				Name:             "FakeName",
+				HistoryLineCount: &historyLineCount,
+				EventsLineCount:  &eventsLineCount,
+				LogLineCount:     &logLineCount,
+				HistoryTail:      &history,
+				SummaryMetrics:   &summary,
+				Config:           &config,
+				EventsTail:       "[]",
+				WandbConfig:      `{"t": 1}`,
+			},
+		},
+	}
+	jsonData, err := json.Marshal(rr)
+	assert.Nil(t, err, "Failed to marshal json data")
+	mockGQL.StubMatchOnce(
+		gqlmock.WithOpName("RunResumeStatus"),
+		string(jsonData),
+	)
+	resumeState := runbranch.NewResumeBranch(
+		context.Background(),
+		mockGQL,
+		"must",
+		observability.NewNoOpLogger())
+	assert.Nil(t, resumeState.Stats())
+
+	_, err = resumeState.GetUpdates(nil, runbranch.RunPath{})
+
+	assert.Nil(t, err)
+	stats := resumeState.Stats()
+	assert.Equal(t, 8, stats.HistoryLineCount)
+	assert.Equal(t, 3, stats.EventsLineCount)
+	assert.Equal(t, 12, stats.LogLineCount)
+	if assert.NotNil(t, stats.HistoryTailStep) {
+		assert.Equal(t, int64(7), *stats.HistoryTailStep)
+	}
+}
+
+func TestMergeSummaryWithKeepsLocalValues(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+
+	historyLineCount := 10
+	eventsLineCount := 0
+	logLineCount := 0
+	history := `["{\"_step\":9}"]`
+	config := "{}"
+	summary := `{"_step": 9, "loss": 0.5, "acc": 0.9}`
+	rr := ResumeResponse{
+		Model: Model{
+			Bucket: Bucket{
+				Name:             "FakeName",
+				HistoryLineCount: &historyLineCount,
+				EventsLineCount:  &eventsLineCount,
+				LogLineCount:     &logLineCount,
+				HistoryTail:      &history,
+				SummaryMetrics:   &summary,
+				Config:           &config,
+				EventsTail:       "[]",
+				WandbConfig:      `{"t": 1}`,
+			},
+		},
+	}
+	jsonData, err := json.Marshal(rr)
+	assert.Nil(t, err, "Failed to marshal json data")
+	mockGQL.StubMatchOnce(
+		gqlmock.WithOpName("RunResumeStatus"),
+		string(jsonData),
+	)
+
+	params := runbranch.NewRunParams()
+	params.Summary = map[string]any{"loss": 0.1, "lr": 0.01}
+	update, err := runbranch.NewResumeBranch(
+		context.Background(),
+		mockGQL,
+		"must",
+		observability.NewNoOpLogger(),
+	).MergeSummaryWith(runbranch.SummaryMergePreferLocal).
+		GetUpdates(params, runbranch.RunPath{})
+
+	assert.Nil(t, err)
+	assert.Equal(t,
+		map[string]any{"_step": int64(9), "loss": 0.1, "acc": 0.9, "lr": 0.01},
+		update.Summary)
+	assert.EqualValues(t, 10, update.StartingStep)
+}
+
+func TestResumeSummaryAggregationHints(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+
+	historyLineCount := 10
+	eventsLineCount := 0
+	logLineCount := 0
+	history := `["{\"_step\":9}"]`
+	config := "{}"
+	summary := `{"_step": 9, "samples": 1000, "loss": 0.5,` +
+		` "_wandb": {"aggregation": {"samples": "sum"}}}`
+	rr := ResumeResponse{
+		Model: Model{
+			Bucket: Bucket{
+				Name:             "FakeName",
+				HistoryLineCount: &historyLineCount,
+				EventsLineCount:  &eventsLineCount,
+				LogLineCount:     &logLineCount,
+				HistoryTail:      &history,
+				SummaryMetrics:   &summary,
+				Config:           &config,
+				EventsTail:       "[]",
+				WandbConfig:      `{"t": 1}`,
+			},
+		},
+	}
+	jsonData, err := json.Marshal(rr)
+	require.NoError(t, err)
+	mockGQL.StubMatchOnce(
+		gqlmock.WithOpName("RunResumeStatus"),
+		string(jsonData),
+	)
+
+	params := runbranch.NewRunParams()
+	params.Summary = map[string]any{"samples": int64(200), "loss": 0.1}
+	update, err := runbranch.NewResumeBranch(
+		context.Background(),
+		mockGQL,
+		"must",
+		observability.NewNoOpLogger(),
+	).GetUpdates(params, runbranch.RunPath{})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1200), update.Summary["samples"])
+	assert.Equal(t, 0.5, update.Summary["loss"])
+}
+
+func TestResumeRuntimeSource(t *testing.T) {
+	testCases := []struct {
+		name            string
+		historyTail     string
+		summary         string
+		expectedRuntime int32
+		expectedSource  string
+	}{
+		{
+			"HistoryTail",
+			`["{\"_step\":9,\"_runtime\":30}"]`,
+			`{"_step": 9}`,
+			30,
+			"history",
+		},
+		{
+			"SummaryWithoutHistoryTailRuntime",
+			`["{\"_step\":9}"]`,
+			`{"_step": 9, "_runtime": 25.5}`,
+			25,
+			"summary",
+		},
+		{
+			"Neither",
+			`["{\"_step\":9}"]`,
+			`{"_step": 9}`,
+			0,
+			"none",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockGQL := gqlmock.NewMockClient()
+			historyLineCount := 10
+			eventsLineCount := 0
+			logLineCount := 0
+			config := "{}"
+			rr := ResumeResponse{
+				Model: Model{
+					Bucket: Bucket{
+						Name:             "FakeName",
+						HistoryLineCount: &historyLineCount,
+						EventsLineCount:  &eventsLineCount,
+						LogLineCount:     &logLineCount,
+						HistoryTail:      &tc.historyTail,
+						SummaryMetrics:   &tc.summary,
+						Config:           &config,
+						EventsTail:       "[]",
+						WandbConfig:      `{"t": 1}`,
+					},
+				},
+			}
+			jsonData, err := json.Marshal(rr)
+			assert.Nil(t, err, "Failed to marshal json data")
+			mockGQL.StubMatchOnce(
+				gqlmock.WithOpName("RunResumeStatus"),
+				string(jsonData),
+			)
+
+			branch := runbranch.NewResumeBranch(
+				context.Background(),
+				mockGQL,
+				"must",
+				observability.NewNoOpLogger())
+			update, err := branch.GetUpdates(nil, runbranch.RunPath{})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedRuntime, update.Runtime)
+			assert.Equal(t, tc.expectedSource, branch.Stats().RuntimeSource)
+		})
+	}
+}
+
+func TestMustResumeMultiRowHistoryTail(t *testing.T) {
+	testCases := []struct {
+		name    string
+		history string
+	}{
+		{
+			"EncodedRows",
+			`["{\"_step\":3,\"_runtime\":30}", "{\"_step\":7,\"_runtime\":70}", "", "{\"_step\":5,\"_runtime\":50}"]`,
+		},
+		{
+			"ObjectRows",
+			`[{"_step":7,"_runtime":70}, null, {"_step":5,"_runtime":50}]`,
+		},
+		{
+			"RuntimeNotOnLatestStep",
+			`["{\"_step\":7,\"_runtime\":60}", "{\"_step\":5,\"_runtime\":70}"]`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockGQL := gqlmock.NewMockClient()
+
+			history := tc.history
+			config := "{}"
+			summary := "{}"
+			historyLineCount := 8
+			eventsLineCount := 0
+			logLineCount := 0
+			rr := ResumeResponse{
+				Model: Model{
+					Bucket: Bucket{
+						Name:             "FakeName",
+						HistoryLineCount: &historyLineCount,
+						EventsLineCount:  &eventsLineCount,
+						LogLineCount:     &logLineCount,
+						HistoryTail:      &history,
+						SummaryMetrics:   &summary,
+						Config:           &config,
+						EventsTail:       "[]",
+						WandbConfig:      `{"t": 1}`,
+					},
+				},
+			}
+			jsonData, err := json.Marshal(rr)
+			assert.Nil(t, err, "Failed to marshal json data")
+			mockGQL.StubMatchOnce(
+				gqlmock.WithOpName("RunResumeStatus"),
+				string(jsonData),
+			)
+			resumeState := runbranch.NewResumeBranch(
+				context.Background(),
+				mockGQL,
+				"must",
+				observability.NewNoOpLogger())
+
+			params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
+
+			assert.Nil(t, err)
+			assert.Equal(t, int64(8), params.StartingStep)
+			assert.Equal(t, int32(70), params.Runtime)
+			if assert.NotNil(t, resumeState.Stats().HistoryTailStep) {
+				assert.Equal(t, int64(7), *resumeState.Stats().HistoryTailStep)
+			}
+		})
+	}
+}
+
+// stubConfigResume stubs a resume response for a run with the config.
+func stubConfigResume(mockGQL *gqlmock.MockClient, config string) {
+	history := `[]`
+	summary := `{}`
+	historyLineCount := 0
+	eventsLineCount := 0
+	logLineCount := 0
+	rr := ResumeResponse{
+		Model: Model{
+			Bucket: Bucket{
+				Name:             "FakeName",
+				HistoryLineCount: &historyLineCount,
+				EventsLineCount:  &eventsLineCount,
+				LogLineCount:     &logLineCount,
+				HistoryTail:      &history,
+				SummaryMetrics:   &summary,
+				Config:           &config,
+				EventsTail:       `[]`,
+				WandbConfig:      `{"t": 1}`,
+			},
+		},
+	}
+	jsonData, _ := json.Marshal(rr)
+	mockGQL.StubMatchOnce(
+		gqlmock.WithOpName("RunResumeStatus"),
+		string(jsonData),
+	)
+}
+
+const mismatchedConfig = `{"lr": {"value": 0.01}, "epochs": 10, "name": "run"}`
+
+func TestResumeConfigTypeMismatch_MustFailsListingKeys(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+	stubConfigResume(mockGQL, mismatchedConfig)
+
+	_, err := runbranch.NewResumeBranch(
+		context.Background(),
+		mockGQL,
+		"must",
+		observability.NewNoOpLogger(),
+	).GetUpdates(nil, runbranch.RunPath{})
+
+	assert.IsType(t, &runbranch.BranchError{}, err)
+	assert.ErrorContains(t, err,
+		"unexpected types for config keys: epochs (int64), name (string)")
+}
+
+func TestResumeConfigTypeMismatch_AllowResumesWithoutConfig(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+	stubConfigResume(mockGQL, mismatchedConfig)
+
+	params, err := runbranch.NewResumeBranch(
+		context.Background(),
+		mockGQL,
+		"allow",
+		observability.NewNoOpLogger(),
+	).GetUpdates(nil, runbranch.RunPath{})
+
+	var typeErr *runbranch.ConfigTypeError
+	if assert.ErrorAs(t, err, &typeErr) {
+		assert.Equal(t,
+			map[string]string{"epochs": "int64", "name": "string"},
+			typeErr.Types)
+	}
+	require.NotNil(t, params)
+	assert.Nil(t, params.Config)
+}
+
+func TestResumeConfigTypeMismatch_Dropped(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+	stubConfigResume(mockGQL, mismatchedConfig)
+	var logs bytes.Buffer
+	resumeState := runbranch.NewResumeBranch(
+		context.Background(),
+		mockGQL,
+		"must",
+		observability.NewCoreLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	).DropMismatchedConfig(true)
+
+	params, err := resumeState.GetUpdates(nil, runbranch.RunPath{})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"lr": 0.01}, params.Config)
+	assert.Equal(t, []string{"epochs", "name"}, resumeState.Stats().DroppedConfigKeys)
+	assert.Contains(t, logs.String(), "dropped config values with unexpected types")
+}
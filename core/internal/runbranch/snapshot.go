@@ -0,0 +1,78 @@
+package runbranch
+
+import (
+	"os"
+
+	"github.com/wandb/simplejsonext"
+	"github.com/wandb/wandb/core/internal/filestream"
+	"github.com/wandb/wandb/core/internal/gql"
+)
+
+// ResumeSnapshotFileName is the name of the file in the run's files
+// directory that a resume snapshot is written to.
+const ResumeSnapshotFileName = "wandb-resume-snapshot.json"
+
+// chunkNames are the names of the file stream chunks in a snapshot.
+var chunkNames = map[filestream.ChunkTypeEnum]string{
+	filestream.HistoryChunk: "history",
+	filestream.OutputChunk:  "output",
+	filestream.EventsChunk:  "events",
+	filestream.SummaryChunk: "summary",
+}
+
+// writeResumeSnapshot writes what the server reported about a resumed run
+// and the state it resumes with to a JSON file, for debugging.
+//
+// The "resumed" section has the summary, config and tags parsed from the
+// server's response, and the "merged" section the state after combining
+// them with the local state. Sections that failed to parse are null, and
+// err, if any, is included as "error". The merged section is null if
+// nothing could be resumed.
+func writeResumeSnapshot(
+	path string,
+	data *gql.RunResumeStatusModelProjectBucketRun,
+	update *RunParams,
+	err error,
+) error {
+	// Parse errors are reported by processResponse; partial results are
+	// kept here to show what the server sent.
+	summary, _ := processSummary(data.GetSummaryMetrics())
+	config, _ := processConfigResume(data.GetConfig())
+
+	snapshot := map[string]any{
+		"resumed": map[string]any{
+			"summary": summary,
+			"config":  config,
+			"tags":    data.GetTags(),
+		},
+		"merged": nil,
+	}
+
+	if update != nil {
+		offsets := make(map[string]any, len(update.FileStreamOffset))
+		for chunk, offset := range update.FileStreamOffset {
+			if name, ok := chunkNames[chunk]; ok {
+				offsets[name] = offset
+			}
+		}
+
+		snapshot["merged"] = map[string]any{
+			"summary":          update.Summary,
+			"config":           update.Config,
+			"tags":             update.Tags,
+			"fileStreamOffset": offsets,
+			"startingStep":     update.StartingStep,
+			"runtime":          update.Runtime,
+		}
+	}
+
+	if err != nil {
+		snapshot["error"] = err.Error()
+	}
+
+	contents, err := simplejsonext.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, contents, 0o644)
+}
@@ -0,0 +1,106 @@
+package runbranch_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wandb/wandb/core/internal/gqlmock"
+	"github.com/wandb/wandb/core/internal/observability"
+	"github.com/wandb/wandb/core/internal/runbranch"
+)
+
+// stubSnapshotResume stubs the server's state for a run with a summary,
+// config and tags.
+func stubSnapshotResume(t *testing.T, mockGQL *gqlmock.MockClient) {
+	t.Helper()
+
+	historyLineCount := 10
+	eventsLineCount := 3
+	logLineCount := 7
+	history := `["{\"_step\":9}"]`
+	summary := `{"_step": 9, "loss": 0.5}`
+	config := `{"lr": {"value": 0.01}}`
+	rr := ResumeResponse{
+		Model: Model{
+			Bucket: Bucket{
+				Name:             "FakeName",
+				HistoryLineCount: &historyLineCount,
+				EventsLineCount:  &eventsLineCount,
+				LogLineCount:     &logLineCount,
+				HistoryTail:      &history,
+				SummaryMetrics:   &summary,
+				Config:           &config,
+				EventsTail:       `[]`,
+				Tags:             []string{"baseline"},
+				WandbConfig:      `{"t": 1}`,
+			},
+		},
+	}
+	jsonData, err := json.Marshal(rr)
+	require.NoError(t, err)
+	mockGQL.StubMatchOnce(
+		gqlmock.WithOpName("RunResumeStatus"),
+		string(jsonData),
+	)
+}
+
+func TestResumeSnapshot(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+	stubSnapshotResume(t, mockGQL)
+	path := filepath.Join(t.TempDir(), runbranch.ResumeSnapshotFileName)
+
+	params := runbranch.NewRunParams()
+	params.Summary = map[string]any{"acc": 0.9}
+	_, err := runbranch.NewResumeBranch(
+		context.Background(),
+		mockGQL,
+		"must",
+		observability.NewNoOpLogger(),
+	).MergeSummaryWith(runbranch.SummaryMergeUnion).
+		SnapshotTo(path).
+		GetUpdates(params, runbranch.RunPath{})
+	require.NoError(t, err)
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t,
+		`{
+			"resumed": {
+				"summary": {"_step": 9, "loss": 0.5},
+				"config": {"lr": 0.01},
+				"tags": ["baseline"]
+			},
+			"merged": {
+				"summary": {"_step": 9, "loss": 0.5, "acc": 0.9},
+				"config": {"lr": 0.01},
+				"tags": ["baseline"],
+				"fileStreamOffset": {"history": 10, "events": 3, "output": 7},
+				"startingStep": 10,
+				"runtime": 0
+			}
+		}`,
+		string(contents))
+}
+
+func TestResumeSnapshot_WriteFailureDoesNotFailResume(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+	stubSnapshotResume(t, mockGQL)
+	path := filepath.Join(t.TempDir(), "missing", runbranch.ResumeSnapshotFileName)
+
+	update, err := runbranch.NewResumeBranch(
+		context.Background(),
+		mockGQL,
+		"must",
+		observability.NewNoOpLogger(),
+	).SnapshotTo(path).
+		GetUpdates(runbranch.NewRunParams(), runbranch.RunPath{})
+
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, update.StartingStep)
+	assert.NoFileExists(t, path)
+}
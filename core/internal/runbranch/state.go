@@ -1,6 +1,9 @@
 package runbranch
 
 import (
+	"fmt"
+	"maps"
+	"strings"
 	"time"
 
 	"github.com/wandb/simplejsonext"
@@ -29,6 +32,266 @@ func (re BranchError) Error() string {
 	return re.Err.Error()
 }
 
+// TagMergePolicy decides how the tags of a resumed run combine with the
+// tags provided at init time.
+type TagMergePolicy int
+
+const (
+	// TagMergeReplace keeps the init tags if there are any, and uses the
+	// resumed run's tags otherwise. This is the default.
+	TagMergeReplace TagMergePolicy = iota
+
+	// TagMergeAppend keeps the resumed run's tags followed by any new init
+	// tags.
+	TagMergeAppend
+
+	// TagMergeResumedOnly ignores the init tags.
+	TagMergeResumedOnly
+)
+
+// ParseTagMergePolicy parses a tag merge policy: "replace", "append" or
+// "resumed_only".
+//
+// An empty string means TagMergeReplace.
+func ParseTagMergePolicy(value string) (TagMergePolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "replace":
+		return TagMergeReplace, nil
+	case "append":
+		return TagMergeAppend, nil
+	case "resumed_only":
+		return TagMergeResumedOnly, nil
+	default:
+		return TagMergeReplace, fmt.Errorf(
+			"runbranch: invalid tag merge policy %q,"+
+				" expected replace, append or resumed_only",
+			value,
+		)
+	}
+}
+
+// MergeTags combines the resumed run's tags with the init tags.
+//
+// The result has no duplicates and keeps the first-seen order. Tags are
+// compared case-sensitively.
+func MergeTags(policy TagMergePolicy, resumed, local []string) []string {
+	var tags []string
+	switch policy {
+	case TagMergeAppend:
+		tags = append(append(tags, resumed...), local...)
+	case TagMergeResumedOnly:
+		tags = append(tags, resumed...)
+	default:
+		if len(local) > 0 {
+			tags = append(tags, local...)
+		} else {
+			tags = append(tags, resumed...)
+		}
+	}
+
+	seen := make(map[string]struct{}, len(tags))
+	unique := tags[:0]
+	for _, tag := range tags {
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		unique = append(unique, tag)
+	}
+	return unique
+}
+
+// SummaryMergePolicy decides how the summary of a resumed run combines
+// with summary values set locally before the run started.
+type SummaryMergePolicy int
+
+const (
+	// SummaryMergePreferResumed uses the resumed run's summary and drops
+	// the local values. This is the default.
+	SummaryMergePreferResumed SummaryMergePolicy = iota
+
+	// SummaryMergePreferLocal keeps the keys of both summaries, using the
+	// local value for keys set in both.
+	SummaryMergePreferLocal
+
+	// SummaryMergeUnion keeps the keys of both summaries, using the
+	// resumed run's value for keys set in both.
+	SummaryMergeUnion
+)
+
+// ParseSummaryMergePolicy parses a summary merge policy: "prefer_resumed",
+// "prefer_local" or "union".
+//
+// An empty string means SummaryMergePreferResumed.
+func ParseSummaryMergePolicy(value string) (SummaryMergePolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "prefer_resumed":
+		return SummaryMergePreferResumed, nil
+	case "prefer_local":
+		return SummaryMergePreferLocal, nil
+	case "union":
+		return SummaryMergeUnion, nil
+	default:
+		return SummaryMergePreferResumed, fmt.Errorf(
+			"runbranch: invalid summary merge policy %q,"+
+				" expected prefer_resumed, prefer_local or union",
+			value,
+		)
+	}
+}
+
+// SummaryAggregation is how a summary value of a resumed run combines with
+// the local value for the same key.
+type SummaryAggregation int
+
+const (
+	// SummaryAggregateOverwrite picks one of the values according to the
+	// SummaryMergePolicy. This is the default.
+	SummaryAggregateOverwrite SummaryAggregation = iota
+
+	// SummaryAggregateSum adds the values, for keys that are running totals.
+	SummaryAggregateSum
+
+	// SummaryAggregateCount adds the values, for keys that count events.
+	SummaryAggregateCount
+)
+
+// SummaryAggregationHints returns how each key of a resumed run's summary
+// combines with local values.
+//
+// The hints are read from the summary's metadata, as in
+//
+//	{"_wandb": {"aggregation": {"samples": "sum", "batches": "count"}}}
+//
+// Keys without a hint, or with an unknown one, are overwritten.
+func SummaryAggregationHints(summary map[string]any) map[string]SummaryAggregation {
+	metadata, _ := summary["_wandb"].(map[string]any)
+	aggregation, _ := metadata["aggregation"].(map[string]any)
+
+	hints := make(map[string]SummaryAggregation, len(aggregation))
+	for key, hint := range aggregation {
+		switch hint {
+		case "sum":
+			hints[key] = SummaryAggregateSum
+		case "count":
+			hints[key] = SummaryAggregateCount
+		}
+	}
+	return hints
+}
+
+// MergeSummary combines the resumed run's summary with the local summary.
+//
+// Only top-level keys are compared. Keys that the resumed summary's
+// aggregation hints mark as sums or counts are added together if both
+// values are numbers; the others are merged according to policy. Neither
+// input is modified.
+func MergeSummary(policy SummaryMergePolicy, resumed, local map[string]any) map[string]any {
+	merged := mergeSummaryValues(policy, resumed, local)
+	cloned := false
+
+	// Every hint adds the values.
+	for key := range SummaryAggregationHints(resumed) {
+		total, ok := addSummaryValues(resumed[key], local[key])
+		if !ok {
+			continue
+		}
+
+		// The merged summary may be the resumed one, which must not change.
+		if !cloned {
+			merged = maps.Clone(merged)
+			cloned = true
+		}
+		merged[key] = total
+	}
+
+	return merged
+}
+
+// mergeSummaryValues combines two summaries according to the policy alone.
+func mergeSummaryValues(
+	policy SummaryMergePolicy,
+	resumed, local map[string]any,
+) map[string]any {
+	switch policy {
+	case SummaryMergePreferLocal:
+		merged := maps.Clone(resumed)
+		if merged == nil {
+			merged = make(map[string]any, len(local))
+		}
+		maps.Copy(merged, local)
+		return merged
+	case SummaryMergeUnion:
+		merged := maps.Clone(local)
+		if merged == nil {
+			merged = make(map[string]any, len(resumed))
+		}
+		maps.Copy(merged, resumed)
+		return merged
+	default:
+		return resumed
+	}
+}
+
+// addSummaryValues adds two summary values if both are numbers.
+//
+// The sum of two integers is an integer.
+func addSummaryValues(a, b any) (any, bool) {
+	if a, ok := a.(int64); ok {
+		if b, ok := b.(int64); ok {
+			return a + b, true
+		}
+	}
+
+	x, ok := summaryNumber(a)
+	if !ok {
+		return nil, false
+	}
+	y, ok := summaryNumber(b)
+	if !ok {
+		return nil, false
+	}
+	return x + y, true
+}
+
+// summaryNumber converts a numeric summary value to a float64.
+func summaryNumber(value any) (float64, bool) {
+	switch x := value.(type) {
+	case int64:
+		return float64(x), true
+	case float64:
+		return x, true
+	default:
+		return 0, false
+	}
+}
+
+// BranchType is how a run relates to existing runs on the server.
+type BranchType int
+
+const (
+	// BranchNone is a new run.
+	BranchNone BranchType = iota
+
+	// BranchResume continues an existing run.
+	BranchResume
+
+	// BranchFork starts from a step of an existing run, either as a new
+	// run with fork_from or by rewinding the run itself with resume_from.
+	BranchFork
+)
+
+func (t BranchType) String() string {
+	switch t {
+	case BranchResume:
+		return "resume"
+	case BranchFork:
+		return "fork"
+	default:
+		return "none"
+	}
+}
+
 type RunParams struct {
 	RunID       string
 	Project     string
@@ -39,8 +302,13 @@ type RunParams struct {
 	SweepID     string
 
 	// run state fields based on response from the server
+
+	// StartingStep is the next step to write, i.e. one past the last step
+	// of the resumed run.
 	StartingStep int64
-	Runtime      int32
+
+	// Runtime is how long the run has been running, in seconds.
+	Runtime int32
 
 	Tags    []string
 	Config  map[string]any
@@ -54,6 +322,42 @@ type RunParams struct {
 	Initialized bool
 }
 
+// GetStartingStep returns the next step to write.
+//
+// It is 0 for a new run, including when r is nil.
+func (r *RunParams) GetStartingStep() int64 {
+	if r == nil {
+		return 0
+	}
+	return r.StartingStep
+}
+
+// GetRuntime returns the runtime of the run in seconds.
+//
+// It is 0 for a new run, including when r is nil.
+func (r *RunParams) GetRuntime() int32 {
+	if r == nil {
+		return 0
+	}
+	return r.Runtime
+}
+
+// Type returns how the run was branched from an existing run.
+//
+// It is BranchNone for a new run, including when r is nil.
+func (r *RunParams) Type() BranchType {
+	switch {
+	case r == nil:
+		return BranchNone
+	case r.Forked:
+		return BranchFork
+	case r.Resumed:
+		return BranchResume
+	default:
+		return BranchNone
+	}
+}
+
 func (r *RunParams) Proto() *spb.RunRecord {
 
 	proto := &spb.RunRecord{}
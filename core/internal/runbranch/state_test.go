@@ -100,3 +100,226 @@ func TestMerge(t *testing.T) {
 	assert.Equal(t, r.Tags, r2.Tags)
 	assert.Equal(t, r.Resumed, true)
 }
+
+func TestMergeTags(t *testing.T) {
+	resumed := []string{"a", "b", "B"}
+	local := []string{"c", "b", "c"}
+
+	testCases := []struct {
+		name     string
+		policy   runbranch.TagMergePolicy
+		local    []string
+		expected []string
+	}{
+		{"ReplaceWithLocal", runbranch.TagMergeReplace, local, []string{"c", "b"}},
+		{"ReplaceWithoutLocal", runbranch.TagMergeReplace, nil, []string{"a", "b", "B"}},
+		{"Append", runbranch.TagMergeAppend, local, []string{"a", "b", "B", "c"}},
+		{"ResumedOnly", runbranch.TagMergeResumedOnly, local, []string{"a", "b", "B"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tags := runbranch.MergeTags(tc.policy, resumed, tc.local)
+
+			assert.Equal(t, tc.expected, tags)
+			assert.Equal(t, []string{"a", "b", "B"}, resumed, "resumed tags must not be modified")
+		})
+	}
+}
+
+func TestParseTagMergePolicy(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected runbranch.TagMergePolicy
+	}{
+		{"", runbranch.TagMergeReplace},
+		{"replace", runbranch.TagMergeReplace},
+		{"Append", runbranch.TagMergeAppend},
+		{" resumed_only ", runbranch.TagMergeResumedOnly},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			policy, err := runbranch.ParseTagMergePolicy(tc.value)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, policy)
+		})
+	}
+}
+
+func TestParseTagMergePolicy_Invalid(t *testing.T) {
+	policy, err := runbranch.ParseTagMergePolicy("union")
+
+	assert.ErrorContains(t, err, `invalid tag merge policy "union"`)
+	assert.Equal(t, runbranch.TagMergeReplace, policy)
+}
+
+func TestParseSummaryMergePolicy(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected runbranch.SummaryMergePolicy
+	}{
+		{"", runbranch.SummaryMergePreferResumed},
+		{"prefer_resumed", runbranch.SummaryMergePreferResumed},
+		{"Prefer_Local", runbranch.SummaryMergePreferLocal},
+		{" union ", runbranch.SummaryMergeUnion},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			policy, err := runbranch.ParseSummaryMergePolicy(tc.value)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, policy)
+		})
+	}
+}
+
+func TestParseSummaryMergePolicy_Invalid(t *testing.T) {
+	policy, err := runbranch.ParseSummaryMergePolicy("append")
+
+	assert.ErrorContains(t, err, `invalid summary merge policy "append"`)
+	assert.Equal(t, runbranch.SummaryMergePreferResumed, policy)
+}
+
+func TestMergeSummary(t *testing.T) {
+	resumed := map[string]any{"loss": 0.5, "acc": 0.9}
+	local := map[string]any{"loss": 0.1, "lr": 0.01}
+
+	testCases := []struct {
+		name     string
+		policy   runbranch.SummaryMergePolicy
+		expected map[string]any
+	}{
+		{"PreferResumed", runbranch.SummaryMergePreferResumed,
+			map[string]any{"loss": 0.5, "acc": 0.9}},
+		{"PreferLocal", runbranch.SummaryMergePreferLocal,
+			map[string]any{"loss": 0.1, "acc": 0.9, "lr": 0.01}},
+		{"Union", runbranch.SummaryMergeUnion,
+			map[string]any{"loss": 0.5, "acc": 0.9, "lr": 0.01}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			summary := runbranch.MergeSummary(tc.policy, resumed, local)
+
+			assert.Equal(t, tc.expected, summary)
+			assert.Equal(t, map[string]any{"loss": 0.5, "acc": 0.9}, resumed,
+				"resumed summary must not be modified")
+			assert.Equal(t, map[string]any{"loss": 0.1, "lr": 0.01}, local,
+				"local summary must not be modified")
+		})
+	}
+}
+
+func TestMergeSummary_NoLocalSummary(t *testing.T) {
+	resumed := map[string]any{"loss": 0.5}
+
+	for _, policy := range []runbranch.SummaryMergePolicy{
+		runbranch.SummaryMergePreferResumed,
+		runbranch.SummaryMergePreferLocal,
+		runbranch.SummaryMergeUnion,
+	} {
+		assert.Equal(t, resumed, runbranch.MergeSummary(policy, resumed, nil))
+	}
+}
+
+func TestMergeSummary_AggregationHints(t *testing.T) {
+	resumed := map[string]any{
+		"samples": int64(1000),
+		"tokens":  1.5e6,
+		"loss":    0.5,
+		"_wandb": map[string]any{"aggregation": map[string]any{
+			"samples": "sum",
+			"tokens":  "count",
+			"loss":    "overwrite",
+		}},
+	}
+	local := map[string]any{"samples": int64(200), "tokens": int64(5e5), "loss": 0.1}
+
+	summary := runbranch.MergeSummary(runbranch.SummaryMergePreferLocal, resumed, local)
+
+	assert.Equal(t, int64(1200), summary["samples"])
+	assert.Equal(t, 2e6, summary["tokens"])
+	assert.Equal(t, 0.1, summary["loss"])
+	assert.Equal(t, int64(1000), resumed["samples"],
+		"resumed summary must not be modified")
+}
+
+func TestMergeSummary_AggregationHintsWithDefaultPolicy(t *testing.T) {
+	resumed := map[string]any{
+		"samples": int64(1000),
+		"loss":    0.5,
+		"_wandb": map[string]any{"aggregation": map[string]any{
+			"samples": "sum",
+		}},
+	}
+	local := map[string]any{"samples": int64(200), "loss": 0.1}
+
+	summary := runbranch.MergeSummary(runbranch.SummaryMergePreferResumed, resumed, local)
+
+	assert.Equal(t, int64(1200), summary["samples"])
+	assert.Equal(t, 0.5, summary["loss"])
+	assert.Equal(t, int64(1000), resumed["samples"],
+		"resumed summary must not be modified")
+}
+
+func TestMergeSummary_AggregationHintSkipsNonNumbers(t *testing.T) {
+	resumed := map[string]any{
+		"samples": "many",
+		"_wandb": map[string]any{"aggregation": map[string]any{
+			"samples": "sum",
+		}},
+	}
+	local := map[string]any{"samples": int64(200)}
+
+	summary := runbranch.MergeSummary(runbranch.SummaryMergeUnion, resumed, local)
+
+	assert.Equal(t, "many", summary["samples"])
+}
+
+func TestSummaryAggregationHints(t *testing.T) {
+	hints := runbranch.SummaryAggregationHints(map[string]any{
+		"_wandb": map[string]any{"aggregation": map[string]any{
+			"samples": "sum",
+			"batches": "count",
+			"loss":    "mean",
+		}},
+	})
+
+	assert.Equal(t,
+		map[string]runbranch.SummaryAggregation{
+			"samples": runbranch.SummaryAggregateSum,
+			"batches": runbranch.SummaryAggregateCount,
+		},
+		hints)
+	assert.Empty(t, runbranch.SummaryAggregationHints(map[string]any{"loss": 0.5}))
+}
+
+func TestGetStartingStepAndRuntime_NewRun(t *testing.T) {
+	var params *runbranch.RunParams
+
+	assert.Equal(t, int64(0), params.GetStartingStep())
+	assert.Equal(t, int32(0), params.GetRuntime())
+	assert.Equal(t, int64(0), (&runbranch.RunParams{}).GetStartingStep())
+	assert.Equal(t, int32(0), (&runbranch.RunParams{}).GetRuntime())
+}
+
+func TestType_NewRun(t *testing.T) {
+	var params *runbranch.RunParams
+
+	assert.Equal(t, runbranch.BranchNone, params.Type())
+	assert.Equal(t, runbranch.BranchNone, runbranch.NewRunParams().Type())
+}
+
+func TestType_Resumed(t *testing.T) {
+	params := &runbranch.RunParams{Resumed: true}
+
+	assert.Equal(t, runbranch.BranchResume, params.Type())
+	assert.Equal(t, "resume", params.Type().String())
+}
+
+func TestType_Forked(t *testing.T) {
+	params := runbranch.NewRunParams()
+	params.Merge(&runbranch.RunParams{Forked: true})
+
+	assert.Equal(t, runbranch.BranchFork, params.Type())
+	assert.Equal(t, "fork", params.Type().String())
+}
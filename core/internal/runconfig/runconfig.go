@@ -2,6 +2,9 @@ package runconfig
 
 import (
 	"fmt"
+	"reflect"
+	"slices"
+	"strings"
 
 	"github.com/wandb/simplejsonext"
 	"github.com/wandb/wandb/core/internal/corelib"
@@ -17,6 +20,42 @@ const (
 	FormatJson
 )
 
+// How to resolve top-level keys that are set both locally and in the config
+// of a run that's being resumed.
+type ConfigMergePolicy int
+
+const (
+	// Keep the local value. This is the default.
+	ConfigMergePreferLocal ConfigMergePolicy = iota
+
+	// Replace the local value with the resumed run's value.
+	ConfigMergePreferResumed
+
+	// Fail if the values differ.
+	ConfigMergeErrorOnConflict
+)
+
+// ParseConfigMergePolicy parses a config merge policy: "prefer_local",
+// "prefer_resumed" or "error".
+//
+// An empty string means ConfigMergePreferLocal.
+func ParseConfigMergePolicy(value string) (ConfigMergePolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "prefer_local":
+		return ConfigMergePreferLocal, nil
+	case "prefer_resumed":
+		return ConfigMergePreferResumed, nil
+	case "error":
+		return ConfigMergeErrorOnConflict, nil
+	default:
+		return ConfigMergePreferLocal, fmt.Errorf(
+			"runconfig: invalid config merge policy %q,"+
+				" expected prefer_local, prefer_resumed or error",
+			value,
+		)
+	}
+}
+
 // The configuration of a run.
 //
 // This is usually used for hyperparameters and some run metadata like the
@@ -126,7 +165,32 @@ func (rc *RunConfig) AddTelemetryAndMetrics(
 }
 
 // Incorporates the config from a run that's being resumed.
-func (rc *RunConfig) MergeResumedConfig(oldConfig map[string]any) {
+//
+// The policy decides which value wins for top-level keys set in both
+// configs. With ConfigMergeErrorOnConflict, an error listing the
+// conflicting keys is returned and the config is left unchanged.
+//
+// The "_wandb" key is always kept local, as it describes the current
+// process.
+func (rc *RunConfig) MergeResumedConfig(
+	oldConfig map[string]any,
+	policy ConfigMergePolicy,
+) error {
+	switch policy {
+	case ConfigMergePreferLocal:
+	case ConfigMergePreferResumed:
+		rc.replaceKeys(oldConfig)
+	case ConfigMergeErrorOnConflict:
+		if conflicts := rc.conflictingKeys(oldConfig); len(conflicts) > 0 {
+			return fmt.Errorf(
+				"runconfig: resumed config conflicts with local config for keys: %s",
+				strings.Join(conflicts, ", "),
+			)
+		}
+	default:
+		return fmt.Errorf("runconfig: unknown config merge policy: %d", policy)
+	}
+
 	// Add any top-level keys that aren't already set.
 	rc.addUnsetKeysFromSubtree(oldConfig, nil)
 
@@ -142,6 +206,47 @@ func (rc *RunConfig) MergeResumedConfig(oldConfig map[string]any) {
 		oldConfig,
 		[]string{"_wandb", "viz"},
 	)
+
+	return nil
+}
+
+// replaceKeys overwrites top-level keys with their values in oldConfig.
+func (rc *RunConfig) replaceKeys(oldConfig map[string]any) {
+	for key, value := range oldConfig {
+		if key == "_wandb" {
+			continue
+		}
+
+		path := pathtree.PathOf(key)
+		rc.pathTree.Remove(path)
+		switch x := value.(type) {
+		case map[string]any:
+			pathtree.SetSubtree(rc.pathTree, path, x)
+		default:
+			rc.pathTree.Set(path, x)
+		}
+	}
+}
+
+// conflictingKeys returns the sorted top-level keys whose local values
+// differ from those in oldConfig.
+func (rc *RunConfig) conflictingKeys(oldConfig map[string]any) []string {
+	local := rc.pathTree.CloneTree()
+
+	var conflicts []string
+	for key, value := range oldConfig {
+		if key == "_wandb" {
+			continue
+		}
+
+		localValue, ok := local[key]
+		if ok && !reflect.DeepEqual(localValue, value) {
+			conflicts = append(conflicts, key)
+		}
+	}
+
+	slices.Sort(conflicts)
+	return conflicts
 }
 
 func (rc *RunConfig) addUnsetKeysFromSubtree(
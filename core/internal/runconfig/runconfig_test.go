@@ -150,3 +150,106 @@ func TestCloneTree(t *testing.T) {
 		runConfig.CloneTree(),
 	)
 }
+
+func TestMergeResumedConfig(t *testing.T) {
+	resumed := map[string]any{
+		"lr":     0.1,
+		"epochs": int64(10),
+		"_wandb": map[string]any{"cli_version": "old"},
+	}
+
+	testCases := []struct {
+		name     string
+		policy   runconfig.ConfigMergePolicy
+		local    map[string]any
+		expected map[string]any
+	}{
+		{
+			name:   "PreferLocal",
+			policy: runconfig.ConfigMergePreferLocal,
+			local:  map[string]any{"lr": 0.2, "_wandb": map[string]any{"cli_version": "new"}},
+			expected: map[string]any{
+				"lr":     0.2,
+				"epochs": int64(10),
+				"_wandb": map[string]any{"cli_version": "new"},
+			},
+		},
+		{
+			name:   "PreferResumed",
+			policy: runconfig.ConfigMergePreferResumed,
+			local:  map[string]any{"lr": 0.2, "_wandb": map[string]any{"cli_version": "new"}},
+			expected: map[string]any{
+				"lr":     0.1,
+				"epochs": int64(10),
+				"_wandb": map[string]any{"cli_version": "new"},
+			},
+		},
+		{
+			name:   "ErrorOnConflictDisjoint",
+			policy: runconfig.ConfigMergeErrorOnConflict,
+			local:  map[string]any{"batch_size": int64(32), "lr": 0.1},
+			expected: map[string]any{
+				"lr":         0.1,
+				"epochs":     int64(10),
+				"batch_size": int64(32),
+				"_wandb":     map[string]any{"cli_version": "old"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runConfig := runconfig.NewFrom(tc.local)
+
+			err := runConfig.MergeResumedConfig(resumed, tc.policy)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, runConfig.CloneTree())
+		})
+	}
+}
+
+func TestMergeResumedConfigErrorOnConflict(t *testing.T) {
+	runConfig := runconfig.NewFrom(map[string]any{
+		"lr":     0.2,
+		"epochs": int64(5),
+		"seed":   int64(1),
+	})
+
+	err := runConfig.MergeResumedConfig(
+		map[string]any{"lr": 0.1, "epochs": int64(10), "seed": int64(1)},
+		runconfig.ConfigMergeErrorOnConflict,
+	)
+
+	assert.ErrorContains(t, err, "epochs, lr")
+	assert.Equal(t,
+		map[string]any{"lr": 0.2, "epochs": int64(5), "seed": int64(1)},
+		runConfig.CloneTree(),
+	)
+}
+
+func TestParseConfigMergePolicy(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected runconfig.ConfigMergePolicy
+	}{
+		{"", runconfig.ConfigMergePreferLocal},
+		{"prefer_local", runconfig.ConfigMergePreferLocal},
+		{"Prefer_Resumed", runconfig.ConfigMergePreferResumed},
+		{" error ", runconfig.ConfigMergeErrorOnConflict},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			policy, err := runconfig.ParseConfigMergePolicy(tc.value)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, policy)
+		})
+	}
+}
+
+func TestParseConfigMergePolicy_Invalid(t *testing.T) {
+	policy, err := runconfig.ParseConfigMergePolicy("overwrite")
+
+	assert.ErrorContains(t, err, `invalid config merge policy "overwrite"`)
+	assert.Equal(t, runconfig.ConfigMergePreferLocal, policy)
+}
@@ -0,0 +1,241 @@
+package settings
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envSettings are the settings read from environment variables rather than
+// from the Settings proto.
+//
+// They are all read once, by readEnvSettings, when Settings is created.
+// The getters that return them document the variables.
+type envSettings struct {
+	identityFederation  bool
+	identityTokenSet    bool
+	identityTokenSource string
+
+	clientCertFile string
+	clientKeyFile  string
+	caCertFile     string
+
+	disabledMonitors          []string
+	prometheusExporter        bool
+	prometheusExporterAddress string
+
+	gpuMetricPrefix         string
+	gpuMetricRenames        string
+	gpuMetricWindow         time.Duration
+	gpuMetricAggregations   string
+	gpuMetricStatistics     string
+	gpuMetricAllow          []string
+	gpuMetricDeny           []string
+	gpuPowerHistogram       bool
+	gpuPowerHistogramBounds string
+	gpuIdleGracePeriod      time.Duration
+	gpuIdleGracePeriodSet   bool
+	gpuIdleSampleInterval   time.Duration
+	gpuStatsPath            string
+
+	resumeSnapshot             bool
+	resumeDropMismatchedConfig bool
+	resumeConfigMerge          string
+	resumeTagMerge             string
+	resumeSummaryMerge         string
+	createdRunStartTimeout     time.Duration
+	createdRunPollInterval     time.Duration
+
+	disableCredentialsFile  bool
+	accessToken             string
+	accessTokenExpiresAt    time.Time
+	credentialsFile         string
+	credentialsFileMode     os.FileMode
+	credentialsInMemory     bool
+	credentialHeaders       string
+	appendCredentialHeaders bool
+
+	oidcTokenEndpoint          string
+	oidcAudience               string
+	oidcScope                  string
+	oidcClientID               string
+	oidcClientAssertionKeyFile string
+	oidcClientAssertionKeyID   string
+	oidcInsecureSkipVerify     bool
+	oidcClockSkew              time.Duration
+
+	httpIPPreference   string
+	httpConnectTimeout time.Duration
+
+	s3Restore             bool
+	s3RestoreDays         int32
+	s3RestorePollInterval time.Duration
+	s3RestoreTimeout      time.Duration
+}
+
+// readEnvSettings reads the settings from environment variables using
+// lookup, like os.LookupEnv.
+//
+// Values that can't be parsed are ignored.
+func readEnvSettings(lookup func(string) (string, bool)) envSettings {
+	env := envLookup(lookup)
+
+	gpuIdleGracePeriod, gpuIdleGracePeriodSet := env.nonNegativeSeconds(
+		"WANDB_GPU_IDLE_GRACE_PERIOD")
+
+	return envSettings{
+		identityFederation:  env.bool("WANDB_ENABLE_IDENTITY_FEDERATION"),
+		identityTokenSet:    env.string("WANDB_IDENTITY_TOKEN") != "",
+		identityTokenSource: env.string("WANDB_IDENTITY_TOKEN_SOURCE"),
+
+		clientCertFile: env.string("WANDB_CLIENT_CERT_FILE"),
+		clientKeyFile:  env.string("WANDB_CLIENT_KEY_FILE"),
+		caCertFile:     env.string("WANDB_CA_CERT_FILE"),
+
+		disabledMonitors:          env.list("WANDB_DISABLED_MONITORS"),
+		prometheusExporter:        env.bool("WANDB_PROMETHEUS_EXPORTER"),
+		prometheusExporterAddress: env.string("WANDB_PROMETHEUS_EXPORTER_ADDRESS"),
+
+		gpuMetricPrefix:         env.string("WANDB_GPU_METRIC_PREFIX"),
+		gpuMetricRenames:        env.string("WANDB_GPU_METRIC_RENAMES"),
+		gpuMetricWindow:         env.seconds("WANDB_GPU_METRIC_WINDOW"),
+		gpuMetricAggregations:   env.string("WANDB_GPU_METRIC_AGGREGATIONS"),
+		gpuMetricStatistics:     env.string("WANDB_GPU_METRIC_STATISTICS"),
+		gpuMetricAllow:          env.list("WANDB_GPU_METRIC_ALLOW"),
+		gpuMetricDeny:           env.list("WANDB_GPU_METRIC_DENY"),
+		gpuPowerHistogram:       env.bool("WANDB_GPU_POWER_HISTOGRAM"),
+		gpuPowerHistogramBounds: env.string("WANDB_GPU_POWER_HISTOGRAM_BOUNDARIES"),
+		gpuIdleGracePeriod:      gpuIdleGracePeriod,
+		gpuIdleGracePeriodSet:   gpuIdleGracePeriodSet,
+		gpuIdleSampleInterval:   env.seconds("WANDB_GPU_IDLE_SAMPLE_INTERVAL"),
+		gpuStatsPath:            env.string("WANDB_GPU_STATS_PATH"),
+
+		resumeSnapshot:             env.bool("WANDB_RESUME_SNAPSHOT"),
+		resumeDropMismatchedConfig: env.bool("WANDB_RESUME_DROP_MISMATCHED_CONFIG"),
+		resumeConfigMerge:          env.string("WANDB_RESUME_CONFIG_MERGE"),
+		resumeTagMerge:             env.string("WANDB_RESUME_TAG_MERGE"),
+		resumeSummaryMerge:         env.string("WANDB_RESUME_SUMMARY_MERGE"),
+		createdRunStartTimeout:     env.seconds("WANDB_CREATED_RUN_START_TIMEOUT"),
+		createdRunPollInterval:     env.seconds("WANDB_CREATED_RUN_POLL_INTERVAL"),
+
+		disableCredentialsFile:  env.bool("WANDB_DISABLE_CREDENTIALS_FILE"),
+		accessToken:             env.string("WANDB_ACCESS_TOKEN"),
+		accessTokenExpiresAt:    env.time("WANDB_ACCESS_TOKEN_EXPIRES_AT"),
+		credentialsFile:         env.string("WANDB_CREDENTIALS_FILE"),
+		credentialsFileMode:     env.fileMode("WANDB_CREDENTIALS_FILE_MODE"),
+		credentialsInMemory:     env.bool("WANDB_CREDENTIALS_IN_MEMORY"),
+		credentialHeaders:       env.string("WANDB_CREDENTIAL_HEADERS"),
+		appendCredentialHeaders: env.bool("WANDB_CREDENTIAL_HEADERS_APPEND"),
+
+		oidcTokenEndpoint:          env.string("WANDB_OIDC_TOKEN_ENDPOINT"),
+		oidcAudience:               env.string("WANDB_OIDC_AUDIENCE"),
+		oidcScope:                  env.string("WANDB_OIDC_SCOPE"),
+		oidcClientID:               env.string("WANDB_OIDC_CLIENT_ID"),
+		oidcClientAssertionKeyFile: env.string("WANDB_OIDC_CLIENT_ASSERTION_KEY_FILE"),
+		oidcClientAssertionKeyID:   env.string("WANDB_OIDC_CLIENT_ASSERTION_KEY_ID"),
+		oidcInsecureSkipVerify:     env.bool("WANDB_OIDC_INSECURE_SKIP_VERIFY"),
+		oidcClockSkew:              env.signedSeconds("WANDB_OIDC_CLOCK_SKEW"),
+
+		httpIPPreference:   env.string("WANDB_HTTP_IP_PREFERENCE"),
+		httpConnectTimeout: env.seconds("WANDB_HTTP_CONNECT_TIMEOUT"),
+
+		s3Restore:             env.bool("WANDB_S3_RESTORE"),
+		s3RestoreDays:         env.positiveInt32("WANDB_S3_RESTORE_DAYS"),
+		s3RestorePollInterval: env.seconds("WANDB_S3_RESTORE_POLL_INTERVAL"),
+		s3RestoreTimeout:      env.seconds("WANDB_S3_RESTORE_TIMEOUT"),
+	}
+}
+
+// envLookup parses environment variables.
+type envLookup func(string) (string, bool)
+
+// string returns the variable's value, or an empty string if it's unset.
+func (lookup envLookup) string(name string) string {
+	value, _ := lookup(name)
+	return value
+}
+
+// list splits the variable on commas, dropping surrounding whitespace and
+// empty items, and returns nil if it's unset.
+func (lookup envLookup) list(name string) []string {
+	var items []string
+	for _, item := range strings.Split(lookup.string(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// bool parses the variable like "true", returning false if it's unset or
+// invalid.
+func (lookup envLookup) bool(name string) bool {
+	value, err := strconv.ParseBool(lookup.string(name))
+	return err == nil && value
+}
+
+// positiveInt32 parses the variable as a positive 32-bit integer,
+// returning zero if it's unset or invalid.
+func (lookup envLookup) positiveInt32(name string) int32 {
+	value, err := strconv.ParseInt(lookup.string(name), 10, 32)
+	if err != nil || value <= 0 {
+		return 0
+	}
+	return int32(value)
+}
+
+// seconds parses the variable as a positive number of seconds, returning
+// zero if it's unset or invalid.
+func (lookup envLookup) seconds(name string) time.Duration {
+	seconds, err := strconv.ParseFloat(lookup.string(name), 64)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// nonNegativeSeconds parses the variable as a number of seconds that may
+// be zero, and reports whether it's set to a valid value.
+func (lookup envLookup) nonNegativeSeconds(name string) (time.Duration, bool) {
+	value, ok := lookup(name)
+	if !ok {
+		return 0, false
+	}
+
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
+
+// signedSeconds parses the variable as a number of seconds that may be
+// negative, returning zero if it's unset or invalid.
+func (lookup envLookup) signedSeconds(name string) time.Duration {
+	seconds, err := strconv.ParseFloat(lookup.string(name), 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// time parses the variable as an RFC 3339 timestamp, returning the zero
+// time if it's unset or invalid.
+func (lookup envLookup) time(name string) time.Time {
+	t, err := time.Parse(time.RFC3339, lookup.string(name))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// fileMode parses the variable as octal permissions like "0640",
+// returning zero if it's unset or invalid.
+func (lookup envLookup) fileMode(name string) os.FileMode {
+	mode, err := strconv.ParseUint(lookup.string(name), 8, 32)
+	if err != nil {
+		return 0
+	}
+	return os.FileMode(mode)
+}
@@ -4,10 +4,13 @@ package settings
 import (
 	"fmt"
 	"net/url"
+	"os"
 	"time"
 
 	"github.com/wandb/wandb/core/internal/auth"
 	spb "github.com/wandb/wandb/core/pkg/service_go_proto"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
@@ -20,11 +23,49 @@ type Settings struct {
 	//
 	// DO NOT ADD USAGES. Used to refactor incrementally.
 	Proto *spb.Settings
+
+	// Settings that aren't in the proto, read from environment variables.
+	env envSettings
 }
 
 // Parses the Settings proto into a Settings object.
+//
+// Settings that aren't in the proto are read from environment variables.
 func From(proto *spb.Settings) *Settings {
-	return &Settings{Proto: proto}
+	return &Settings{Proto: proto, env: readEnvSettings(os.LookupEnv)}
+}
+
+// RedactSecret masks all but the last 4 characters of a secret, like an
+// API key, so that it can be logged.
+//
+// Secrets too short to reveal any characters are masked completely.
+func RedactSecret(secret string) string {
+	const visible = 4
+	if secret == "" {
+		return ""
+	}
+	if len(secret) <= 2*visible {
+		return "****"
+	}
+	return "****" + secret[len(secret)-visible:]
+}
+
+// Redacted returns a copy of the settings proto with secrets masked.
+//
+// The API key is masked with RedactSecret. The identity token is only
+// referenced by path and is never read into the settings.
+func (s *Settings) Redacted() *spb.Settings {
+	redacted := proto.Clone(s.Proto).(*spb.Settings)
+	if redacted.ApiKey != nil {
+		redacted.ApiKey = wrapperspb.String(RedactSecret(redacted.ApiKey.GetValue()))
+	}
+	return redacted
+}
+
+// String formats the settings with secrets masked, so that they're safe
+// to include in logs and errors.
+func (s *Settings) String() string {
+	return prototext.Format(s.Redacted())
 }
 
 // Ensures the APIKey is set if it needs to be.
@@ -58,11 +99,429 @@ func (s *Settings) GetAPIKey() string {
 	return s.Proto.ApiKey.GetValue()
 }
 
+// Whether identity federation, authenticating with an identity token
+// instead of an API key, is enabled.
+//
+// Read from the WANDB_ENABLE_IDENTITY_FEDERATION environment variable,
+// like "true". It is disabled by default while the feature is reintroduced
+// in wandb-core.
+func (s *Settings) IsIdentityFederationEnabled() bool {
+	return s.env.identityFederation
+}
+
 // Path to file containing an identity token for authentication.
 func (s *Settings) GetIdentityTokenFile() string {
 	return s.Proto.IdentityTokenFile.GetValue()
 }
 
+// The name of the environment variable containing an identity token for
+// authentication, if it is set.
+//
+// CI systems can inject the token as WANDB_IDENTITY_TOKEN instead of
+// writing it to a file. Returns an empty string if it isn't set.
+func (s *Settings) GetIdentityTokenEnvVar() string {
+	if !s.env.identityTokenSet {
+		return ""
+	}
+	return "WANDB_IDENTITY_TOKEN"
+}
+
+// Where to fetch the identity token for authentication from, instead of
+// a file.
+//
+// Read from the WANDB_IDENTITY_TOKEN_SOURCE environment variable. It is
+// "gcp" or "azure" to fetch the token from the cloud's instance metadata
+// server, or empty to use the identity token file.
+func (s *Settings) GetIdentityTokenSource() string {
+	return s.env.identityTokenSource
+}
+
+// Path to a client certificate to present to servers that request one.
+//
+// Read from the WANDB_CLIENT_CERT_FILE environment variable. It is used
+// with GetClientKeyFile for mutual TLS, like behind an mTLS gateway.
+func (s *Settings) GetClientCertFile() string {
+	return s.env.clientCertFile
+}
+
+// Path to the private key of the client certificate.
+//
+// Read from the WANDB_CLIENT_KEY_FILE environment variable.
+func (s *Settings) GetClientKeyFile() string {
+	return s.env.clientKeyFile
+}
+
+// Path to CA certificates to trust in addition to the system's.
+//
+// Read from the WANDB_CA_CERT_FILE environment variable.
+func (s *Settings) GetCACertFile() string {
+	return s.env.caCertFile
+}
+
+// Names of the system monitors not to run, like "gpu,network".
+//
+// Read from the WANDB_DISABLED_MONITORS environment variable as a
+// comma-separated list. OpenMetrics endpoints are named by their key in
+// the _stats_open_metrics_endpoints setting.
+func (s *Settings) GetDisabledMonitors() []string {
+	return s.env.disabledMonitors
+}
+
+// Whether to serve system metrics for scraping by Prometheus.
+//
+// Read from the WANDB_PROMETHEUS_EXPORTER environment variable, like
+// "true".
+func (s *Settings) GetPrometheusExporter() bool {
+	return s.env.prometheusExporter
+}
+
+// The address at which to serve system metrics for Prometheus, like
+// "127.0.0.1:9464".
+//
+// Read from the WANDB_PROMETHEUS_EXPORTER_ADDRESS environment variable.
+// Only used if WANDB_PROMETHEUS_EXPORTER is set. If empty, the monitor's
+// default is used.
+func (s *Settings) GetPrometheusExporterAddress() string {
+	return s.env.prometheusExporterAddress
+}
+
+// A prefix for the keys of GPU metrics, like "system/".
+//
+// Read from the WANDB_GPU_METRIC_PREFIX environment variable.
+func (s *Settings) GetGPUMetricPrefix() string {
+	return s.env.gpuMetricPrefix
+}
+
+// Renames of GPU metrics, like "powerWatts=power_w,temp=temperature".
+//
+// Read from the WANDB_GPU_METRIC_RENAMES environment variable. Each rule
+// renames a metric for every GPU and for the process-specific variant.
+func (s *Settings) GetGPUMetricRenames() string {
+	return s.env.gpuMetricRenames
+}
+
+// The window over which GPU metrics are aggregated, like 15 seconds.
+//
+// Read from the WANDB_GPU_METRIC_WINDOW environment variable in seconds.
+// If zero, the default, each sample is reported as is.
+func (s *Settings) GetGPUMetricWindow() time.Duration {
+	return s.env.gpuMetricWindow
+}
+
+// How GPU metrics are aggregated over the metric window, like
+// "gpu.*.temp=max,gpu.*.powerWatts=max".
+//
+// Read from the WANDB_GPU_METRIC_AGGREGATIONS environment variable. Each
+// rule maps a metric key pattern to one of mean, max, min, last or p95;
+// the first matching rule applies, and other metrics are averaged. Only
+// used if WANDB_GPU_METRIC_WINDOW is set.
+func (s *Settings) GetGPUMetricAggregations() string {
+	return s.env.gpuMetricAggregations
+}
+
+// Statistics of each GPU metric over the metric window that are reported
+// along with it, like "max,p95".
+//
+// Read from the WANDB_GPU_METRIC_STATISTICS environment variable. Each
+// statistic is max or a percentile like p95 or p99, and is reported under
+// the metric's key with the statistic as a suffix, like gpu.0.gpu.p95. By
+// default, none are reported. Only used if WANDB_GPU_METRIC_WINDOW is set.
+func (s *Settings) GetGPUMetricStatistics() string {
+	return s.env.gpuMetricStatistics
+}
+
+// Patterns of the GPU metrics to report, like "gpu.*.temp,gpu.*.powerWatts".
+//
+// Read from the WANDB_GPU_METRIC_ALLOW environment variable as a
+// comma-separated list of globs matched against the default metric keys.
+// If empty, the default, all GPU metrics that aren't denied are reported.
+func (s *Settings) GetGPUMetricAllow() []string {
+	return s.env.gpuMetricAllow
+}
+
+// Patterns of the GPU metrics not to report, like "gpu.process.*".
+//
+// Read from the WANDB_GPU_METRIC_DENY environment variable as a
+// comma-separated list of globs. It takes precedence over
+// WANDB_GPU_METRIC_ALLOW.
+func (s *Settings) GetGPUMetricDeny() []string {
+	return s.env.gpuMetricDeny
+}
+
+// Whether to report a histogram of each GPU's power draw over the run.
+//
+// Read from the WANDB_GPU_POWER_HISTOGRAM environment variable, like
+// "true".
+func (s *Settings) GetGPUPowerHistogram() bool {
+	return s.env.gpuPowerHistogram
+}
+
+// The bucket edges of the GPU power draw histogram in watts, like
+// "0,100,200,300".
+//
+// Read from the WANDB_GPU_POWER_HISTOGRAM_BOUNDARIES environment variable.
+// Only used if WANDB_GPU_POWER_HISTOGRAM is set. If empty, the buckets are
+// 50W wide from 0W to 750W.
+func (s *Settings) GetGPUPowerHistogramBoundaries() string {
+	return s.env.gpuPowerHistogramBounds
+}
+
+// How long the monitored process may go without using any GPU before GPU
+// sampling slows down, and whether it's enabled.
+//
+// Read from the WANDB_GPU_IDLE_GRACE_PERIOD environment variable in
+// seconds. Sampling is never slowed down if it's unset, the default.
+func (s *Settings) GetGPUIdleGracePeriod() (time.Duration, bool) {
+	return s.env.gpuIdleGracePeriod, s.env.gpuIdleGracePeriodSet
+}
+
+// How often GPUs are sampled while the monitored process isn't using any.
+//
+// Read from the WANDB_GPU_IDLE_SAMPLE_INTERVAL environment variable in
+// seconds. Only used if WANDB_GPU_IDLE_GRACE_PERIOD is set. If zero, the
+// monitor's default is used.
+func (s *Settings) GetGPUIdleSampleInterval() time.Duration {
+	return s.env.gpuIdleSampleInterval
+}
+
+// The path to the gpu_stats binary used to collect GPU metrics.
+//
+// Read from the WANDB_GPU_STATS_PATH environment variable. If empty, the
+// default, the binary next to the wandb-core executable is used.
+func (s *Settings) GetGPUStatsPath() string {
+	return s.env.gpuStatsPath
+}
+
+// Whether resuming a run writes a snapshot of the state the server
+// reported and the state the run resumes with to the run's files
+// directory, for debugging.
+//
+// Read from the WANDB_RESUME_SNAPSHOT environment variable, like "true".
+func (s *Settings) GetResumeSnapshot() bool {
+	return s.env.resumeSnapshot
+}
+
+// How long to wait for a run created ahead of time to start before
+// treating it as a new run, such as a sweep run picked up by an agent.
+//
+// Read from the WANDB_CREATED_RUN_START_TIMEOUT environment variable in
+// seconds. Zero, the default, means not to wait.
+func (s *Settings) GetCreatedRunStartTimeout() time.Duration {
+	return s.env.createdRunStartTimeout
+}
+
+// The first interval between checks for a created run to start.
+//
+// Read from the WANDB_CREATED_RUN_POLL_INTERVAL environment variable in
+// seconds. The interval doubles after each check.
+func (s *Settings) GetCreatedRunPollInterval() time.Duration {
+	return s.env.createdRunPollInterval
+}
+
+// Whether resuming a run drops config values that don't have the expected
+// form, rather than failing to restore the config.
+//
+// Read from the WANDB_RESUME_DROP_MISMATCHED_CONFIG environment variable,
+// like "true".
+func (s *Settings) GetResumeDropMismatchedConfig() bool {
+	return s.env.resumeDropMismatchedConfig
+}
+
+// Which config values win when resuming a run, for keys set both locally
+// and in the resumed run: "prefer_local", "prefer_resumed" or "error".
+//
+// Read from the WANDB_RESUME_CONFIG_MERGE environment variable.
+func (s *Settings) GetResumeConfigMerge() string {
+	return s.env.resumeConfigMerge
+}
+
+// How the tags passed to init combine with a resumed run's tags:
+// "replace", "append" or "resumed_only".
+//
+// Read from the WANDB_RESUME_TAG_MERGE environment variable.
+func (s *Settings) GetResumeTagMerge() string {
+	return s.env.resumeTagMerge
+}
+
+// How summary values set before a run resumes combine with the resumed
+// run's summary: "prefer_resumed", "prefer_local" or "union".
+//
+// Read from the WANDB_RESUME_SUMMARY_MERGE environment variable.
+func (s *Settings) GetResumeSummaryMerge() string {
+	return s.env.resumeSummaryMerge
+}
+
+// Path to file for writing temporary access tokens.
+//
+// The WANDB_CREDENTIALS_FILE environment variable takes precedence over
+// the setting, which allows pointing it at a per-run location.
+func (s *Settings) GetCredentialsFile() string {
+	if s.env.credentialsFile != "" {
+		return s.env.credentialsFile
+	}
+	return s.Proto.CredentialsFile.GetValue()
+}
+
+// Permissions of the credentials file, or zero for the default.
+//
+// Read from the WANDB_CREDENTIALS_FILE_MODE environment variable as an
+// octal number like "0640". Values that aren't octal numbers are ignored.
+func (s *Settings) GetCredentialsFileMode() os.FileMode {
+	return s.env.credentialsFileMode
+}
+
+// Whether to authorize requests only with credentials given directly in
+// the settings, never reading or writing the credentials or .netrc files.
+//
+// Read from the WANDB_DISABLE_CREDENTIALS_FILE environment variable, like
+// "true". This suits tests and short-lived jobs. The access token is used
+// if set, otherwise the API key.
+func (s *Settings) GetDisableCredentialsFile() bool {
+	return s.env.disableCredentialsFile
+}
+
+// An access token to authorize requests with, if credentials files are
+// disabled.
+//
+// Read from the WANDB_ACCESS_TOKEN environment variable.
+func (s *Settings) GetAccessToken() string {
+	return s.env.accessToken
+}
+
+// When the access token expires, or the zero time if it doesn't.
+//
+// Read from the WANDB_ACCESS_TOKEN_EXPIRES_AT environment variable as an
+// RFC 3339 timestamp.
+func (s *Settings) GetAccessTokenExpiresAt() time.Time {
+	return s.env.accessTokenExpiresAt
+}
+
+// Whether to keep OAuth2 access tokens only in memory, never reading or
+// writing the credentials file.
+//
+// Read from the WANDB_CREDENTIALS_IN_MEMORY environment variable, like
+// "true". This suits read-only file systems.
+func (s *Settings) GetCredentialsInMemory() bool {
+	return s.env.credentialsInMemory
+}
+
+// Extra headers to set on every request along with the credentials, like
+// "X-Tenant-ID=acme,X-CDN-Token=secret".
+//
+// Read from the WANDB_CREDENTIAL_HEADERS environment variable. This is for
+// gateways in front of the W&B server that require their own headers.
+func (s *Settings) GetCredentialHeaders() string {
+	return s.env.credentialHeaders
+}
+
+// Whether the extra credential headers are added after any existing
+// values of the same headers, rather than replacing them.
+//
+// Read from the WANDB_CREDENTIAL_HEADERS_APPEND environment variable,
+// like "true".
+func (s *Settings) GetAppendCredentialHeaders() bool {
+	return s.env.appendCredentialHeaders
+}
+
+// URL of the OIDC token endpoint used to exchange identity tokens.
+//
+// Read from the WANDB_OIDC_TOKEN_ENDPOINT environment variable. If empty,
+// the endpoint is derived from the base URL.
+func (s *Settings) GetOIDCTokenEndpoint() string {
+	return s.env.oidcTokenEndpoint
+}
+
+// The audience to request when exchanging the identity token.
+//
+// Read from the WANDB_OIDC_AUDIENCE environment variable.
+func (s *Settings) GetOIDCAudience() string {
+	return s.env.oidcAudience
+}
+
+// The scopes to request when exchanging the identity token.
+//
+// Read from the WANDB_OIDC_SCOPE environment variable, space-separated.
+func (s *Settings) GetOIDCScope() string {
+	return s.env.oidcScope
+}
+
+// The OIDC client ID, for authenticating the client to the token endpoint.
+//
+// Read from the WANDB_OIDC_CLIENT_ID environment variable.
+func (s *Settings) GetOIDCClientID() string {
+	return s.env.oidcClientID
+}
+
+// Path to the PEM-encoded private key that signs client assertions for the
+// private_key_jwt token endpoint authentication method.
+//
+// Read from the WANDB_OIDC_CLIENT_ASSERTION_KEY_FILE environment variable.
+// If empty, the default, the client doesn't authenticate to the token
+// endpoint. The client ID is required if it's set.
+func (s *Settings) GetOIDCClientAssertionKeyFile() string {
+	return s.env.oidcClientAssertionKeyFile
+}
+
+// The ID of the client assertion signing key registered with the OIDC
+// provider, sent as the assertion's "kid" header.
+//
+// Read from the WANDB_OIDC_CLIENT_ASSERTION_KEY_ID environment variable.
+// It's optional.
+func (s *Settings) GetOIDCClientAssertionKeyID() string {
+	return s.env.oidcClientAssertionKeyID
+}
+
+// Whether to skip verifying the TLS certificate of the OIDC token endpoint.
+//
+// Read from the WANDB_OIDC_INSECURE_SKIP_VERIFY environment variable, like
+// "true". This is INSECURE and only meant for staging servers with
+// self-signed certificates. It doesn't affect any other connections.
+func (s *Settings) GetOIDCInsecureSkipVerify() bool {
+	return s.env.oidcInsecureSkipVerify
+}
+
+// How far the local clock is behind the auth server's, for checking
+// when access tokens expire.
+//
+// Read from the WANDB_OIDC_CLOCK_SKEW environment variable in seconds. It
+// is negative if the local clock is ahead.
+func (s *Settings) GetOIDCClockSkew() time.Duration {
+	return s.env.oidcClockSkew
+}
+
+// Whether downloading an archived S3 object, like one in Glacier, restores
+// it and waits for the restore instead of failing.
+//
+// Read from the WANDB_S3_RESTORE environment variable, like "true".
+func (s *Settings) GetS3Restore() bool {
+	return s.env.s3Restore
+}
+
+// How many days an S3 object restored for a download stays available.
+//
+// Read from the WANDB_S3_RESTORE_DAYS environment variable. If zero, the
+// file transfer default is used.
+func (s *Settings) GetS3RestoreDays() int32 {
+	return s.env.s3RestoreDays
+}
+
+// How often to check whether an S3 object being restored is available.
+//
+// Read from the WANDB_S3_RESTORE_POLL_INTERVAL environment variable in
+// seconds. If zero, the file transfer default is used.
+func (s *Settings) GetS3RestorePollInterval() time.Duration {
+	return s.env.s3RestorePollInterval
+}
+
+// How long to wait for an S3 object to be restored before failing its
+// download.
+//
+// Read from the WANDB_S3_RESTORE_TIMEOUT environment variable in seconds.
+// If zero, the default, there is no limit.
+func (s *Settings) GetS3RestoreTimeout() time.Duration {
+	return s.env.s3RestoreTimeout
+}
+
 // Whether we are in offline mode.
 func (s *Settings) IsOffline() bool {
 	return s.Proto.XOffline.GetValue()
@@ -230,6 +689,23 @@ func (s *Settings) GetHTTPSProxy() string {
 	return s.Proto.HttpsProxy.GetValue()
 }
 
+// Which IP versions HTTP connections use, like "ipv6" to prefer IPv6.
+//
+// Read from the WANDB_HTTP_IP_PREFERENCE environment variable. See
+// api.ParseIPPreference for the values. If empty, the default, IPv4 and
+// IPv6 are raced.
+func (s *Settings) GetHTTPIPPreference() string {
+	return s.env.httpIPPreference
+}
+
+// How long establishing an HTTP connection may take.
+//
+// Read from the WANDB_HTTP_CONNECT_TIMEOUT environment variable in
+// seconds. If zero, the default, api.DefaultConnectTimeout is used.
+func (s *Settings) GetHTTPConnectTimeout() time.Duration {
+	return s.env.httpConnectTimeout
+}
+
 // Path to the script that created the run, if available.
 func (s *Settings) GetProgram() string {
 	return s.Proto.Program.GetValue()
@@ -0,0 +1,88 @@
+package settings_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wandb/wandb/core/internal/settings"
+	spb "github.com/wandb/wandb/core/pkg/service_go_proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const testAPIKey = "0123456789abcdef0123456789abcdef01234567"
+
+func TestRedactSecret(t *testing.T) {
+	assert.Equal(t, "", settings.RedactSecret(""))
+	assert.Equal(t, "****", settings.RedactSecret("short"))
+	assert.Equal(t, "****4567", settings.RedactSecret(testAPIKey))
+}
+
+func TestRedacted(t *testing.T) {
+	s := settings.From(&spb.Settings{
+		ApiKey:            wrapperspb.String(testAPIKey),
+		IdentityTokenFile: wrapperspb.String("/path/to/jwt.txt"),
+	})
+
+	redacted := s.Redacted()
+
+	assert.Equal(t, "****4567", redacted.GetApiKey().GetValue())
+	assert.Equal(t, "/path/to/jwt.txt", redacted.GetIdentityTokenFile().GetValue())
+	assert.Equal(t, testAPIKey, s.GetAPIKey(), "original settings unchanged")
+}
+
+func TestString_DoesNotLeakAPIKey(t *testing.T) {
+	s := settings.From(&spb.Settings{
+		ApiKey: wrapperspb.String(testAPIKey),
+	})
+
+	assert.NotContains(t, s.String(), testAPIKey)
+	assert.NotContains(t, fmt.Sprintf("%v", s), testAPIKey)
+	assert.NotContains(t, fmt.Errorf("bad settings: %v", s).Error(), testAPIKey)
+	assert.Contains(t, s.String(), "****4567")
+}
+
+func TestEnvSettings_ReadWhenCreated(t *testing.T) {
+	t.Setenv("WANDB_OIDC_AUDIENCE", "before")
+	t.Setenv("WANDB_GPU_METRIC_WINDOW", "1.5")
+	t.Setenv("WANDB_CREDENTIALS_FILE_MODE", "0640")
+	t.Setenv("WANDB_GPU_IDLE_GRACE_PERIOD", "0")
+	t.Setenv("WANDB_DISABLED_MONITORS", " gpu, ,network ")
+	t.Setenv("WANDB_S3_RESTORE", "true")
+	t.Setenv("WANDB_S3_RESTORE_DAYS", "3")
+	t.Setenv("WANDB_S3_RESTORE_POLL_INTERVAL", "30")
+	t.Setenv("WANDB_S3_RESTORE_TIMEOUT", "3600")
+	s := settings.From(&spb.Settings{})
+
+	t.Setenv("WANDB_OIDC_AUDIENCE", "after")
+
+	assert.Equal(t, "before", s.GetOIDCAudience())
+	assert.Equal(t, 1500*time.Millisecond, s.GetGPUMetricWindow())
+	assert.Equal(t, os.FileMode(0o640), s.GetCredentialsFileMode())
+	grace, ok := s.GetGPUIdleGracePeriod()
+	assert.True(t, ok)
+	assert.Zero(t, grace)
+	assert.Equal(t, []string{"gpu", "network"}, s.GetDisabledMonitors())
+	assert.True(t, s.GetS3Restore())
+	assert.Equal(t, int32(3), s.GetS3RestoreDays())
+	assert.Equal(t, 30*time.Second, s.GetS3RestorePollInterval())
+	assert.Equal(t, time.Hour, s.GetS3RestoreTimeout())
+}
+
+func TestEnvSettings_InvalidValuesIgnored(t *testing.T) {
+	t.Setenv("WANDB_GPU_METRIC_WINDOW", "-1")
+	t.Setenv("WANDB_CREDENTIALS_FILE_MODE", "rw-r-----")
+	t.Setenv("WANDB_CREDENTIALS_IN_MEMORY", "sometimes")
+	t.Setenv("WANDB_GPU_IDLE_GRACE_PERIOD", "soon")
+	t.Setenv("WANDB_S3_RESTORE_DAYS", "-2")
+	s := settings.From(&spb.Settings{})
+
+	assert.Zero(t, s.GetGPUMetricWindow())
+	assert.Zero(t, s.GetCredentialsFileMode())
+	assert.False(t, s.GetCredentialsInMemory())
+	_, ok := s.GetGPUIdleGracePeriod()
+	assert.False(t, ok)
+	assert.Zero(t, s.GetS3RestoreDays())
+}
@@ -62,10 +62,21 @@ type SenderParams struct {
 	TBHandler           *tensorboard.TBHandler
 	GraphqlClient       graphql.Client
 	Peeker              *observability.Peeker
+	TerminalPrinter     *observability.Printer
 	RunSummary          *runsummary.RunSummary
 	Mailbox             *mailbox.Mailbox
 	OutChan             chan *spb.Result
 	OutputFileName      *paths.RelativePath
+
+	// ConfigMergePolicy decides which config values win when resuming a run.
+	ConfigMergePolicy runconfig.ConfigMergePolicy
+
+	// TagMergePolicy decides how init tags combine with a resumed run's tags.
+	TagMergePolicy runbranch.TagMergePolicy
+
+	// SummaryMergePolicy decides how the local summary combines with a
+	// resumed run's summary.
+	SummaryMergePolicy runbranch.SummaryMergePolicy
 }
 
 // Sender is the sender for a stream it handles the incoming messages and sends to the server
@@ -134,6 +145,16 @@ type Sender struct {
 	// Keep track of config which is being updated incrementally
 	runConfig *runconfig.RunConfig
 
+	// configMergePolicy decides which config values win when resuming a run
+	configMergePolicy runconfig.ConfigMergePolicy
+
+	// tagMergePolicy decides how init tags combine with a resumed run's tags
+	tagMergePolicy runbranch.TagMergePolicy
+
+	// summaryMergePolicy decides how the local summary combines with a
+	// resumed run's summary
+	summaryMergePolicy runbranch.SummaryMergePolicy
+
 	// Keep track of exit record to pass to file stream when the time comes
 	exitRecord *spb.Record
 
@@ -153,6 +174,9 @@ type Sender struct {
 	// networkPeeker is a helper for peeking into network responses
 	networkPeeker *observability.Peeker
 
+	// terminalPrinter gathers terminal messages to send back to the user process
+	terminalPrinter *observability.Printer
+
 	// mailbox is used to store cancel functions for each mailbox slot
 	mailbox *mailbox.Mailbox
 
@@ -178,6 +202,9 @@ func NewSender(
 	s := &Sender{
 		runWork:             runWork,
 		runConfig:           runconfig.New(),
+		configMergePolicy:   params.ConfigMergePolicy,
+		tagMergePolicy:      params.TagMergePolicy,
+		summaryMergePolicy:  params.SummaryMergePolicy,
 		telemetry:           &spb.TelemetryRecord{CoreVersion: version.Version},
 		runConfigMetrics:    runmetric.NewRunConfigMetrics(),
 		logger:              params.Logger,
@@ -193,13 +220,14 @@ func NewSender(
 			params.GraphqlClient,
 			params.FileTransferManager,
 		),
-		tbHandler:     params.TBHandler,
-		networkPeeker: params.Peeker,
-		graphqlClient: params.GraphqlClient,
-		mailbox:       params.Mailbox,
-		runSummary:    params.RunSummary,
-		outChan:       params.OutChan,
-		startState:    runbranch.NewRunParams(),
+		tbHandler:       params.TBHandler,
+		networkPeeker:   params.Peeker,
+		terminalPrinter: params.TerminalPrinter,
+		graphqlClient:   params.GraphqlClient,
+		mailbox:         params.Mailbox,
+		runSummary:      params.RunSummary,
+		outChan:         params.OutChan,
+		startState:      runbranch.NewRunParams(),
 		configDebouncer: debounce.NewDebouncer(
 			configDebouncerRateLimit,
 			configDebouncerBurstSize,
@@ -496,6 +524,11 @@ func (s *Sender) sendRequestRunStart(_ *spb.RunStartRequest) {
 	// mark the run state as initialized, indicating the run has started and was
 	// successfully upserted on the server.
 	s.startState.Initialized = true
+	s.logger.Info(
+		"sender: sendRequestRunStart: starting run",
+		"runId", s.startState.RunID,
+		"branch", s.startState.Type().String(),
+	)
 
 	s.updateSettings()
 
@@ -856,7 +889,9 @@ func (s *Sender) sendRewindRun(record *spb.Record, run *spb.RunRecord) {
 
 	s.startState.Merge(update)
 	// Merge the resumed config into the run config
-	s.runConfig.MergeResumedConfig(s.startState.Config)
+	if !s.mergeResumedConfig(record) {
+		return
+	}
 
 	if record.GetControl().GetReqResp() || record.GetControl().GetMailboxSlot() != "" {
 		proto.Merge(run, s.startState.Proto())
@@ -882,17 +917,69 @@ func (s *Sender) sendResumeRun(record *spb.Record, run *spb.RunRecord) {
 		return
 	}
 
+	resumeMode := s.settings.GetResume()
+	if err := runbranch.ValidateResumeMode(resumeMode); err != nil {
+		if runbranch.IsMustLike(resumeMode) {
+			s.logger.Error("send: sendRun: invalid resume mode", "error", err)
+			if record.GetControl().GetReqResp() || record.GetControl().GetMailboxSlot() != "" {
+				s.respond(record,
+					&spb.RunUpdateResult{
+						Error: &spb.ErrorInfo{
+							Code: spb.ErrorInfo_USAGE,
+							Message: fmt.Sprintf(
+								"Invalid value for the `resume` argument: %v.", err),
+						},
+					},
+				)
+			}
+			return
+		}
+
+		// unknown modes are treated like 'allow' for compatibility
+		s.logger.CaptureWarn(
+			"send: sendRun: unknown resume mode, treating it as 'allow'",
+			"error", err,
+		)
+	}
+
 	update, err := runbranch.NewResumeBranch(
 		s.runWork.BeforeEndCtx(),
 		s.graphqlClient,
-		s.settings.GetResume(),
+		resumeMode,
+		s.logger,
+	).WaitForStart(
+		s.settings.GetCreatedRunStartTimeout(),
+		s.settings.GetCreatedRunPollInterval(),
+	).MergeSummaryWith(
+		s.summaryMergePolicy,
+	).DropMismatchedConfig(
+		s.settings.GetResumeDropMismatchedConfig(),
+	).SnapshotTo(
+		s.resumeSnapshotPath(),
 	).GetUpdates(s.startState, runbranch.RunPath{
 		Entity:  s.startState.Entity,
 		Project: s.startState.Project,
 		RunID:   s.startState.RunID,
 	})
 
-	if err != nil {
+	var resumeErr *runbranch.ResumeError
+	if errors.As(err, &resumeErr) && update != nil {
+		// the run is resumed, but parts of its state are lost
+		s.logger.CaptureWarn(
+			"send: sendRun: resumed run partially",
+			"skipped", resumeErr.SectionNames(),
+			"error", err,
+		)
+
+		// RunUpdateResult has no field for warnings, so the user sees this
+		// through the internal messages polled by the client instead.
+		s.terminalPrinter.Writef(
+			"Resumed run %s, but could not restore its %s."+
+				" These were not carried over from the previous run.",
+			s.startState.RunID,
+			strings.Join(resumeErr.SectionNames(), ", "),
+		)
+	} else if err != nil {
 		s.logger.CaptureError(
 			fmt.Errorf("send: sendRun: failed to update run state: %s", err),
 		)
@@ -910,21 +997,70 @@ func (s *Sender) sendResumeRun(record *spb.Record, run *spb.RunRecord) {
 		}
 	}
 	s.startState.Merge(update)
-
-	// On the first invocation of sendRun, we overwrite the tags if the user
-	// has set them in wandb.init(). Otherwise, we keep the tags from the
-	// original run.
-	if len(run.Tags) == 0 {
-		run.Tags = append(run.Tags, s.startState.Tags...)
+	if update != nil {
+		s.logger.Info(
+			"sender: sendRun: resuming run",
+			"starting_step", update.GetStartingStep(),
+			"runtime", update.GetRuntime(),
+		)
 	}
 
+	// On the first invocation of sendRun, combine the tags the user set in
+	// wandb.init() with the tags from the original run.
+	run.Tags = runbranch.MergeTags(
+		s.tagMergePolicy,
+		s.startState.Tags,
+		run.Tags,
+	)
+
 	// Merge the resumed config into the run config
-	s.runConfig.MergeResumedConfig(s.startState.Config)
+	if !s.mergeResumedConfig(record) {
+		return
+	}
 
 	proto.Merge(run, s.startState.Proto())
 	s.upsertRun(record, run)
 }
 
+// mergeResumedConfig merges the config of the resumed run into the run
+// config.
+//
+// Returns false if the configs conflict, after reporting the conflict
+// to the client.
+func (s *Sender) mergeResumedConfig(record *spb.Record) bool {
+	err := s.runConfig.MergeResumedConfig(
+		s.startState.Config,
+		s.configMergePolicy,
+	)
+	if err == nil {
+		return true
+	}
+
+	s.logger.CaptureError(
+		fmt.Errorf("send: sendRun: failed to merge resumed config: %v", err),
+	)
+	if record.GetControl().GetReqResp() || record.GetControl().GetMailboxSlot() != "" {
+		s.respond(record,
+			&spb.RunUpdateResult{
+				Error: &spb.ErrorInfo{
+					Code:    spb.ErrorInfo_USAGE,
+					Message: err.Error(),
+				},
+			},
+		)
+	}
+	return false
+}
+
+// resumeSnapshotPath returns where resuming a run writes its snapshot, or
+// an empty string if resume snapshots are disabled.
+func (s *Sender) resumeSnapshotPath() string {
+	if !s.settings.GetResumeSnapshot() {
+		return ""
+	}
+	return filepath.Join(s.settings.GetFilesDir(), runbranch.ResumeSnapshotFileName)
+}
+
 // sendRun sends a run record to the server and updates the run record
 func (s *Sender) sendRun(record *spb.Record, run *spb.RunRecord) {
 	// TODO: we use the same record type for the initial run upsert and the
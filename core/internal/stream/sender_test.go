@@ -39,13 +39,28 @@ const validLinkArtifactResponse = `{
 }`
 
 func makeSender(client graphql.Client, resultChan chan *spb.Result) *stream.Sender {
+	return makeSenderWithSettings(
+		client,
+		resultChan,
+		&spb.Settings{},
+		observability.NewPrinter(),
+	)
+}
+
+// makeSenderWithSettings is like makeSender but starts from the given
+// settings and writes terminal messages to the printer.
+func makeSenderWithSettings(
+	client graphql.Client,
+	resultChan chan *spb.Result,
+	settingsProto *spb.Settings,
+	printer *observability.Printer,
+) *stream.Sender {
 	runWork := runworktest.New()
 	logger := observability.NewNoOpLogger()
-	settings := wbsettings.From(&spb.Settings{
-		RunId:   &wrapperspb.StringValue{Value: "run1"},
-		Console: &wrapperspb.StringValue{Value: "off"},
-		ApiKey:  &wrapperspb.StringValue{Value: "test-api-key"},
-	})
+	settingsProto.RunId = &wrapperspb.StringValue{Value: "run1"}
+	settingsProto.Console = &wrapperspb.StringValue{Value: "off"}
+	settingsProto.ApiKey = &wrapperspb.StringValue{Value: "0123456789abcdef0123456789abcdef01234567"}
+	settings := wbsettings.From(settingsProto)
 	backend := stream.NewBackend(logger, settings)
 	fileStream := stream.NewFileStream(
 		backend,
@@ -82,6 +97,7 @@ func makeSender(client graphql.Client, resultChan chan *spb.Result) *stream.Send
 			OutChan:             resultChan,
 			Mailbox:             mailbox.New(),
 			GraphqlClient:       client,
+			TerminalPrinter:     printer,
 		},
 	)
 	return sender
@@ -129,6 +145,62 @@ func TestSendRun(t *testing.T) {
 		requests[0])
 }
 
+// Verify that a partially resumed run tells the user what was not restored
+func TestSendRun_PartialResumeWarnsUser(t *testing.T) {
+	mockGQL := gqlmock.NewMockClient()
+	mockGQL.StubMatchOnce(
+		gqlmock.WithOpName("RunResumeStatus"),
+		`{
+			"model": {
+				"bucket": {
+					"name": "run1",
+					"historyLineCount": 2,
+					"eventsLineCount": 0,
+					"logLineCount": 0,
+					"historyTail": "[\"{\\\"_step\\\":1}\"]",
+					"summaryMetrics": "{}",
+					"config": "not json",
+					"eventsTail": "[]",
+					"wandbConfig": "{\"t\": 1}"
+				}
+			}
+		}`,
+	)
+	mockGQL.StubMatchOnce(
+		gqlmock.WithOpName("UpsertBucket"),
+		validUpsertBucketResponse,
+	)
+	outChan := make(chan *spb.Result, 1)
+	printer := observability.NewPrinter()
+	sender := makeSenderWithSettings(
+		mockGQL,
+		outChan,
+		&spb.Settings{Resume: wrapperspb.String("allow")},
+		printer,
+	)
+
+	sender.SendRecord(&spb.Record{
+		RecordType: &spb.Record_Run{
+			Run: &spb.RunRecord{
+				RunId:   "run1",
+				Project: "testProject",
+				Entity:  "testEntity",
+			}},
+		Control: &spb.Control{
+			MailboxSlot: "junk",
+		},
+	})
+	result := <-outChan
+
+	assert.Nil(t, result.GetRunResult().GetError())
+	assert.Equal(t,
+		[]string{
+			"Resumed run run1, but could not restore its config." +
+				" These were not carried over from the previous run.",
+		},
+		printer.Read())
+}
+
 // Verify that arguments are properly passed through to graphql
 func TestSendLinkArtifact(t *testing.T) {
 	mockGQL := gqlmock.NewMockClient()
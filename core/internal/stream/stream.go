@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/Khan/genqlient/graphql"
+	"github.com/wandb/wandb/core/internal/api"
 	"github.com/wandb/wandb/core/internal/filestream"
 	"github.com/wandb/wandb/core/internal/filetransfer"
 	"github.com/wandb/wandb/core/internal/mailbox"
@@ -59,6 +60,9 @@ type Stream struct {
 	// sender is the sender for the stream
 	sender *Sender
 
+	// backend is the W&B backend, or nil if offline
+	backend *api.Backend
+
 	// dispatcher is the dispatcher for the stream
 	dispatcher *Dispatcher
 
@@ -171,6 +175,7 @@ func NewStream(
 	terminalPrinter := observability.NewPrinter()
 
 	backendOrNil := NewBackend(s.logger, opts.Settings)
+	s.backend = backendOrNil
 	fileTransferStats := filetransfer.NewFileTransferStats()
 	fileWatcher := watcher.New(watcher.Params{Logger: s.logger})
 	tbHandler := tensorboard.NewTBHandler(tensorboard.Params{
@@ -263,11 +268,15 @@ func NewStream(
 			RunfilesUploader:    runfilesUploaderOrNil,
 			TBHandler:           tbHandler,
 			Peeker:              peeker,
+			TerminalPrinter:     terminalPrinter,
 			RunSummary:          runsummary.New(),
 			GraphqlClient:       graphqlClientOrNil,
 			OutChan:             make(chan *spb.Result, BufferSize),
 			Mailbox:             mailbox,
 			OutputFileName:      outputFile,
+			ConfigMergePolicy:   NewConfigMergePolicy(s.logger, s.settings),
+			TagMergePolicy:      NewTagMergePolicy(s.logger, s.settings),
+			SummaryMergePolicy:  NewSummaryMergePolicy(s.logger, s.settings),
 		},
 	)
 
@@ -350,9 +359,26 @@ func (s *Stream) Close() {
 	s.logger.Info("stream: closing", "id", s.settings.GetRunID())
 	s.runWork.Close()
 	s.wg.Wait()
+	s.logTokenExchangeStats()
 	s.logger.Info("stream: closed", "id", s.settings.GetRunID())
 }
 
+// logTokenExchangeStats logs how many access tokens the stream requested
+// and how long that took, if it requested any.
+func (s *Stream) logTokenExchangeStats() {
+	stats := s.backend.TokenExchangeStats()
+	if stats == nil || stats.Successes()+stats.Failures() == 0 {
+		return
+	}
+
+	s.logger.Info(
+		"stream: token exchanges",
+		"successes", stats.Successes(),
+		"failures", stats.Failures(),
+		"total_latency", stats.TotalLatency(),
+	)
+}
+
 // FinishAndClose emits an exit record, waits for all run messages
 // to be fully processed, and prints the run footer to the terminal.
 func (s *Stream) FinishAndClose(exitCode int32) {
@@ -3,8 +3,11 @@ package stream
 // This file contains functions to construct the objects used by a Stream.
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"maps"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -17,6 +20,8 @@ import (
 	"github.com/wandb/wandb/core/internal/filestream"
 	"github.com/wandb/wandb/core/internal/filetransfer"
 	"github.com/wandb/wandb/core/internal/observability"
+	"github.com/wandb/wandb/core/internal/runbranch"
+	"github.com/wandb/wandb/core/internal/runconfig"
 	"github.com/wandb/wandb/core/internal/runfiles"
 	"github.com/wandb/wandb/core/internal/runwork"
 	"github.com/wandb/wandb/core/internal/settings"
@@ -41,7 +46,27 @@ func NewBackend(
 			fmt.Errorf("stream_init: failed to parse base URL: %v", err))
 	}
 
-	credentialProvider, err := api.NewCredentialProvider(settings)
+	tlsClientConfig := NewTLSClientConfig(logger, settings)
+	dialContext := NewDialContext(logger, settings)
+
+	tokenExchangeStats := &api.TokenExchangeStats{}
+
+	credentialProvider, err := api.NewCredentialProvider(
+		settings,
+		api.CredentialProviderOptions{
+			HTTPClient: &http.Client{
+				Timeout: api.DefaultTokenExchangeTimeout,
+				Transport: &http.Transport{
+					Proxy:             ProxyFn(settings.GetHTTPProxy(), settings.GetHTTPSProxy()),
+					TLSClientConfig:   tlsClientConfig,
+					DialContext:       dialContext,
+					ForceAttemptHTTP2: true,
+				},
+			},
+			Logger: logger.Logger,
+			Stats:  tokenExchangeStats,
+		},
+	)
 	if err != nil {
 		logger.CaptureFatalAndPanic(
 			fmt.Errorf("stream_init: failed to fetch credentials: %v", err))
@@ -51,9 +76,96 @@ func NewBackend(
 		BaseURL:            baseURL,
 		Logger:             logger.Logger,
 		CredentialProvider: credentialProvider,
+		TLSClientConfig:    tlsClientConfig,
+		DialContext:        dialContext,
+		TokenExchangeStats: tokenExchangeStats,
+	})
+}
+
+// NewDialContext returns the function that makes HTTP connections, as
+// configured by the settings.
+//
+// An invalid IP preference is logged and the default is used.
+func NewDialContext(
+	logger *observability.CoreLogger,
+	settings *settings.Settings,
+) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	preference, err := api.ParseIPPreference(settings.GetHTTPIPPreference())
+	if err != nil {
+		logger.Warn("stream_init: ignoring IP preference", "error", err)
+	}
+
+	return api.NewDialContext(api.DialOptions{
+		IPPreference:   preference,
+		ConnectTimeout: settings.GetHTTPConnectTimeout(),
 	})
 }
 
+// NewConfigMergePolicy returns how resuming a run merges its config, as
+// configured by the settings.
+//
+// An invalid policy is logged and the default is used.
+func NewConfigMergePolicy(
+	logger *observability.CoreLogger,
+	settings *settings.Settings,
+) runconfig.ConfigMergePolicy {
+	policy, err := runconfig.ParseConfigMergePolicy(
+		settings.GetResumeConfigMerge())
+	if err != nil {
+		logger.Warn("stream_init: ignoring config merge policy", "error", err)
+	}
+	return policy
+}
+
+// NewTagMergePolicy returns how resuming a run merges its tags, as
+// configured by the settings.
+//
+// An invalid policy is logged and the default is used.
+func NewTagMergePolicy(
+	logger *observability.CoreLogger,
+	settings *settings.Settings,
+) runbranch.TagMergePolicy {
+	policy, err := runbranch.ParseTagMergePolicy(settings.GetResumeTagMerge())
+	if err != nil {
+		logger.Warn("stream_init: ignoring tag merge policy", "error", err)
+	}
+	return policy
+}
+
+// NewSummaryMergePolicy returns how resuming a run merges its summary, as
+// configured by the settings.
+//
+// An invalid policy is logged and the default is used.
+func NewSummaryMergePolicy(
+	logger *observability.CoreLogger,
+	settings *settings.Settings,
+) runbranch.SummaryMergePolicy {
+	policy, err := runbranch.ParseSummaryMergePolicy(
+		settings.GetResumeSummaryMerge())
+	if err != nil {
+		logger.Warn("stream_init: ignoring summary merge policy", "error", err)
+	}
+	return policy
+}
+
+// NewTLSClientConfig returns the TLS configuration for HTTP clients, or nil
+// if the defaults should be used.
+func NewTLSClientConfig(
+	logger *observability.CoreLogger,
+	settings *settings.Settings,
+) *tls.Config {
+	config, err := api.NewTLSClientConfig(api.TLSClientOptions{
+		CertFile: settings.GetClientCertFile(),
+		KeyFile:  settings.GetClientKeyFile(),
+		CAFile:   settings.GetCACertFile(),
+	})
+	if err != nil {
+		logger.CaptureFatalAndPanic(
+			fmt.Errorf("stream_init: failed to configure TLS: %v", err))
+	}
+	return config
+}
+
 // ProxyFn returns a function that returns a proxy URL for a given hhtp.Request.
 //
 // The function first checks if there's a custom proxy setting for the request
@@ -210,11 +322,19 @@ func NewFileTransferManager(
 		fileTransferRetryClient,
 		logger,
 		fileTransferStats,
+		filetransfer.S3RestoreOptions{
+			Enabled:      settings.GetS3Restore(),
+			Days:         settings.GetS3RestoreDays(),
+			PollInterval: settings.GetS3RestorePollInterval(),
+			Timeout:      settings.GetS3RestoreTimeout(),
+		},
 	)
 
 	// Set the Proxy function on the HTTP client.
 	transport := &http.Transport{
-		Proxy: ProxyFn(settings.GetHTTPProxy(), settings.GetHTTPSProxy()),
+		Proxy:             ProxyFn(settings.GetHTTPProxy(), settings.GetHTTPSProxy()),
+		TLSClientConfig:   NewTLSClientConfig(logger, settings),
+		ForceAttemptHTTP2: true,
 	}
 	// Set the "Proxy-Authorization" header for the CONNECT requests
 	// to the proxy server if the header is present in the extra headers.
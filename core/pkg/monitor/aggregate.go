@@ -0,0 +1,34 @@
+package monitor
+
+// WeightedMerge combines two sets of averaged metrics, such as those of two
+// sampling assets or of one asset before and after a restart.
+//
+// Each set is weighted by the number of samples it averages. Metrics in
+// both sets are averaged by weight, and metrics in only one set are kept
+// as they are. Negative weights count as zero, and if both weights are
+// zero, shared metrics are averaged equally.
+//
+// Neither input is modified.
+func WeightedMerge(a, b map[string]float64, wa, wb int) map[string]float64 {
+	wa, wb = max(wa, 0), max(wb, 0)
+	if wa == 0 && wb == 0 {
+		wa, wb = 1, 1
+	}
+
+	merged := make(map[string]float64, len(a)+len(b))
+	for key, value := range a {
+		merged[key] = value
+	}
+
+	for key, bValue := range b {
+		aValue, ok := merged[key]
+		if !ok {
+			merged[key] = bValue
+			continue
+		}
+
+		merged[key] = (aValue*float64(wa) + bValue*float64(wb)) / float64(wa+wb)
+	}
+
+	return merged
+}
@@ -0,0 +1,50 @@
+package monitor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wandb/wandb/core/pkg/monitor"
+)
+
+func TestWeightedMerge_Overlapping(t *testing.T) {
+	a := map[string]float64{"gpu.0.gpu": 10, "gpu.0.temp": 60}
+	b := map[string]float64{"gpu.0.gpu": 50, "gpu.0.temp": 80}
+
+	merged := monitor.WeightedMerge(a, b, 3, 1)
+
+	assert.Equal(t, map[string]float64{"gpu.0.gpu": 20, "gpu.0.temp": 65}, merged)
+	assert.Equal(t, map[string]float64{"gpu.0.gpu": 10, "gpu.0.temp": 60}, a)
+}
+
+func TestWeightedMerge_Disjoint(t *testing.T) {
+	a := map[string]float64{"gpu.0.gpu": 10}
+	b := map[string]float64{"gpu.1.gpu": 50}
+
+	merged := monitor.WeightedMerge(a, b, 1, 9)
+
+	assert.Equal(t, map[string]float64{"gpu.0.gpu": 10, "gpu.1.gpu": 50}, merged)
+}
+
+func TestWeightedMerge_PartialOverlap(t *testing.T) {
+	a := map[string]float64{"gpu.0.gpu": 0, "gpu.0.temp": 60}
+	b := map[string]float64{"gpu.0.gpu": 100, "gpu.1.gpu": 40}
+
+	merged := monitor.WeightedMerge(a, b, 1, 4)
+
+	assert.Equal(t,
+		map[string]float64{"gpu.0.gpu": 80, "gpu.0.temp": 60, "gpu.1.gpu": 40},
+		merged)
+}
+
+func TestWeightedMerge_ZeroWeights(t *testing.T) {
+	a := map[string]float64{"gpu.0.gpu": 10}
+	b := map[string]float64{"gpu.0.gpu": 30}
+
+	assert.Equal(t,
+		map[string]float64{"gpu.0.gpu": 20},
+		monitor.WeightedMerge(a, b, 0, 0))
+	assert.Equal(t,
+		map[string]float64{"gpu.0.gpu": 30},
+		monitor.WeightedMerge(a, b, 0, 5))
+}
@@ -0,0 +1,237 @@
+package monitor
+
+import (
+	"slices"
+
+	"github.com/wandb/wandb/core/internal/observability"
+	"github.com/wandb/wandb/core/internal/settings"
+	spb "github.com/wandb/wandb/core/pkg/service_go_proto"
+)
+
+// assetFactory creates an asset, returning nil if it can't be monitored.
+type assetFactory func(
+	logger *observability.CoreLogger,
+	settings *spb.Settings,
+) Asset
+
+// assetRegistry lists the built-in assets in the order they're created.
+//
+// Each entry is keyed by the Name() of the assets it creates. Several
+// assets may share a name, like the Nvidia and AMD GPU assets, in which
+// case disabling the name disables all of them.
+var assetRegistry = []struct {
+	name    string
+	factory assetFactory
+}{
+	{"cpu", func(_ *observability.CoreLogger, s *spb.Settings) Asset {
+		return nilIfNil(NewCPU(s.XStatsPid.GetValue()))
+	}},
+	{"disk", func(_ *observability.CoreLogger, s *spb.Settings) Asset {
+		return nilIfNil(NewDisk(s.XStatsDiskPaths.GetValue()))
+	}},
+	{"memory", func(_ *observability.CoreLogger, s *spb.Settings) Asset {
+		return nilIfNil(NewMemory(s.XStatsPid.GetValue()))
+	}},
+	{"network", func(_ *observability.CoreLogger, _ *spb.Settings) Asset {
+		return nilIfNil(NewNetwork())
+	}},
+	{"gpu", func(l *observability.CoreLogger, s *spb.Settings) Asset {
+		gpuSettings := settings.From(s)
+		gpu := NewGPU(
+			l,
+			s.XStatsPid.GetValue(),
+			gpuSettings.GetGPUStatsPath(),
+		)
+		if gpu != nil {
+			gpu.MetricFilter = newGPUMetricFilter(l, gpuSettings)
+			gpu.MetricNamer = newGPUMetricNamer(l, gpuSettings)
+			powerLimitWindow := DefaultPowerLimitWindow
+			if window := gpuSettings.GetGPUMetricWindow(); window > 0 {
+				gpu.Window = NewMetricWindow(window)
+				gpu.Window.SetAggregations(newGPUMetricAggregations(l, gpuSettings))
+				gpu.Window.SetStatistics(newGPUMetricStatistics(l, gpuSettings))
+				powerLimitWindow = window
+			}
+			gpu.PowerLimit = NewPowerLimitTracker(powerLimitWindow)
+			gpu.PowerHistogram = newGPUPowerHistogram(l, gpuSettings)
+			if grace, ok := gpuSettings.GetGPUIdleGracePeriod(); ok {
+				gpu.IdleGate = NewGPUIdleGate(
+					grace,
+					gpuSettings.GetGPUIdleSampleInterval(),
+				)
+			}
+		}
+		return nilIfNil(gpu)
+	}},
+	{"gpu", func(l *observability.CoreLogger, _ *spb.Settings) Asset {
+		return nilIfNil(NewGPUAMD(l))
+	}},
+	{"tpu", func(_ *observability.CoreLogger, _ *spb.Settings) Asset {
+		return nilIfNil(NewTPU())
+	}},
+	{"slurm", func(_ *observability.CoreLogger, _ *spb.Settings) Asset {
+		return nilIfNil(NewSLURM())
+	}},
+	{"trainium", func(l *observability.CoreLogger, s *spb.Settings) Asset {
+		return nilIfNil(NewTrainium(
+			l,
+			s.XStatsPid.GetValue(),
+			s.XStatsSamplingInterval.GetValue(),
+			s.XStatsNeuronMonitorConfigPath.GetValue(),
+		))
+	}},
+}
+
+// newGPUMetricFilter returns the filter for GPU metrics configured in the
+// settings, or nil if all metrics are reported.
+//
+// Invalid patterns are logged and ignored, so that all metrics are
+// reported.
+func newGPUMetricFilter(
+	logger *observability.CoreLogger,
+	s *settings.Settings,
+) *MetricFilter {
+	allow, deny := s.GetGPUMetricAllow(), s.GetGPUMetricDeny()
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil
+	}
+
+	filter, err := NewMetricFilter(allow, deny)
+	if err != nil {
+		logger.Warn("monitor: gpu: ignoring metric filter", "error", err)
+		return nil
+	}
+	return filter
+}
+
+// newGPUMetricNamer returns the namer for GPU metrics configured in the
+// settings, or nil if the default keys are used.
+//
+// Invalid renames are logged and ignored.
+func newGPUMetricNamer(
+	logger *observability.CoreLogger,
+	s *settings.Settings,
+) *MetricNamer {
+	prefix := s.GetGPUMetricPrefix()
+	renames, err := ParseMetricRenames(s.GetGPUMetricRenames())
+	if err != nil {
+		logger.Warn("monitor: gpu: ignoring metric renames", "error", err)
+		renames = nil
+	}
+
+	if prefix == "" && len(renames) == 0 {
+		return nil
+	}
+	return NewMetricNamer(prefix, renames)
+}
+
+// newGPUMetricAggregations returns how GPU metrics are aggregated over
+// the metric window as configured in the settings.
+//
+// Invalid rules are logged and ignored, so that all metrics are averaged.
+func newGPUMetricAggregations(
+	logger *observability.CoreLogger,
+	s *settings.Settings,
+) *MetricAggregations {
+	aggregations, err := ParseMetricAggregations(s.GetGPUMetricAggregations())
+	if err != nil {
+		logger.Warn("monitor: gpu: ignoring metric aggregations", "error", err)
+		return nil
+	}
+	return aggregations
+}
+
+// newGPUMetricStatistics returns the statistics of GPU metrics over the
+// metric window that are reported as configured in the settings.
+//
+// Invalid statistics are logged and ignored, so that none are reported.
+func newGPUMetricStatistics(
+	logger *observability.CoreLogger,
+	s *settings.Settings,
+) []MetricStatistic {
+	statistics, err := ParseMetricStatistics(s.GetGPUMetricStatistics())
+	if err != nil {
+		logger.Warn("monitor: gpu: ignoring metric statistics", "error", err)
+		return nil
+	}
+	return statistics
+}
+
+// newGPUPowerHistogram returns the histogram of GPU power draw if it's
+// enabled in the settings.
+//
+// Invalid boundaries are logged, and no histogram is reported.
+func newGPUPowerHistogram(
+	logger *observability.CoreLogger,
+	s *settings.Settings,
+) *PowerHistogram {
+	if !s.GetGPUPowerHistogram() {
+		return nil
+	}
+
+	boundaries := DefaultPowerHistogramBoundaries
+	if spec := s.GetGPUPowerHistogramBoundaries(); spec != "" {
+		var err error
+		boundaries, err = ParsePowerHistogramBoundaries(spec)
+		if err != nil {
+			logger.Warn("monitor: gpu: ignoring power histogram", "error", err)
+			return nil
+		}
+	}
+
+	histogram, err := NewPowerHistogram(boundaries)
+	if err != nil {
+		logger.Warn("monitor: gpu: ignoring power histogram", "error", err)
+		return nil
+	}
+	return histogram
+}
+
+// nilIfNil converts a nil asset pointer into a nil Asset interface.
+func nilIfNil[T interface {
+	Asset
+	comparable
+}](asset T) Asset {
+	var zero T
+	if asset == zero {
+		return nil
+	}
+	return asset
+}
+
+// NewAssetSet creates the assets to monitor, skipping those whose name is
+// in disabled.
+//
+// OpenMetrics endpoints are named by their key in the settings and can be
+// disabled by that name.
+func NewAssetSet(
+	logger *observability.CoreLogger,
+	settings *spb.Settings,
+	disabled []string,
+) []Asset {
+	var assets []Asset
+
+	for _, entry := range assetRegistry {
+		if slices.Contains(disabled, entry.name) {
+			continue
+		}
+		if asset := entry.factory(logger, settings); asset != nil {
+			assets = append(assets, asset)
+		}
+	}
+
+	// OpenMetrics endpoints to monitor.
+	if endpoints := settings.XStatsOpenMetricsEndpoints.GetValue(); endpoints != nil {
+		for name, url := range endpoints {
+			if slices.Contains(disabled, name) {
+				continue
+			}
+			filters := settings.XStatsOpenMetricsFilters
+			if om := NewOpenMetrics(logger, name, url, filters, nil); om != nil {
+				assets = append(assets, om)
+			}
+		}
+	}
+
+	return assets
+}
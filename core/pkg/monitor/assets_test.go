@@ -0,0 +1,117 @@
+package monitor_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wandb/wandb/core/internal/observability"
+	"github.com/wandb/wandb/core/pkg/monitor"
+	spb "github.com/wandb/wandb/core/pkg/service_go_proto"
+)
+
+func assetNames(assets []monitor.Asset) []string {
+	names := make([]string, 0, len(assets))
+	for _, asset := range assets {
+		names = append(names, asset.Name())
+	}
+	return names
+}
+
+func TestNewAssetSet(t *testing.T) {
+	assets := monitor.NewAssetSet(
+		observability.NewNoOpLogger(),
+		&spb.Settings{},
+		nil,
+	)
+
+	names := assetNames(assets)
+	assert.Contains(t, names, "cpu")
+	assert.Contains(t, names, "disk")
+	assert.Contains(t, names, "memory")
+	assert.Contains(t, names, "network")
+}
+
+func TestNewAssetSet_Disabled(t *testing.T) {
+	assets := monitor.NewAssetSet(
+		observability.NewNoOpLogger(),
+		&spb.Settings{},
+		[]string{"gpu", "network"},
+	)
+
+	names := assetNames(assets)
+	assert.NotContains(t, names, "gpu")
+	assert.NotContains(t, names, "network")
+	assert.Contains(t, names, "cpu")
+	assert.Contains(t, names, "disk")
+	assert.Contains(t, names, "memory")
+}
+
+// newFakeGPUAsset creates the Nvidia GPU asset from the registry, backed
+// by the test binary acting as gpu_stats.
+func newFakeGPUAsset(t *testing.T) *monitor.GPU {
+	t.Helper()
+	executable, err := os.Executable()
+	require.NoError(t, err)
+	t.Setenv("WANDB_TEST_FAKE_GPU_STATS", "0")
+	t.Setenv("WANDB_GPU_STATS_PATH", executable)
+
+	assets := monitor.NewAssetSet(
+		observability.NewNoOpLogger(),
+		&spb.Settings{},
+		[]string{"cpu", "disk", "memory", "network", "tpu", "slurm", "trainium"},
+	)
+
+	for _, asset := range assets {
+		if gpu, ok := asset.(*monitor.GPU); ok {
+			t.Cleanup(gpu.Close)
+			return gpu
+		}
+	}
+	require.FailNow(t, "no Nvidia GPU asset")
+	return nil
+}
+
+func TestNewAssetSet_GPUPowerHistogram(t *testing.T) {
+	t.Setenv("WANDB_GPU_POWER_HISTOGRAM", "true")
+	t.Setenv("WANDB_GPU_POWER_HISTOGRAM_BOUNDARIES", "0,100,200")
+
+	gpu := newFakeGPUAsset(t)
+
+	require.NotNil(t, gpu.PowerHistogram)
+	gpu.PowerHistogram.Add(0, 150)
+	assert.Equal(t,
+		map[string]any{
+			"gpu.0.powerWatts.histogram.0-100":   0,
+			"gpu.0.powerWatts.histogram.100-200": 1,
+		},
+		gpu.PowerHistogram.Metrics(),
+	)
+}
+
+func TestNewAssetSet_GPUPowerHistogramDisabledByDefault(t *testing.T) {
+	gpu := newFakeGPUAsset(t)
+
+	assert.Nil(t, gpu.PowerHistogram)
+}
+
+func TestNewAssetSet_GPUMetricFilter(t *testing.T) {
+	t.Setenv("WANDB_GPU_METRIC_ALLOW", "gpu.*")
+	t.Setenv("WANDB_GPU_METRIC_DENY", "gpu.*.temp")
+
+	gpu := newFakeGPUAsset(t)
+
+	require.NotNil(t, gpu.MetricFilter)
+	metrics, err := gpu.Sample()
+	require.NoError(t, err)
+	assert.NotContains(t, metrics, "gpu.0.temp")
+}
+
+func TestNewAssetSet_GPUMetricFilterInvalid(t *testing.T) {
+	t.Setenv("WANDB_GPU_METRIC_DENY", "gpu.[")
+
+	gpu := newFakeGPUAsset(t)
+
+	assert.Nil(t, gpu.MetricFilter)
+}
@@ -3,9 +3,11 @@ package monitor
 import (
 	"errors"
 	"fmt"
+	"runtime"
 	"strings"
 
 	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/load"
 	"github.com/shirou/gopsutil/v4/process"
 
 	spb "github.com/wandb/wandb/core/pkg/service_go_proto"
@@ -64,6 +66,24 @@ func (c *CPU) Sample() (map[string]any, error) {
 		}
 	}
 
+	// system load averages over the last 1, 5 and 15 minutes
+	//
+	// Windows has no load average; gopsutil emulates it with a background
+	// goroutine, which we don't want to start.
+	if runtime.GOOS != "windows" {
+		loadAvg, err := load.Avg()
+		if err != nil {
+			// do not log "not implemented yet" errors
+			if !strings.Contains(err.Error(), "not implemented yet") {
+				errs = append(errs, err)
+			}
+		} else {
+			metrics["cpu.load1"] = loadAvg.Load1
+			metrics["cpu.load5"] = loadAvg.Load5
+			metrics["cpu.load15"] = loadAvg.Load15
+		}
+	}
+
 	return metrics, errors.Join(errs...)
 }
 func (c *CPU) IsAvailable() bool { return true }
@@ -0,0 +1,26 @@
+//go:build linux
+
+package monitor_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wandb/wandb/core/pkg/monitor"
+)
+
+func TestCPUSample(t *testing.T) {
+	cpu := monitor.NewCPU(int32(os.Getpid()))
+
+	metrics, err := cpu.Sample()
+
+	assert.NoError(t, err)
+	assert.Contains(t, metrics, "cpu")
+	assert.Contains(t, metrics, "proc.cpu.threads")
+	assert.Contains(t, metrics, "cpu.0.cpu_percent")
+	for _, key := range []string{"cpu.load1", "cpu.load5", "cpu.load15"} {
+		assert.Contains(t, metrics, key)
+		assert.GreaterOrEqual(t, metrics[key].(float64), 0.0)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/shirou/gopsutil/v4/disk"
 
@@ -15,6 +16,11 @@ type Disk struct {
 	diskPaths []string
 	readInit  int
 	writeInit int
+
+	// I/O counters per device from the previous sample, used to compute
+	// read and write rates.
+	lastIO     map[string]disk.IOCountersStat
+	lastIOTime time.Time
 }
 
 func NewDisk(diskPaths []string) *Disk {
@@ -28,6 +34,7 @@ func NewDisk(diskPaths []string) *Disk {
 	if err == nil {
 		d.readInit = int(ioCounters["disk0"].ReadBytes)
 		d.writeInit = int(ioCounters["disk0"].WriteBytes)
+		d.IORates(ioCounters, time.Now())
 	}
 
 	return d
@@ -63,11 +70,49 @@ func (d *Disk) Sample() (map[string]any, error) {
 		// MB read/written
 		metrics["disk.in"] = float64(int(ioCounters["disk0"].ReadBytes)-d.readInit) / 1024 / 1024
 		metrics["disk.out"] = float64(int(ioCounters["disk0"].WriteBytes)-d.writeInit) / 1024 / 1024
+
+		for k, v := range d.IORates(ioCounters, time.Now()) {
+			metrics[k] = v
+		}
 	}
 
 	return metrics, errors.Join(errs...)
 }
 
+// IORates returns the read and write rates of each device since the
+// previous call, and records the counters for the next one.
+//
+// Devices that appeared since the previous call have no rate until the
+// next call. Devices whose counters went backwards, for example because
+// they were removed and re-attached, are skipped for this call.
+func (d *Disk) IORates(
+	ioCounters map[string]disk.IOCountersStat,
+	now time.Time,
+) map[string]any {
+	metrics := make(map[string]any)
+
+	elapsed := now.Sub(d.lastIOTime).Seconds()
+	if d.lastIO != nil && elapsed > 0 {
+		for device, curr := range ioCounters {
+			prev, ok := d.lastIO[device]
+			if !ok ||
+				curr.ReadBytes < prev.ReadBytes ||
+				curr.WriteBytes < prev.WriteBytes {
+				continue
+			}
+
+			metrics[fmt.Sprintf("disk.%s.readBytesPerSec", device)] =
+				float64(curr.ReadBytes-prev.ReadBytes) / elapsed
+			metrics[fmt.Sprintf("disk.%s.writeBytesPerSec", device)] =
+				float64(curr.WriteBytes-prev.WriteBytes) / elapsed
+		}
+	}
+
+	d.lastIO = ioCounters
+	d.lastIOTime = now
+	return metrics
+}
+
 func (d *Disk) IsAvailable() bool { return true }
 
 func (d *Disk) Probe() *spb.MetadataRequest {
@@ -0,0 +1,57 @@
+package monitor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/stretchr/testify/assert"
+	"github.com/wandb/wandb/core/pkg/monitor"
+)
+
+func TestDiskIORates(t *testing.T) {
+	d := &monitor.Disk{}
+	start := time.Unix(1000, 0)
+
+	first := d.IORates(
+		map[string]disk.IOCountersStat{
+			"sda": {ReadBytes: 1000, WriteBytes: 2000},
+			"sdb": {ReadBytes: 5000, WriteBytes: 5000},
+		},
+		start,
+	)
+	assert.Empty(t, first)
+
+	second := d.IORates(
+		map[string]disk.IOCountersStat{
+			// 2 seconds later, sda read 4000 and wrote 1000 bytes
+			"sda": {ReadBytes: 5000, WriteBytes: 3000},
+			// sdb was detached, and sdc is new
+			"sdc": {ReadBytes: 100, WriteBytes: 100},
+		},
+		start.Add(2*time.Second),
+	)
+	assert.Equal(t,
+		map[string]any{
+			"disk.sda.readBytesPerSec":  2000.0,
+			"disk.sda.writeBytesPerSec": 500.0,
+		},
+		second,
+	)
+
+	third := d.IORates(
+		map[string]disk.IOCountersStat{
+			// sda was re-attached and its counters reset
+			"sda": {ReadBytes: 10, WriteBytes: 10},
+			"sdc": {ReadBytes: 200, WriteBytes: 100},
+		},
+		start.Add(3*time.Second),
+	)
+	assert.Equal(t,
+		map[string]any{
+			"disk.sdc.readBytesPerSec":  100.0,
+			"disk.sdc.writeBytesPerSec": 0.0,
+		},
+		third,
+	)
+}
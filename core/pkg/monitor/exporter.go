@@ -0,0 +1,203 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// openMetricsContentType is the content type of the OpenMetrics text format.
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// invalidMetricNameChars matches characters not allowed in OpenMetrics names.
+var invalidMetricNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// PrometheusExporter serves the latest system metrics in the OpenMetrics
+// text format, so that they can be scraped by Prometheus.
+//
+// Metric keys are translated by treating numeric segments as label values,
+// labelled by the first segment of the key: "gpu.0.memoryAllocated"
+// becomes `gpu_memory_allocated{gpu="0"}`.
+type PrometheusExporter struct {
+	mu sync.Mutex
+
+	// metrics is the latest value of each metric, by key.
+	metrics map[string]float64
+
+	// server serves the metrics, if Start was called.
+	server *http.Server
+}
+
+func NewPrometheusExporter() *PrometheusExporter {
+	return &PrometheusExporter{metrics: make(map[string]float64)}
+}
+
+// Update records the latest values of the given metrics.
+//
+// Non-numeric values are ignored.
+func (e *PrometheusExporter) Update(metrics map[string]any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for key, value := range metrics {
+		switch v := value.(type) {
+		case float64:
+			e.metrics[key] = v
+		case float32:
+			e.metrics[key] = float64(v)
+		case int:
+			e.metrics[key] = float64(v)
+		case int64:
+			e.metrics[key] = float64(v)
+		case uint64:
+			e.metrics[key] = float64(v)
+		}
+	}
+}
+
+// Start serves the metrics at "/metrics" on the given address.
+func (e *PrometheusExporter) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("monitor: exporter: failed to listen on %s: %v", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+
+	e.mu.Lock()
+	e.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	server := e.server
+	e.mu.Unlock()
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	return nil
+}
+
+// Close stops serving the metrics.
+func (e *PrometheusExporter) Close() {
+	e.mu.Lock()
+	server := e.server
+	e.server = nil
+	e.mu.Unlock()
+
+	if server == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		_ = server.Close()
+	}
+}
+
+// ServeHTTP writes the latest metrics in the OpenMetrics text format.
+func (e *PrometheusExporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", openMetricsContentType)
+	_, _ = w.Write([]byte(e.Format()))
+}
+
+// Format returns the latest metrics in the OpenMetrics text format.
+func (e *PrometheusExporter) Format() string {
+	e.mu.Lock()
+	samples := make(map[string][]string)
+	for key, value := range e.metrics {
+		name, labels := OpenMetricsName(key)
+		samples[name] = append(samples[name],
+			name+labels+" "+strconv.FormatFloat(value, 'g', -1, 64))
+	}
+	e.mu.Unlock()
+
+	names := make([]string, 0, len(samples))
+	for name := range samples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&sb, "# TYPE %s gauge\n", name)
+		lines := samples[name]
+		sort.Strings(lines)
+		for _, line := range lines {
+			sb.WriteString(line)
+			sb.WriteByte('\n')
+		}
+	}
+	sb.WriteString("# EOF\n")
+	return sb.String()
+}
+
+// OpenMetricsName translates a metric key into an OpenMetrics metric name
+// and label set.
+//
+// Numeric segments of the key become the value of a label named after the
+// first segment, and the remaining segments are joined in snake case:
+// "gpu.process.0.temp" becomes "gpu_process_temp" with labels `{gpu="0"}`.
+func OpenMetricsName(key string) (name string, labels string) {
+	segments := strings.Split(key, ".")
+
+	var parts []string
+	var indices []string
+	for _, segment := range segments {
+		if _, err := strconv.Atoi(segment); err == nil && len(parts) > 0 {
+			indices = append(indices, segment)
+			continue
+		}
+		if segment = toSnakeCase(segment); segment != "" {
+			parts = append(parts, segment)
+		}
+	}
+
+	name = invalidMetricNameChars.ReplaceAllString(strings.Join(parts, "_"), "_")
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "_" + name
+	}
+
+	if len(indices) > 0 {
+		labelName := invalidMetricNameChars.ReplaceAllString(parts[0], "_")
+		labelValues := make([]string, len(indices))
+		for i, index := range indices {
+			label := labelName
+			if i > 0 {
+				label = fmt.Sprintf("%s%d", labelName, i)
+			}
+			labelValues[i] = fmt.Sprintf("%s=%q", label, index)
+		}
+		labels = "{" + strings.Join(labelValues, ",") + "}"
+	}
+
+	return name, labels
+}
+
+// toSnakeCase converts a camelCase string to snake_case, keeping
+// acronyms together: "rssMB" becomes "rss_mb".
+func toSnakeCase(s string) string {
+	runes := []rune(s)
+	var sb strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && !unicode.IsUpper(runes[i-1]) && runes[i-1] != '_'
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) &&
+				unicode.IsUpper(runes[i-1])
+			if prevLower || nextLower {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,83 @@
+package monitor_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wandb/wandb/core/pkg/monitor"
+)
+
+func TestOpenMetricsName(t *testing.T) {
+	testCases := []struct {
+		key    string
+		name   string
+		labels string
+	}{
+		{"gpu.0.memoryAllocated", "gpu_memory_allocated", `{gpu="0"}`},
+		{"gpu.process.1.temp", "gpu_process_temp", `{gpu="1"}`},
+		{"cpu.3.cpu_percent", "cpu_cpu_percent", `{cpu="3"}`},
+		{"memory_percent", "memory_percent", ""},
+		{"proc.memory.rssMB", "proc_memory_rss_mb", ""},
+		{"network.eth0.sentBytesPerSec", "network_eth0_sent_bytes_per_sec", ""},
+		{"gpu.0.powerWatts", "gpu_power_watts", `{gpu="0"}`},
+		{"disk./.usagePercent", "disk___usage_percent", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			name, labels := monitor.OpenMetricsName(tc.key)
+			assert.Equal(t, tc.name, name)
+			assert.Equal(t, tc.labels, labels)
+		})
+	}
+}
+
+func TestPrometheusExporter_Scrape(t *testing.T) {
+	exporter := monitor.NewPrometheusExporter()
+	exporter.Update(map[string]any{
+		"gpu.0.memoryAllocated": 12.5,
+		"gpu.1.memoryAllocated": 50.0,
+		"memory_percent":        42.0,
+		"gpu.0.name":            "not a number",
+	})
+	server := httptest.NewServer(exporter)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Contains(t, resp.Header.Get("Content-Type"), "application/openmetrics-text")
+	assert.Equal(t,
+		"# TYPE gpu_memory_allocated gauge\n"+
+			"gpu_memory_allocated{gpu=\"0\"} 12.5\n"+
+			"gpu_memory_allocated{gpu=\"1\"} 50\n"+
+			"# TYPE memory_percent gauge\n"+
+			"memory_percent 42\n"+
+			"# EOF\n",
+		string(body),
+	)
+}
+
+func TestPrometheusExporter_UpdateOverwrites(t *testing.T) {
+	exporter := monitor.NewPrometheusExporter()
+
+	exporter.Update(map[string]any{"memory_percent": 10.0})
+	exporter.Update(map[string]any{"memory_percent": 20.0})
+
+	assert.Contains(t, exporter.Format(), "memory_percent 20\n")
+	assert.NotContains(t, exporter.Format(), "memory_percent 10\n")
+}
+
+func TestPrometheusExporter_StartClose(t *testing.T) {
+	exporter := monitor.NewPrometheusExporter()
+	require.NoError(t, exporter.Start("127.0.0.1:0"))
+	exporter.Close()
+	exporter.Close()
+}
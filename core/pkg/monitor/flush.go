@@ -0,0 +1,51 @@
+package monitor
+
+import "sync"
+
+// pendingSamples holds samples that were taken but may not have been
+// handed to the run, so that they can be flushed on shutdown.
+type pendingSamples struct {
+	mu sync.Mutex
+
+	// samples are each numeric metric's samples, oldest first.
+	samples map[string][]float64
+}
+
+// Add records the numeric metrics of a sample.
+//
+// Other metrics are ignored.
+func (p *pendingSamples) Add(metrics map[string]any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, value := range metrics {
+		number, ok := value.(float64)
+		if !ok {
+			continue
+		}
+		if p.samples == nil {
+			p.samples = make(map[string][]float64)
+		}
+		p.samples[key] = append(p.samples[key], number)
+	}
+}
+
+// AggregateAndClear returns the average of each metric's samples and
+// forgets them.
+//
+// Returns nil if there are no samples.
+func (p *pendingSamples) AggregateAndClear() map[string]float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.samples) == 0 {
+		return nil
+	}
+
+	aggregates := make(map[string]float64, len(p.samples))
+	for key, samples := range p.samples {
+		aggregates[key] = AggregationMean.apply(samples)
+	}
+	p.samples = nil
+	return aggregates
+}
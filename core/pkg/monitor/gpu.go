@@ -3,14 +3,22 @@ package monitor
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/wandb/wandb/core/internal/observability"
 	spb "github.com/wandb/wandb/core/pkg/service_go_proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -21,44 +29,288 @@ import (
 //
 // It collects GPU metrics from the gpu_stats binary via gRPC.
 type GPU struct {
+	logger *observability.CoreLogger
 	// pid of the process to collect process-specific metrics for.
 	pid int32
+	// Path to the gpu_stats binary and its command-line arguments.
+	cmdPath string
+	cmdArgs []string
+	// GPUs visible to the process, as set by CUDA_VISIBLE_DEVICES.
+	visible VisibleGPUs
+
+	// procMu protects the gpu_stats process and the connection to it,
+	// which are replaced when gpu_stats is restarted.
+	procMu sync.Mutex
 	// gpu_stats process.
 	cmd *exec.Cmd
+	// exited is closed when the gpu_stats process exits.
+	exited chan struct{}
+	// startTime is when gpu_stats was last started or attempted to be.
+	startTime time.Time
+	// failedStarts counts the restarts of gpu_stats that failed in a row.
+	failedStarts int
+	// closed is set by Close, after which gpu_stats isn't restarted.
+	closed bool
 	// gRPC client connection and client for GPU metrics.
 	conn   *grpc.ClientConn
 	client spb.SystemMonitorClient
+
+	// RestartDelay is the minimum time between starts of gpu_stats.
+	//
+	// If gpu_stats exits, for example because it crashed, it's restarted
+	// before the next sample taken at least this long after it was last
+	// started. This keeps a binary that keeps crashing from slowing down
+	// every sample. Samples taken while gpu_stats is down are empty.
+	//
+	// The delay doubles after each restart that fails, up to
+	// maxGPUStatsRestartDelay.
+	RestartDelay time.Duration
+
+	// PowerHistogram, if set, accumulates the power draw of each GPU and
+	// its bucket counts are reported along with the other metrics.
+	PowerHistogram *PowerHistogram
+
+	// Window, if set, reports each metric's average over a recent window of
+	// time rather than its latest sample.
+	Window *MetricWindow
+
+	// PowerLimit, if set, reports how often each GPU's power draw is at its
+	// enforced power limit.
+	PowerLimit *PowerLimitTracker
+
+	// MetricFilter, if set, selects which metrics are reported.
+	//
+	// By default, all metrics are reported.
+	MetricFilter *MetricFilter
+
+	// MetricNamer, if set, maps the keys of the reported metrics.
+	//
+	// It is applied after MetricFilter, whose patterns match the default
+	// keys.
+	MetricNamer *MetricNamer
+
+	// IdleGate, if set, slows down sampling while the monitored process
+	// isn't using any GPU.
+	//
+	// Samples skipped by the gate are empty.
+	IdleGate *GPUIdleGate
+
+	// lastProbeMu protects lastProbe.
+	lastProbeMu sync.Mutex
+	// lastProbe is the metadata from the latest Probe, for Reprobe.
+	lastProbe *spb.MetadataRequest
 }
 
-func NewGPU(pid int32) *GPU {
-	g := &GPU{pid: pid}
+var _ MetricKeysAsset = &GPU{}
+var _ MetricUnitsAsset = &GPU{}
 
-	// A portfile is used to communicate the port number of the gRPC service
-	// started by the gpu_stats binary.
-	pf := NewPortfile()
-	if pf == nil {
+// nvidiaMetricNames are the names of the metrics gpu_stats reports for
+// each Nvidia GPU, as in "gpu.0.temp".
+var nvidiaMetricNames = []string{
+	"gpu",
+	"memory",
+	"memoryAllocated",
+	"memoryAllocatedBytes",
+	"temp",
+	"powerWatts",
+	"enforcedPowerLimitWatts",
+	"powerPercent",
+	"fanSpeed",
+	"encoderUtilization",
+	"graphicsClock",
+	"memoryClock",
+	"smClock",
+	"pcieLinkGen",
+	"correctedMemoryErrors",
+	"uncorrectedMemoryErrors",
+}
+
+// nvidiaProcessMetricNames are the names of the metrics gpu_stats reports
+// for each Nvidia GPU used by the monitored process, as in
+// "gpu.process.0.temp".
+var nvidiaProcessMetricNames = []string{
+	"gpu",
+	"memory",
+	"memoryAllocated",
+	"memoryAllocatedBytes",
+	"temp",
+	"powerWatts",
+	"enforcedPowerLimitWatts",
+	"powerPercent",
+}
+
+const (
+	// DefaultGPUStatsRestartDelay is the default minimum time between
+	// starts of the gpu_stats binary.
+	DefaultGPUStatsRestartDelay = 30 * time.Second
+
+	// maxGPUStatsRestartDelay caps the time between failed restarts of
+	// the gpu_stats binary.
+	maxGPUStatsRestartDelay = 10 * time.Minute
+
+	// gpuStatsExitWait is how long a failed sample waits to see whether
+	// gpu_stats exited, in which case the sample is skipped.
+	gpuStatsExitWait = time.Second
+)
+
+// VisibleGPUs is the set of physical GPU indices a process can use.
+//
+// A nil set means that all GPUs are visible.
+type VisibleGPUs map[int]struct{}
+
+// gpuMetricIndex matches the GPU index in keys like "gpu.0.temp" and
+// "gpu.process.0.temp".
+var gpuMetricIndex = regexp.MustCompile(`^gpu\.(?:process\.)?(\d+)\.`)
+
+// ParseCUDAVisibleDevices parses the value of CUDA_VISIBLE_DEVICES.
+//
+// If the variable is unset or "all", all GPUs are visible. If it is empty,
+// no GPUs are visible. Otherwise it is a comma-separated list of GPU
+// indices.
+//
+// Devices may also be listed by UUID, as in "GPU-<uuid>". The sampled
+// metrics are keyed by index, so UUIDs can't be matched against them and
+// all GPUs are treated as visible.
+func ParseCUDAVisibleDevices(value string, isSet bool) VisibleGPUs {
+	value = strings.TrimSpace(value)
+	if !isSet || value == "all" {
 		return nil
 	}
 
+	visible := make(VisibleGPUs)
+	if value == "" {
+		return visible
+	}
+
+	for _, device := range strings.Split(value, ",") {
+		index, err := strconv.Atoi(strings.TrimSpace(device))
+		if err != nil || index < 0 {
+			return nil
+		}
+		visible[index] = struct{}{}
+	}
+	return visible
+}
+
+// Filter removes the metrics of GPUs that are not visible.
+//
+// Metrics that are not specific to one GPU are kept.
+func (v VisibleGPUs) Filter(metrics map[string]any) map[string]any {
+	if v == nil {
+		return metrics
+	}
+
+	for key := range metrics {
+		match := gpuMetricIndex.FindStringSubmatch(key)
+		if match == nil {
+			continue
+		}
+		index, err := strconv.Atoi(match[1])
+		if err != nil {
+			continue
+		}
+		if _, ok := v[index]; !ok {
+			delete(metrics, key)
+		}
+	}
+	return metrics
+}
+
+// ResolveMonitoredPID returns the PID whose GPU usage is reported.
+//
+// If pid is not set, the current process is monitored. When wandb-core
+// runs as a separate service, the PID of the training process must be
+// passed explicitly.
+func ResolveMonitoredPID(pid int32) int32 {
+	if pid <= 0 {
+		return int32(os.Getpid())
+	}
+	return pid
+}
+
+// NewGPU starts the gpu_stats binary and connects to it.
+//
+// Process-specific metrics are reported for pid and its descendants, or for
+// the current process if pid is 0.
+//
+// statsPath is the path to the gpu_stats binary, or empty to use the one
+// next to the current executable.
+//
+// Returns nil if GPU metrics can't be collected. The reason is logged as a
+// warning, since otherwise GPU metrics would silently be missing.
+func NewGPU(
+	logger *observability.CoreLogger,
+	pid int32,
+	statsPath string,
+) *GPU {
+	g := &GPU{
+		logger:       logger,
+		pid:          ResolveMonitoredPID(pid),
+		visible:      ParseCUDAVisibleDevices(os.LookupEnv("CUDA_VISIBLE_DEVICES")),
+		RestartDelay: DefaultGPUStatsRestartDelay,
+	}
+
+	unavailable := func(reason string, err error) *GPU {
+		logger.Warn(
+			"monitor: gpu: GPU metrics are unavailable",
+			"reason", reason,
+			"error", err,
+		)
+		return nil
+	}
+
+	cmdPath, err := GPUStatsPath(statsPath)
+	var notFound *GPUStatsNotFoundError
+	switch {
+	case errors.As(err, &notFound):
+		return unavailable("gpu_stats binary not found", err)
+	case err != nil:
+		return unavailable("failed to find gpu_stats", err)
+	}
+	g.cmdPath = cmdPath
+
 	// pid of the current wandb-core process.
 	// the gpu_binary would shut down if this process dies.
-	ppid := os.Getpid()
+	g.cmdArgs = []string{"--ppid", strconv.Itoa(os.Getpid())}
+
+	if err := g.start(); err != nil {
+		return unavailable("failed to start gpu_stats", err)
+	}
+
+	return g
+}
+
+// start starts the gpu_stats binary and connects to it.
+//
+// The caller must hold procMu, unless g is not yet shared.
+func (g *GPU) start() error {
+	// A portfile is used to communicate the port number of the gRPC service
+	// started by the gpu_stats binary.
+	pf := NewPortfile()
+	if pf == nil {
+		return errors.New("monitor: failed to create portfile")
+	}
 
 	// Start the gpu_stats binary, which will in turn start a gRPC service and
 	// write the port number to the portfile.
-	cmdPath, err := getGPUStatsCmdPath()
-	if err != nil {
-		return nil
+	args := append([]string{"--portfile", pf.path}, g.cmdArgs...)
+	cmd := exec.Command(g.cmdPath, args...)
+	g.startTime = time.Now()
+	if err := cmd.Start(); err != nil {
+		return err
 	}
-	g.cmd = exec.Command(
-		cmdPath,
-		"--portfile",
-		pf.path,
-		"--ppid",
-		strconv.Itoa(ppid),
-	)
-	if err := g.cmd.Start(); err != nil {
-		return nil
+
+	// Wait for the process to exit to prevent zombie processes, and to
+	// detect when it must be restarted.
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+
+	fail := func(err error) error {
+		_ = cmd.Process.Kill()
+		return err
 	}
 
 	// Read the port number of the gRPC service from the portfile.
@@ -67,11 +319,11 @@ func NewGPU(pid int32) *GPU {
 	defer cancel()
 	port, err := pf.Read(ctx)
 	if err != nil {
-		return nil
+		return fail(fmt.Errorf("monitor: gpu_stats did not report its port: %v", err))
 	}
 	err = pf.Delete()
 	if err != nil {
-		return nil
+		return fail(fmt.Errorf("monitor: failed to delete portfile: %v", err))
 	}
 
 	// Establish connection to gpu_stats via gRPC.
@@ -80,32 +332,150 @@ func NewGPU(pid int32) *GPU {
 	// Use of the ClientConn for RPCs will automatically cause it to connect.
 	conn, err := grpc.NewClient(grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
-		return nil
+		return fail(fmt.Errorf("monitor: failed to connect to gpu_stats: %v", err))
 	}
+
+	g.cmd = cmd
+	g.exited = exited
 	g.conn = conn
+	g.client = spb.NewSystemMonitorClient(conn)
+	return nil
+}
 
-	client := spb.NewSystemMonitorClient(g.conn)
-	g.client = client
+// currentClient returns the client for gpu_stats, first restarting
+// gpu_stats if it has exited and is due for a restart.
+//
+// Returns nil if gpu_stats has exited and isn't running again yet.
+func (g *GPU) currentClient() spb.SystemMonitorClient {
+	g.procMu.Lock()
+	defer g.procMu.Unlock()
 
-	return g
+	if g.exited == nil || g.closed {
+		return g.client
+	}
+
+	select {
+	case <-g.exited:
+	default:
+		return g.client
+	}
+
+	if time.Since(g.startTime) < g.restartDelay() {
+		return nil
+	}
+
+	g.logger.Warn("monitor: gpu: gpu_stats exited, restarting it")
+	if g.conn != nil {
+		g.conn.Close()
+		g.conn = nil
+	}
+	if err := g.start(); err != nil {
+		g.failedStarts++
+		g.logger.Error(
+			"monitor: gpu: failed to restart gpu_stats",
+			"error", err,
+			"retry_in", g.restartDelay(),
+		)
+		return nil
+	}
+	g.failedStarts = 0
+	return g.client
 }
 
-// getGPUStatsCmdPath returns the path to the gpu_stats program.
-func getGPUStatsCmdPath() (string, error) {
-	ex, err := os.Executable()
-	if err != nil {
-		return "", err
+// restartDelay is the minimum time from the last start of gpu_stats to
+// the next one, which grows with each restart that fails.
+//
+// The caller must hold procMu.
+func (g *GPU) restartDelay() time.Duration {
+	delay := g.RestartDelay
+	for range g.failedStarts {
+		if delay >= maxGPUStatsRestartDelay/2 {
+			return maxGPUStatsRestartDelay
+		}
+		delay *= 2
 	}
-	exDirPath := filepath.Dir(ex)
-	exPath := filepath.Join(exDirPath, "gpu_stats")
+	return delay
+}
 
-	// append .exe if running on Windows
-	if runtime.GOOS == "windows" {
-		exPath += ".exe"
+// gpuStatsExited reports whether gpu_stats exits within the given time.
+//
+// It's false if the GPU doesn't manage a gpu_stats process.
+func (g *GPU) gpuStatsExited(wait time.Duration) bool {
+	g.procMu.Lock()
+	exited := g.exited
+	g.procMu.Unlock()
+
+	if exited == nil {
+		return false
 	}
 
-	if _, err := os.Stat(exPath); os.IsNotExist(err) {
-		return "", err
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-exited:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// getGPUStatsCmdPath returns the path to the gpu_stats program.
+// GPUStatsNotFoundError is returned when the gpu_stats binary doesn't
+// exist.
+type GPUStatsNotFoundError struct {
+	// Path is where the binary was looked for.
+	Path string
+}
+
+func (e *GPUStatsNotFoundError) Error() string {
+	return fmt.Sprintf("monitor: gpu_stats binary not found at %s", e.Path)
+}
+
+// GPUStatsStatError is returned when it can't be determined whether the
+// gpu_stats binary exists, for example because of missing permissions.
+type GPUStatsStatError struct {
+	// Path is where the binary was looked for.
+	Path string
+
+	// Err is the error from stat.
+	Err error
+}
+
+func (e *GPUStatsStatError) Error() string {
+	return fmt.Sprintf("monitor: failed to stat gpu_stats at %s: %v", e.Path, e.Err)
+}
+
+func (e *GPUStatsStatError) Unwrap() error {
+	return e.Err
+}
+
+// GPUStatsPath returns the path to the gpu_stats binary.
+//
+// If override is set, it's the path to use. Otherwise, the binary is
+// expected next to the current executable.
+//
+// Returns a *GPUStatsNotFoundError if the binary doesn't exist, or a
+// *GPUStatsStatError if that can't be determined.
+func GPUStatsPath(override string) (string, error) {
+	exPath := override
+	if exPath == "" {
+		ex, err := os.Executable()
+		if err != nil {
+			return "", err
+		}
+		exPath = filepath.Join(filepath.Dir(ex), "gpu_stats")
+
+		// append .exe if running on Windows
+		if runtime.GOOS == "windows" {
+			exPath += ".exe"
+		}
+	}
+
+	switch _, err := os.Stat(exPath); {
+	case errors.Is(err, fs.ErrNotExist):
+		return "", &GPUStatsNotFoundError{Path: exPath}
+	case err != nil:
+		return "", &GPUStatsStatError{Path: exPath, Err: err}
 	}
 	return exPath, nil
 }
@@ -124,8 +494,25 @@ func (g *GPU) IsAvailable() bool {
 // This function is a temporary adapter that adds extra ser/de ops.
 // Will refactor to use the protobuf message directly.
 func (g *GPU) Sample() (map[string]any, error) {
-	stats, err := g.client.GetStats(context.Background(), &spb.GetStatsRequest{Pid: g.pid})
+	if g.IdleGate != nil && !g.IdleGate.ShouldSample(time.Now()) {
+		return nil, nil
+	}
+
+	client := g.currentClient()
+	if client == nil {
+		return nil, nil // gpu_stats is down until it's restarted
+	}
+
+	stats, err := client.GetStats(
+		context.Background(),
+		&spb.GetStatsRequest{Pid: g.pid},
+	)
 	if err != nil {
+		if g.gpuStatsExited(gpuStatsExitWait) {
+			// gpu_stats crashed; it's restarted by a later sample.
+			g.logger.Warn("monitor: gpu: gpu_stats exited", "error", err)
+			return nil, nil
+		}
 		return nil, err
 	}
 
@@ -144,26 +531,183 @@ func (g *GPU) Sample() (map[string]any, error) {
 		metrics[item.Key] = unmarshalled
 	}
 
-	return metrics, nil
+	metrics = g.visible.Filter(metrics)
+
+	now := time.Now()
+
+	if g.IdleGate != nil {
+		g.IdleGate.Observe(now, metrics)
+	}
+
+	// The histogram and the power limit tracker count individual samples,
+	// not averages.
+	if g.PowerHistogram != nil {
+		g.PowerHistogram.AddMetrics(metrics)
+	}
+	if g.PowerLimit != nil {
+		g.PowerLimit.Add(now, metrics)
+	}
+
+	if g.Window != nil {
+		g.Window.Add(now, metrics)
+		metrics = g.Window.Aggregate(now, metrics)
+	}
+
+	if g.PowerHistogram != nil {
+		for k, v := range g.PowerHistogram.Metrics() {
+			metrics[k] = v
+		}
+	}
+	if g.PowerLimit != nil {
+		for k, v := range g.PowerLimit.Metrics(now) {
+			metrics[k] = v
+		}
+	}
+
+	metrics = g.MetricFilter.Filter(metrics)
+	return g.MetricNamer.Apply(metrics), nil
 }
 
 // Probe returns metadata about the GPU.
+//
+// The metadata is reported in proto fields rather than under metric keys,
+// so MetricNamer doesn't apply to it.
 func (g *GPU) Probe() *spb.MetadataRequest {
-	metadata, err := g.client.GetMetadata(context.Background(), &spb.GetMetadataRequest{})
+	client := g.currentClient()
+	if client == nil {
+		return nil
+	}
+
+	metadata, err := client.GetMetadata(
+		context.Background(),
+		&spb.GetMetadataRequest{},
+	)
+	if err != nil {
+		return nil
+	}
+
+	info := metadata.GetRequest().GetMetadata()
+	if g.visible != nil && len(info.GetGpuNvidia()) > 0 {
+		var gpus []*spb.GpuNvidiaInfo
+		for i, gpu := range info.GetGpuNvidia() {
+			if _, ok := g.visible[i]; ok {
+				gpus = append(gpus, gpu)
+			}
+		}
+		info.GpuNvidia = gpus
+		info.GpuCount = uint32(len(gpus))
+	}
+
+	g.lastProbeMu.Lock()
+	g.lastProbe = info
+	g.lastProbeMu.Unlock()
+
+	return info
+}
+
+// Reprobe probes the GPUs again and returns how their metadata changed
+// since the last probe, so that run metadata can be updated mid-run.
+//
+// It returns nil if the GPUs can't be probed.
+func (g *GPU) Reprobe() *GPUMetadataDiff {
+	g.lastProbeMu.Lock()
+	previous := g.lastProbe
+	g.lastProbeMu.Unlock()
+
+	current := g.Probe()
+	if current == nil {
+		return nil
+	}
+	return DiffGPUMetadata(previous, current)
+}
+
+// SetClient sets the client used to talk to gpu_stats.
+func (g *GPU) SetClient(client spb.SystemMonitorClient) {
+	g.procMu.Lock()
+	defer g.procMu.Unlock()
+
+	g.client = client
+}
+
+// MetricKeys returns the keys of the metrics Sample can report for the
+// detected Nvidia GPUs, whether or not any samples have been taken.
+//
+// The keys are filtered and named like the samples. Process metrics are
+// included even though they're only reported for GPUs the monitored
+// process uses. It returns nil if the GPUs can't be probed.
+func (g *GPU) MetricKeys() []string {
+	units := g.metricUnits()
+	if units == nil {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(units))
+}
+
+// MetricUnits returns the unit of each metric Sample can report for the
+// detected Nvidia GPUs, like UnitWatts for "gpu.0.powerWatts", so that
+// consumers can label their axes.
+//
+// It covers the same keys as MetricKeys. It returns nil if the GPUs can't
+// be probed.
+func (g *GPU) MetricUnits() map[string]string {
+	units := g.metricUnits()
+	if units == nil {
+		return nil
+	}
+
+	unitStrings := make(map[string]string, len(units))
+	for key, unit := range units {
+		unitStrings[key] = unit.(string)
+	}
+	return unitStrings
+}
+
+// metricUnits returns the units of the metrics Sample can report, keyed
+// like the samples, or nil if the GPUs can't be probed.
+func (g *GPU) metricUnits() map[string]any {
+	client := g.currentClient()
+	if client == nil {
+		return nil
+	}
+
+	metadata, err := client.GetMetadata(
+		context.Background(),
+		&spb.GetMetadataRequest{},
+	)
 	if err != nil {
 		return nil
 	}
-	return metadata.GetRequest().GetMetadata()
+
+	units := make(map[string]any)
+	for gpu := range len(metadata.GetRequest().GetMetadata().GetGpuNvidia()) {
+		for _, name := range nvidiaMetricNames {
+			units[fmt.Sprintf("gpu.%d.%s", gpu, name)] = nvidiaMetricUnits[name]
+		}
+		for _, name := range nvidiaProcessMetricNames {
+			units[fmt.Sprintf("gpu.process.%d.%s", gpu, name)] = nvidiaMetricUnits[name]
+		}
+		if g.PowerHistogram != nil {
+			for _, key := range g.PowerHistogram.bucketKeys(gpu) {
+				units[key] = UnitCount
+			}
+		}
+		if g.PowerLimit != nil {
+			units[powerLimitedFractionKey(gpu)] = UnitRatio
+		}
+	}
+
+	units = g.visible.Filter(units)
+	units = g.MetricFilter.Filter(units)
+	return g.MetricNamer.Apply(units)
 }
 
 // Close shuts down the gpu_stats binary and releases resources.
 func (g *GPU) Close() {
+	g.procMu.Lock()
+	defer g.procMu.Unlock()
+
+	g.closed = true
 	if _, err := g.client.TearDown(context.Background(), &emptypb.Empty{}); err == nil { // ignore error
 		g.conn.Close()
-		// Wait for the process to exit to prevent zombie processes.
-		// This is a best-effort attempt to clean up the process.
-		go func() {
-			_ = g.cmd.Wait()
-		}()
 	}
 }
@@ -0,0 +1,102 @@
+package monitor_test
+
+import (
+	"context"
+	"fmt"
+
+	spb "github.com/wandb/wandb/core/pkg/service_go_proto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// fakeGPUStats is a gpu_stats client that reports every metric for each of
+// its Nvidia GPUs, standing in for NVML so that the GPU asset can be tested
+// without real hardware.
+type fakeGPUStats struct {
+	// gpus is the number of GPUs to report.
+	gpus int
+
+	// err, if set, is returned by every call.
+	err error
+
+	// idle, if set, omits the process metrics, as if the monitored process
+	// weren't using any GPU.
+	idle bool
+
+	// statsCalls is the number of calls to GetStats.
+	statsCalls int
+}
+
+func (f *fakeGPUStats) GetStats(
+	ctx context.Context,
+	in *spb.GetStatsRequest,
+	opts ...grpc.CallOption,
+) (*spb.Record, error) {
+	f.statsCalls++
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	var items []*spb.StatsItem
+	for gpu := range f.gpus {
+		for _, name := range []string{
+			"gpu", "memory", "memoryAllocated", "memoryAllocatedBytes",
+			"temp", "powerWatts", "enforcedPowerLimitWatts", "powerPercent",
+		} {
+			items = append(items,
+				&spb.StatsItem{Key: fmt.Sprintf("gpu.%d.%s", gpu, name), ValueJson: "100"})
+			if !f.idle {
+				items = append(items,
+					&spb.StatsItem{Key: fmt.Sprintf("gpu.process.%d.%s", gpu, name), ValueJson: "100"})
+			}
+		}
+		for _, name := range []string{
+			"fanSpeed", "encoderUtilization", "graphicsClock", "memoryClock",
+			"smClock", "pcieLinkGen", "correctedMemoryErrors",
+			"uncorrectedMemoryErrors",
+		} {
+			items = append(items,
+				&spb.StatsItem{Key: fmt.Sprintf("gpu.%d.%s", gpu, name), ValueJson: "1"})
+		}
+	}
+	items = append(items, &spb.StatsItem{
+		Key:       "_gpu.count",
+		ValueJson: fmt.Sprint(f.gpus),
+	})
+
+	return &spb.Record{RecordType: &spb.Record_Stats{
+		Stats: &spb.StatsRecord{Item: items},
+	}}, nil
+}
+
+func (f *fakeGPUStats) GetMetadata(
+	ctx context.Context,
+	in *spb.GetMetadataRequest,
+	opts ...grpc.CallOption,
+) (*spb.Record, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	metadata := &spb.MetadataRequest{GpuCount: uint32(f.gpus)}
+	for gpu := range f.gpus {
+		metadata.GpuNvidia = append(metadata.GpuNvidia, &spb.GpuNvidiaInfo{
+			Name:        fmt.Sprintf("GPU %d", gpu),
+			MemoryTotal: 80 << 30,
+		})
+	}
+
+	return &spb.Record{RecordType: &spb.Record_Request{
+		Request: &spb.Request{RequestType: &spb.Request_Metadata{
+			Metadata: metadata,
+		}},
+	}}, nil
+}
+
+func (f *fakeGPUStats) TearDown(
+	ctx context.Context,
+	in *emptypb.Empty,
+	opts ...grpc.CallOption,
+) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, f.err
+}
@@ -0,0 +1,97 @@
+package monitor
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultGPUIdleSampleInterval is how often GPUs are sampled while the
+// monitored process isn't using any of them, unless configured otherwise.
+const DefaultGPUIdleSampleInterval = time.Minute
+
+// GPUIdleGate slows down GPU sampling while the monitored process isn't
+// using any GPU, like between epochs spent preprocessing data on the CPU.
+//
+// Once the process hasn't been seen on a GPU for a grace period, samples
+// are only taken once per idle interval. Each of them checks whether the
+// process is back, in which case full-rate sampling resumes.
+type GPUIdleGate struct {
+	mu sync.Mutex
+
+	// grace is how long the process must go unseen on every GPU before
+	// sampling slows down.
+	grace time.Duration
+
+	// idleInterval is the minimum time between samples while idle.
+	idleInterval time.Duration
+
+	// lastActive is when the process was last seen on a GPU, or when the
+	// first sample was observed if it hasn't been seen yet.
+	lastActive time.Time
+
+	// lastSample is when the last sample was observed.
+	lastSample time.Time
+
+	// idle is whether sampling is slowed down.
+	idle bool
+}
+
+// NewGPUIdleGate returns a gate that slows sampling down to once per
+// idleInterval after the process goes unseen on the GPUs for grace.
+//
+// If idleInterval isn't positive, DefaultGPUIdleSampleInterval is used.
+func NewGPUIdleGate(grace, idleInterval time.Duration) *GPUIdleGate {
+	if idleInterval <= 0 {
+		idleInterval = DefaultGPUIdleSampleInterval
+	}
+
+	return &GPUIdleGate{grace: grace, idleInterval: idleInterval}
+}
+
+// ShouldSample reports whether a sample should be taken at the given time.
+func (g *GPUIdleGate) ShouldSample(now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return !g.idle || now.Sub(g.lastSample) >= g.idleInterval
+}
+
+// Observe records a sample of GPU metrics taken at the given time.
+//
+// The process is using a GPU if there are process metrics for it, like
+// "gpu.process.0.gpu".
+func (g *GPUIdleGate) Observe(now time.Time, metrics map[string]any) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.lastSample = now
+
+	switch {
+	case usesGPU(metrics):
+		g.lastActive = now
+		g.idle = false
+	case g.lastActive.IsZero():
+		g.lastActive = now
+	case now.Sub(g.lastActive) >= g.grace:
+		g.idle = true
+	}
+}
+
+// Idle reports whether sampling is slowed down.
+func (g *GPUIdleGate) Idle() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.idle
+}
+
+// usesGPU reports whether GPU metrics include process metrics.
+func usesGPU(metrics map[string]any) bool {
+	for key := range metrics {
+		if strings.HasPrefix(key, "gpu.process.") {
+			return true
+		}
+	}
+	return false
+}
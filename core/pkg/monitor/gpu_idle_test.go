@@ -0,0 +1,67 @@
+package monitor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wandb/wandb/core/pkg/monitor"
+)
+
+var (
+	activeGPUMetrics = map[string]any{"gpu.0.gpu": 10.0, "gpu.process.0.gpu": 10.0}
+	idleGPUMetrics   = map[string]any{"gpu.0.gpu": 0.0}
+)
+
+func TestGPUIdleGate_SlowsDownAfterGracePeriod(t *testing.T) {
+	gate := monitor.NewGPUIdleGate(30*time.Second, time.Minute)
+	start := time.Now()
+
+	gate.Observe(start, activeGPUMetrics)
+	gate.Observe(start.Add(10*time.Second), idleGPUMetrics)
+	gate.Observe(start.Add(20*time.Second), idleGPUMetrics)
+	assert.False(t, gate.Idle())
+	assert.True(t, gate.ShouldSample(start.Add(30*time.Second)))
+
+	gate.Observe(start.Add(30*time.Second), idleGPUMetrics)
+	assert.True(t, gate.Idle())
+	assert.False(t, gate.ShouldSample(start.Add(40*time.Second)))
+	assert.True(t, gate.ShouldSample(start.Add(90*time.Second)))
+}
+
+func TestGPUIdleGate_ResumesWhenProcessReturns(t *testing.T) {
+	gate := monitor.NewGPUIdleGate(0, time.Minute)
+	start := time.Now()
+
+	gate.Observe(start, idleGPUMetrics)
+	gate.Observe(start.Add(10*time.Second), idleGPUMetrics)
+	assert.True(t, gate.Idle())
+
+	gate.Observe(start.Add(70*time.Second), activeGPUMetrics)
+	assert.False(t, gate.Idle())
+	assert.True(t, gate.ShouldSample(start.Add(80*time.Second)))
+}
+
+func TestGPUIdleGate_GracePeriodStartsAtFirstSample(t *testing.T) {
+	gate := monitor.NewGPUIdleGate(30*time.Second, time.Minute)
+	start := time.Now()
+
+	// The process hasn't started using the GPUs yet.
+	gate.Observe(start, idleGPUMetrics)
+	gate.Observe(start.Add(20*time.Second), idleGPUMetrics)
+	assert.False(t, gate.Idle())
+
+	gate.Observe(start.Add(40*time.Second), idleGPUMetrics)
+	assert.True(t, gate.Idle())
+}
+
+func TestNewGPUIdleGate_DefaultInterval(t *testing.T) {
+	gate := monitor.NewGPUIdleGate(0, 0)
+	start := time.Now()
+
+	gate.Observe(start, idleGPUMetrics)
+	gate.Observe(start, idleGPUMetrics)
+
+	assert.False(t, gate.ShouldSample(start.Add(monitor.DefaultGPUIdleSampleInterval/2)))
+	assert.True(t, gate.ShouldSample(start.Add(monitor.DefaultGPUIdleSampleInterval)))
+}
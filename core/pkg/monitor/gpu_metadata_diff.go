@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	spb "github.com/wandb/wandb/core/pkg/service_go_proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// GPUDeviceChange is the metadata of a GPU in two probes.
+//
+// Previous is nil for an added GPU, and Current for a removed one.
+type GPUDeviceChange struct {
+	// Index is the GPU's position in the probed metadata.
+	Index int
+
+	Previous *spb.GpuNvidiaInfo
+	Current  *spb.GpuNvidiaInfo
+}
+
+// GPUMetadataDiff describes how the GPUs changed between two probes, such
+// as after MIG reconfiguration or a driver reset.
+type GPUMetadataDiff struct {
+	// PreviousCount and CurrentCount are the reported GPU counts.
+	PreviousCount uint32
+	CurrentCount  uint32
+
+	// Added, Removed and Changed are the GPUs that appeared, disappeared
+	// or whose metadata, like the name or total memory, changed.
+	Added   []GPUDeviceChange
+	Removed []GPUDeviceChange
+	Changed []GPUDeviceChange
+}
+
+// IsEmpty reports whether nothing changed.
+func (d *GPUMetadataDiff) IsEmpty() bool {
+	return d.PreviousCount == d.CurrentCount &&
+		len(d.Added) == 0 &&
+		len(d.Removed) == 0 &&
+		len(d.Changed) == 0
+}
+
+// DiffGPUMetadata compares the Nvidia GPUs in two probes.
+//
+// GPUs are matched by their position. Either probe may be nil, meaning it
+// found no GPUs.
+func DiffGPUMetadata(previous, current *spb.MetadataRequest) *GPUMetadataDiff {
+	diff := &GPUMetadataDiff{
+		PreviousCount: previous.GetGpuCount(),
+		CurrentCount:  current.GetGpuCount(),
+	}
+
+	previousGPUs := previous.GetGpuNvidia()
+	currentGPUs := current.GetGpuNvidia()
+	for i := range max(len(previousGPUs), len(currentGPUs)) {
+		change := GPUDeviceChange{Index: i}
+		if i < len(previousGPUs) {
+			change.Previous = previousGPUs[i]
+		}
+		if i < len(currentGPUs) {
+			change.Current = currentGPUs[i]
+		}
+
+		switch {
+		case change.Previous == nil:
+			diff.Added = append(diff.Added, change)
+		case change.Current == nil:
+			diff.Removed = append(diff.Removed, change)
+		case !proto.Equal(change.Previous, change.Current):
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,71 @@
+package monitor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wandb/wandb/core/pkg/monitor"
+	spb "github.com/wandb/wandb/core/pkg/service_go_proto"
+)
+
+func a100(memoryTotal uint64) *spb.GpuNvidiaInfo {
+	return &spb.GpuNvidiaInfo{
+		Name:         "NVIDIA A100-SXM4-80GB",
+		MemoryTotal:  memoryTotal,
+		CudaCores:    6912,
+		Architecture: "Ampere",
+	}
+}
+
+func TestDiffGPUMetadata_Unchanged(t *testing.T) {
+	probe := &spb.MetadataRequest{
+		GpuCount:  2,
+		GpuNvidia: []*spb.GpuNvidiaInfo{a100(80e9), a100(80e9)},
+	}
+
+	diff := monitor.DiffGPUMetadata(probe, probe)
+
+	assert.True(t, diff.IsEmpty())
+}
+
+func TestDiffGPUMetadata_Reconfigured(t *testing.T) {
+	previous := &spb.MetadataRequest{
+		GpuCount:  2,
+		GpuNvidia: []*spb.GpuNvidiaInfo{a100(80e9), a100(80e9)},
+	}
+	// The first GPU was split into MIG instances and the second was reset
+	// and is gone.
+	mig := &spb.GpuNvidiaInfo{Name: "MIG 3g.40gb", MemoryTotal: 40e9}
+	current := &spb.MetadataRequest{
+		GpuCount:  1,
+		GpuNvidia: []*spb.GpuNvidiaInfo{mig},
+	}
+
+	diff := monitor.DiffGPUMetadata(previous, current)
+
+	assert.False(t, diff.IsEmpty())
+	assert.Equal(t, uint32(2), diff.PreviousCount)
+	assert.Equal(t, uint32(1), diff.CurrentCount)
+	assert.Empty(t, diff.Added)
+	assert.Equal(t,
+		[]monitor.GPUDeviceChange{{Index: 1, Previous: previous.GpuNvidia[1]}},
+		diff.Removed)
+	assert.Equal(t,
+		[]monitor.GPUDeviceChange{{Index: 0, Previous: previous.GpuNvidia[0], Current: mig}},
+		diff.Changed)
+}
+
+func TestDiffGPUMetadata_Added(t *testing.T) {
+	current := &spb.MetadataRequest{
+		GpuCount:  1,
+		GpuNvidia: []*spb.GpuNvidiaInfo{a100(80e9)},
+	}
+
+	diff := monitor.DiffGPUMetadata(nil, current)
+
+	assert.Equal(t,
+		[]monitor.GPUDeviceChange{{Index: 0, Current: current.GpuNvidia[0]}},
+		diff.Added)
+	assert.Empty(t, diff.Removed)
+	assert.Empty(t, diff.Changed)
+}
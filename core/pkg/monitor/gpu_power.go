@@ -0,0 +1,263 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPowerHistogramBoundaries are the edges of 50W-wide buckets from
+// 0W to 750W.
+var DefaultPowerHistogramBoundaries = func() []float64 {
+	boundaries := make([]float64, 0, 16)
+	for watts := 0.0; watts <= 750; watts += 50 {
+		boundaries = append(boundaries, watts)
+	}
+	return boundaries
+}()
+
+// gpuPowerMetric matches the power draw of a GPU, as in "gpu.0.powerWatts".
+var gpuPowerMetric = regexp.MustCompile(`^gpu\.(\d+)\.powerWatts$`)
+
+const (
+	// powerLimitedThreshold is the fraction of its enforced power limit at
+	// or above which a GPU counts as power-limited.
+	powerLimitedThreshold = 0.95
+
+	// DefaultPowerLimitWindow is the window over which the power-limited
+	// fraction is computed unless configured otherwise.
+	DefaultPowerLimitWindow = time.Minute
+)
+
+// PowerHistogram counts the power draw samples of each GPU in fixed
+// buckets over the course of a run.
+type PowerHistogram struct {
+	mu sync.Mutex
+
+	// boundaries are the sorted bucket edges; bucket i covers
+	// [boundaries[i], boundaries[i+1]).
+	boundaries []float64
+
+	// counts are the bucket counts by GPU index.
+	counts map[int][]int
+}
+
+// NewPowerHistogram returns a histogram with the given bucket edges.
+//
+// At least two edges are required. Samples outside of the range are
+// counted in the first or last bucket.
+func NewPowerHistogram(boundaries []float64) (*PowerHistogram, error) {
+	if len(boundaries) < 2 {
+		return nil, fmt.Errorf(
+			"monitor: power histogram needs at least 2 boundaries, got %d",
+			len(boundaries),
+		)
+	}
+
+	sorted := append([]float64(nil), boundaries...)
+	sort.Float64s(sorted)
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] == sorted[i-1] {
+			return nil, fmt.Errorf(
+				"monitor: duplicate power histogram boundary %v",
+				sorted[i],
+			)
+		}
+	}
+
+	return &PowerHistogram{
+		boundaries: sorted,
+		counts:     make(map[int][]int),
+	}, nil
+}
+
+// ParsePowerHistogramBoundaries parses a comma-separated list of bucket
+// edges in watts, like "0,100,200,300".
+//
+// Returns nil if the list is empty.
+func ParsePowerHistogramBoundaries(spec string) ([]float64, error) {
+	var boundaries []float64
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		watts, err := strconv.ParseFloat(item, 64)
+		if err != nil || math.IsNaN(watts) || math.IsInf(watts, 0) {
+			return nil, fmt.Errorf(
+				"monitor: invalid power histogram boundary %q, expected watts",
+				item,
+			)
+		}
+		boundaries = append(boundaries, watts)
+	}
+
+	return boundaries, nil
+}
+
+// Add counts a power draw sample for the GPU at the given index.
+func (h *PowerHistogram) Add(gpu int, watts float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	counts, ok := h.counts[gpu]
+	if !ok {
+		counts = make([]int, len(h.boundaries)-1)
+		h.counts[gpu] = counts
+	}
+
+	// index of the first boundary above the sample, clamped to the buckets
+	bucket := sort.SearchFloat64s(h.boundaries, watts)
+	if bucket < len(h.boundaries) && h.boundaries[bucket] == watts {
+		bucket++
+	}
+	bucket = min(max(bucket-1, 0), len(counts)-1)
+	counts[bucket]++
+}
+
+// AddMetrics counts the power draw samples in a set of GPU metrics.
+func (h *PowerHistogram) AddMetrics(metrics map[string]any) {
+	for key, value := range metrics {
+		match := gpuPowerMetric.FindStringSubmatch(key)
+		if match == nil {
+			continue
+		}
+		gpu, err := strconv.Atoi(match[1])
+		if err != nil {
+			continue
+		}
+		if watts, ok := value.(float64); ok {
+			h.Add(gpu, watts)
+		}
+	}
+}
+
+// Metrics returns the bucket counts of each GPU, keyed like
+// "gpu.0.powerWatts.histogram.50-100".
+func (h *PowerHistogram) Metrics() map[string]any {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	metrics := make(map[string]any)
+	for gpu, counts := range h.counts {
+		for i, count := range counts {
+			metrics[h.bucketKey(gpu, i)] = count
+		}
+	}
+	return metrics
+}
+
+// bucketKeys returns the keys of a GPU's bucket counts.
+func (h *PowerHistogram) bucketKeys(gpu int) []string {
+	keys := make([]string, 0, len(h.boundaries)-1)
+	for i := range len(h.boundaries) - 1 {
+		keys = append(keys, h.bucketKey(gpu, i))
+	}
+	return keys
+}
+
+// bucketKey is the key of the count of a GPU's i-th bucket.
+func (h *PowerHistogram) bucketKey(gpu, i int) string {
+	return fmt.Sprintf(
+		"gpu.%d.powerWatts.histogram.%v-%v",
+		gpu, h.boundaries[i], h.boundaries[i+1],
+	)
+}
+
+// PowerLimitTracker reports the fraction of each GPU's samples within a
+// recent window of time in which its power draw was at or above 95% of its
+// enforced power limit, as "gpu.0.powerLimitedFraction".
+//
+// Averaged power hides brief power-limit throttling; this fraction
+// reveals it.
+type PowerLimitTracker struct {
+	// window averages whether each sample was power-limited, as 1 or 0.
+	window *MetricWindow
+
+	mu sync.Mutex
+
+	// gpus are the indices of the GPUs with samples.
+	gpus map[int]struct{}
+}
+
+// NewPowerLimitTracker returns a tracker over the given window.
+func NewPowerLimitTracker(window time.Duration) *PowerLimitTracker {
+	return &PowerLimitTracker{
+		window: NewMetricWindow(window),
+		gpus:   make(map[int]struct{}),
+	}
+}
+
+// Add records whether each GPU was power-limited in a set of GPU metrics.
+//
+// The power draw is compared to the enforced power limit from the same
+// sample. GPUs whose power limit is missing or not positive are skipped.
+func (t *PowerLimitTracker) Add(now time.Time, metrics map[string]any) {
+	limited := make(map[string]any)
+
+	for key, value := range metrics {
+		match := gpuPowerMetric.FindStringSubmatch(key)
+		if match == nil {
+			continue
+		}
+		gpu, err := strconv.Atoi(match[1])
+		if err != nil {
+			continue
+		}
+
+		watts, ok := value.(float64)
+		if !ok {
+			continue
+		}
+		limit, ok := metrics[fmt.Sprintf("gpu.%d.enforcedPowerLimitWatts", gpu)].(float64)
+		if !ok || limit <= 0 {
+			continue
+		}
+
+		fraction := 0.0
+		if watts >= powerLimitedThreshold*limit {
+			fraction = 1.0
+		}
+		limited[powerLimitedFractionKey(gpu)] = fraction
+
+		t.mu.Lock()
+		t.gpus[gpu] = struct{}{}
+		t.mu.Unlock()
+	}
+
+	t.window.Add(now, limited)
+}
+
+// Metrics returns the power-limited fraction of each GPU over the window
+// ending at now.
+//
+// GPUs without samples in the window are omitted.
+func (t *PowerLimitTracker) Metrics(now time.Time) map[string]any {
+	t.mu.Lock()
+	metrics := make(map[string]any, len(t.gpus))
+	for gpu := range t.gpus {
+		// A placeholder that Aggregate replaces if there are samples.
+		metrics[powerLimitedFractionKey(gpu)] = nil
+	}
+	t.mu.Unlock()
+
+	metrics = t.window.Aggregate(now, metrics)
+	for key, value := range metrics {
+		if value == nil {
+			delete(metrics, key)
+		}
+	}
+	return metrics
+}
+
+// powerLimitedFractionKey is the key of a GPU's power-limited fraction.
+func powerLimitedFractionKey(gpu int) string {
+	return fmt.Sprintf("gpu.%d.powerLimitedFraction", gpu)
+}
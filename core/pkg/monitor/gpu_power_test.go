@@ -0,0 +1,132 @@
+package monitor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wandb/wandb/core/pkg/monitor"
+)
+
+func TestPowerHistogram_Buckets(t *testing.T) {
+	h, err := monitor.NewPowerHistogram([]float64{0, 100, 200, 300})
+	require.NoError(t, err)
+
+	for _, watts := range []float64{0, 50, 99.9, 100, 150, 250, 299, 300, 1000, -5} {
+		h.Add(0, watts)
+	}
+	h.Add(1, 120)
+
+	assert.Equal(t,
+		map[string]any{
+			// 0, 50, 99.9 and -5 (clamped)
+			"gpu.0.powerWatts.histogram.0-100": 4,
+			// 100 and 150
+			"gpu.0.powerWatts.histogram.100-200": 2,
+			// 250, 299, 300 and 1000 (clamped)
+			"gpu.0.powerWatts.histogram.200-300": 4,
+
+			"gpu.1.powerWatts.histogram.0-100":   0,
+			"gpu.1.powerWatts.histogram.100-200": 1,
+			"gpu.1.powerWatts.histogram.200-300": 0,
+		},
+		h.Metrics(),
+	)
+}
+
+func TestPowerHistogram_AddMetrics(t *testing.T) {
+	h, err := monitor.NewPowerHistogram(monitor.DefaultPowerHistogramBoundaries)
+	require.NoError(t, err)
+
+	h.AddMetrics(map[string]any{
+		"gpu.0.powerWatts":         275.0,
+		"gpu.0.powerPercent":       50.0,
+		"gpu.process.0.powerWatts": 275.0,
+	})
+
+	metrics := h.Metrics()
+	assert.Len(t, metrics, 15)
+	assert.Equal(t, 1, metrics["gpu.0.powerWatts.histogram.250-300"])
+}
+
+func TestNewPowerHistogram_InvalidBoundaries(t *testing.T) {
+	_, err := monitor.NewPowerHistogram([]float64{100})
+	assert.Error(t, err)
+
+	_, err = monitor.NewPowerHistogram([]float64{0, 100, 100})
+	assert.Error(t, err)
+}
+
+func TestParsePowerHistogramBoundaries(t *testing.T) {
+	boundaries, err := monitor.ParsePowerHistogramBoundaries(" 0, 150.5 ,300,")
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0, 150.5, 300}, boundaries)
+
+	boundaries, err = monitor.ParsePowerHistogramBoundaries("")
+	require.NoError(t, err)
+	assert.Nil(t, boundaries)
+}
+
+func TestParsePowerHistogramBoundaries_Invalid(t *testing.T) {
+	for _, spec := range []string{"0,100W", "0,NaN", "0,Inf"} {
+		_, err := monitor.ParsePowerHistogramBoundaries(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+// powerSample is a GPU sample with the given power draw and limit.
+func powerSample(gpu0Watts, gpu1Watts float64) map[string]any {
+	return map[string]any{
+		"gpu.0.powerWatts":              gpu0Watts,
+		"gpu.0.enforcedPowerLimitWatts": 300.0,
+		"gpu.1.powerWatts":              gpu1Watts,
+		"gpu.1.enforcedPowerLimitWatts": 400.0,
+	}
+}
+
+func TestPowerLimitTracker_Fraction(t *testing.T) {
+	tracker := monitor.NewPowerLimitTracker(time.Minute)
+	start := time.Now()
+
+	// GPU 0 briefly hits its limit (285W is 95% of 300W); GPU 1 never does.
+	for i, watts := range []float64{200, 290, 285, 150} {
+		tracker.Add(start.Add(time.Duration(i)*time.Second), powerSample(watts, 300))
+	}
+
+	assert.Equal(t,
+		map[string]any{
+			"gpu.0.powerLimitedFraction": 0.5,
+			"gpu.1.powerLimitedFraction": 0.0,
+		},
+		tracker.Metrics(start.Add(3*time.Second)))
+}
+
+func TestPowerLimitTracker_ExcludesOldSamples(t *testing.T) {
+	tracker := monitor.NewPowerLimitTracker(10 * time.Second)
+	start := time.Now()
+
+	tracker.Add(start, powerSample(300, 400))
+	tracker.Add(start.Add(20*time.Second), powerSample(100, 100))
+
+	assert.Equal(t,
+		map[string]any{
+			"gpu.0.powerLimitedFraction": 0.0,
+			"gpu.1.powerLimitedFraction": 0.0,
+		},
+		tracker.Metrics(start.Add(20*time.Second)))
+	assert.Empty(t, tracker.Metrics(start.Add(time.Hour)))
+}
+
+func TestPowerLimitTracker_SkipsGPUsWithoutLimit(t *testing.T) {
+	tracker := monitor.NewPowerLimitTracker(time.Minute)
+	now := time.Now()
+
+	tracker.Add(now, map[string]any{
+		"gpu.0.powerWatts":              250.0,
+		"gpu.1.powerWatts":              250.0,
+		"gpu.1.enforcedPowerLimitWatts": 0.0,
+	})
+
+	assert.Empty(t, tracker.Metrics(now))
+}
@@ -0,0 +1,370 @@
+package monitor_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wandb/wandb/core/internal/observability"
+	"github.com/wandb/wandb/core/pkg/monitor"
+)
+
+func TestNewGPU_LogsWhyUnavailable(t *testing.T) {
+	var logs bytes.Buffer
+	logger := observability.NewCoreLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	// The gpu_stats binary is not next to the test executable.
+	gpu := monitor.NewGPU(logger, 0, "")
+
+	assert.Nil(t, gpu)
+	assert.Contains(t, logs.String(), "level=WARN")
+	assert.Contains(t, logs.String(), "gpu_stats binary not found")
+}
+
+func TestResolveMonitoredPID(t *testing.T) {
+	assert.Equal(t, int32(1234), monitor.ResolveMonitoredPID(1234))
+	assert.Equal(t, int32(os.Getpid()), monitor.ResolveMonitoredPID(0))
+}
+
+func TestParseCUDAVisibleDevices(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		isSet    bool
+		expected monitor.VisibleGPUs
+	}{
+		{"Unset", "", false, nil},
+		{"All", "all", true, nil},
+		{"Empty", "", true, monitor.VisibleGPUs{}},
+		{"Indices", "0,2,3", true, monitor.VisibleGPUs{0: {}, 2: {}, 3: {}}},
+		{"IndicesWithSpaces", " 1, 2 ", true, monitor.VisibleGPUs{1: {}, 2: {}}},
+		{"UUID", "GPU-8e3b4b5a-5c1e-4a0a-9d5f-1c2b3a4d5e6f", true, nil},
+		{"MixedUUID", "0,GPU-8e3b4b5a-5c1e-4a0a-9d5f-1c2b3a4d5e6f", true, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t,
+				tc.expected,
+				monitor.ParseCUDAVisibleDevices(tc.value, tc.isSet),
+			)
+		})
+	}
+}
+
+func TestVisibleGPUsFilter(t *testing.T) {
+	metrics := map[string]any{
+		"gpu.0.gpu":          10.0,
+		"gpu.1.gpu":          20.0,
+		"gpu.2.gpu":          30.0,
+		"gpu.process.1.temp": 50.0,
+		"gpu.process.2.temp": 60.0,
+		"cpu":                1.0,
+	}
+
+	filtered := monitor.VisibleGPUs{0: {}, 2: {}}.Filter(metrics)
+
+	assert.Equal(t,
+		map[string]any{
+			"gpu.0.gpu":          10.0,
+			"gpu.2.gpu":          30.0,
+			"gpu.process.2.temp": 60.0,
+			"cpu":                1.0,
+		},
+		filtered,
+	)
+}
+
+func TestVisibleGPUsFilterAll(t *testing.T) {
+	metrics := map[string]any{"gpu.0.gpu": 10.0}
+
+	assert.Equal(t, metrics, monitor.VisibleGPUs(nil).Filter(metrics))
+}
+
+func TestVisibleGPUsFilterNone(t *testing.T) {
+	metrics := map[string]any{"gpu.0.gpu": 10.0}
+
+	assert.Empty(t, monitor.VisibleGPUs{}.Filter(metrics))
+}
+
+func TestGPUMetricKeys_MatchSample(t *testing.T) {
+	histogram, err := monitor.NewPowerHistogram(monitor.DefaultPowerHistogramBoundaries)
+	require.NoError(t, err)
+	gpu := &monitor.GPU{
+		PowerHistogram: histogram,
+		PowerLimit:     monitor.NewPowerLimitTracker(monitor.DefaultPowerLimitWindow),
+	}
+	gpu.SetClient(&fakeGPUStats{gpus: 2})
+
+	metrics, err := gpu.Sample()
+	require.NoError(t, err)
+
+	assert.Contains(t, gpu.MetricKeys(), "gpu.1.temp")
+	assert.ElementsMatch(t, slices.Collect(maps.Keys(metrics)), gpu.MetricKeys())
+}
+
+func TestGPUMetricKeys_FilteredAndRenamed(t *testing.T) {
+	filter, err := monitor.NewMetricFilter([]string{"temp", "powerWatts"}, nil)
+	require.NoError(t, err)
+	gpu := &monitor.GPU{
+		MetricFilter: filter,
+		MetricNamer:  monitor.NewMetricNamer("system/", nil),
+	}
+	gpu.SetClient(&fakeGPUStats{gpus: 2})
+
+	metrics, err := gpu.Sample()
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, slices.Collect(maps.Keys(metrics)), gpu.MetricKeys())
+}
+
+func TestGPUMetricUnits_CoverSample(t *testing.T) {
+	histogram, err := monitor.NewPowerHistogram(monitor.DefaultPowerHistogramBoundaries)
+	require.NoError(t, err)
+	gpu := &monitor.GPU{
+		PowerHistogram: histogram,
+		PowerLimit:     monitor.NewPowerLimitTracker(monitor.DefaultPowerLimitWindow),
+	}
+	gpu.SetClient(&fakeGPUStats{gpus: 2})
+
+	metrics, err := gpu.Sample()
+	require.NoError(t, err)
+	units := gpu.MetricUnits()
+
+	for key := range metrics {
+		assert.NotEmpty(t, units[key], "no unit for %q", key)
+	}
+	assert.Equal(t, monitor.UnitWatts, units["gpu.1.powerWatts"])
+	assert.Equal(t, monitor.UnitCelsius, units["gpu.process.0.temp"])
+	assert.Equal(t, monitor.UnitBytes, units["gpu.0.memoryAllocatedBytes"])
+	assert.Equal(t, monitor.UnitPercent, units["gpu.0.memoryAllocated"])
+	assert.Equal(t, monitor.UnitRatio, units["gpu.0.powerLimitedFraction"])
+}
+
+func TestGPUMetricUnits_Renamed(t *testing.T) {
+	gpu := &monitor.GPU{
+		MetricNamer: monitor.NewMetricNamer("system/", map[string]string{"temp": "temperature"}),
+	}
+	gpu.SetClient(&fakeGPUStats{gpus: 1})
+
+	units := gpu.MetricUnits()
+
+	assert.Equal(t, monitor.UnitCelsius, units["system/gpu.0.temperature"])
+	assert.NotContains(t, units, "gpu.0.temp")
+}
+
+func TestGPUSample_TwoDevices(t *testing.T) {
+	gpu := &monitor.GPU{}
+	gpu.SetClient(&fakeGPUStats{gpus: 2})
+
+	metrics, err := gpu.Sample()
+
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, metrics["gpu.0.temp"])
+	assert.Equal(t, 100.0, metrics["gpu.1.powerWatts"])
+	assert.Equal(t, 100.0, metrics["gpu.process.1.memoryAllocated"])
+	assert.Equal(t, 1.0, metrics["gpu.1.smClock"])
+	assert.NotContains(t, metrics, "gpu.2.temp")
+	assert.NotContains(t, metrics, "_gpu.count")
+}
+
+func TestGPUProbe_TwoDevices(t *testing.T) {
+	gpu := &monitor.GPU{}
+	gpu.SetClient(&fakeGPUStats{gpus: 2})
+
+	info := gpu.Probe()
+
+	assert.EqualValues(t, 2, info.GetGpuCount())
+	if assert.Len(t, info.GetGpuNvidia(), 2) {
+		assert.Equal(t, "GPU 1", info.GetGpuNvidia()[1].GetName())
+	}
+}
+
+func TestGPU_IdleGate(t *testing.T) {
+	stats := &fakeGPUStats{gpus: 2, idle: true}
+	gpu := &monitor.GPU{IdleGate: monitor.NewGPUIdleGate(0, 50*time.Millisecond)}
+	gpu.SetClient(stats)
+
+	// The second idle sample is past the grace period.
+	for range 2 {
+		_, err := gpu.Sample()
+		require.NoError(t, err)
+	}
+	skipped, err := gpu.Sample()
+	require.NoError(t, err)
+	assert.Empty(t, skipped)
+	assert.Equal(t, 2, stats.statsCalls)
+
+	// The sample after the idle interval sees the process again.
+	stats.idle = false
+	time.Sleep(60 * time.Millisecond)
+	_, err = gpu.Sample()
+	require.NoError(t, err)
+	metrics, err := gpu.Sample()
+	require.NoError(t, err)
+	assert.Contains(t, metrics, "gpu.process.0.gpu")
+	assert.Equal(t, 4, stats.statsCalls)
+}
+
+func TestGPU_ClientError(t *testing.T) {
+	gpu := &monitor.GPU{}
+	gpu.SetClient(&fakeGPUStats{gpus: 2, err: errors.New("test error")})
+
+	_, err := gpu.Sample()
+
+	assert.ErrorContains(t, err, "test error")
+	assert.Nil(t, gpu.Probe())
+	assert.Nil(t, gpu.MetricKeys())
+	assert.Nil(t, gpu.MetricUnits())
+}
+
+func TestGPUStatsPath_Override(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "my_gpu_stats")
+	require.NoError(t, os.WriteFile(path, []byte{}, 0o755))
+
+	found, err := monitor.GPUStatsPath(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, path, found)
+}
+
+func TestGPUStatsPath_OverrideNotFound(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing")
+
+	_, err := monitor.GPUStatsPath(path)
+
+	var notFound *monitor.GPUStatsNotFoundError
+	require.ErrorAs(t, err, &notFound)
+	assert.Equal(t, path, notFound.Path)
+}
+
+func TestGPUStatsPath_NotNextToExecutable(t *testing.T) {
+	// The gpu_stats binary is not next to the test executable.
+	_, err := monitor.GPUStatsPath("")
+
+	var notFound *monitor.GPUStatsNotFoundError
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestGPUStatsPath_StatFails(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("paths under a file don't exist on Windows")
+	}
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, []byte{}, 0o644))
+
+	// A path under a regular file is neither found nor missing.
+	_, err := monitor.GPUStatsPath(filepath.Join(file, "gpu_stats"))
+
+	var statErr *monitor.GPUStatsStatError
+	require.ErrorAs(t, err, &statErr)
+	assert.Equal(t, filepath.Join(file, "gpu_stats"), statErr.Path)
+}
+
+func TestGPU_RestartsExitedGPUStats(t *testing.T) {
+	// The test binary acts as a gpu_stats binary that exits after each
+	// sample.
+	t.Setenv("WANDB_TEST_FAKE_GPU_STATS", "1")
+	executable, err := os.Executable()
+	require.NoError(t, err)
+
+	gpu := monitor.NewGPU(observability.NewNoOpLogger(), 0, executable)
+	require.NotNil(t, gpu)
+	gpu.RestartDelay = 0
+	defer gpu.Close()
+
+	metrics, err := gpu.Sample()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"gpu.0.temp": 60.0}, metrics)
+
+	assert.Eventually(t,
+		func() bool {
+			metrics, err := gpu.Sample()
+			return err == nil && metrics["gpu.0.temp"] == 60.0
+		},
+		5*time.Second,
+		50*time.Millisecond,
+	)
+}
+
+func TestGPU_DoesNotRestartBeforeDelay(t *testing.T) {
+	t.Setenv("WANDB_TEST_FAKE_GPU_STATS", "1")
+	executable, err := os.Executable()
+	require.NoError(t, err)
+
+	gpu := monitor.NewGPU(observability.NewNoOpLogger(), 0, executable)
+	require.NotNil(t, gpu)
+	gpu.RestartDelay = time.Hour
+	defer gpu.Close()
+
+	_, err = gpu.Sample()
+	require.NoError(t, err)
+
+	// Let gpu_stats exit.
+	time.Sleep(500 * time.Millisecond)
+	metrics, err := gpu.Sample()
+	assert.NoError(t, err)
+	assert.Empty(t, metrics)
+}
+
+// copyExecutable copies the test binary into a temporary directory, so
+// that it can act as a gpu_stats binary that can be removed.
+func copyExecutable(t *testing.T) string {
+	t.Helper()
+	executable, err := os.Executable()
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), filepath.Base(executable))
+	if os.Link(executable, path) == nil {
+		return path
+	}
+	contents, err := os.ReadFile(executable)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, contents, 0o755))
+	return path
+}
+
+func TestGPU_BacksOffFailedRestarts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("a running executable can't be removed on Windows")
+	}
+	t.Setenv("WANDB_TEST_FAKE_GPU_STATS", "1")
+	executable := copyExecutable(t)
+	var logs bytes.Buffer
+	logger := observability.NewCoreLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	gpu := monitor.NewGPU(logger, 0, executable)
+	require.NotNil(t, gpu)
+	gpu.RestartDelay = 100 * time.Millisecond
+	defer gpu.Close()
+
+	_, err := gpu.Sample()
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(executable))
+
+	// Once gpu_stats exits, restarts fail and the delay doubles.
+	var sampleErr error
+	assert.Eventually(t,
+		func() bool {
+			_, err := gpu.Sample()
+			sampleErr = errors.Join(sampleErr, err)
+			return bytes.Contains(logs.Bytes(), []byte("retry_in=400ms"))
+		},
+		5*time.Second,
+		10*time.Millisecond,
+	)
+	assert.NoError(t, sampleErr)
+	assert.Contains(t, logs.String(), "retry_in=200ms")
+
+	metrics, err := gpu.Sample()
+	assert.NoError(t, err)
+	assert.Empty(t, metrics)
+}
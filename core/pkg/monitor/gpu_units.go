@@ -0,0 +1,40 @@
+package monitor
+
+// Units of the GPU metrics, as reported by MetricUnits.
+const (
+	UnitPercent   = "percent"
+	UnitWatts     = "watts"
+	UnitBytes     = "bytes"
+	UnitCelsius   = "celsius"
+	UnitMegahertz = "MHz"
+
+	// UnitCount is for counters, like the number of memory errors.
+	UnitCount = "count"
+
+	// UnitRatio is for fractions between 0 and 1.
+	UnitRatio = "ratio"
+
+	// UnitNone is for metrics without a unit, like the PCIe link generation.
+	UnitNone = "none"
+)
+
+// nvidiaMetricUnits are the units of the metrics in nvidiaMetricNames,
+// which also apply to the process metrics of the same name.
+var nvidiaMetricUnits = map[string]string{
+	"gpu":                     UnitPercent,
+	"memory":                  UnitPercent,
+	"memoryAllocated":         UnitPercent,
+	"memoryAllocatedBytes":    UnitBytes,
+	"temp":                    UnitCelsius,
+	"powerWatts":              UnitWatts,
+	"enforcedPowerLimitWatts": UnitWatts,
+	"powerPercent":            UnitPercent,
+	"fanSpeed":                UnitPercent,
+	"encoderUtilization":      UnitPercent,
+	"graphicsClock":           UnitMegahertz,
+	"memoryClock":             UnitMegahertz,
+	"smClock":                 UnitMegahertz,
+	"pcieLinkGen":             UnitNone,
+	"correctedMemoryErrors":   UnitCount,
+	"uncorrectedMemoryErrors": UnitCount,
+}
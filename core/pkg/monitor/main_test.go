@@ -0,0 +1,119 @@
+package monitor_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	spb "github.com/wandb/wandb/core/pkg/service_go_proto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// fakeGPUStatsEnv, if set in the environment, makes the test binary act as
+// the gpu_stats binary instead of running tests.
+//
+// Its value is the number of samples to serve before exiting, or 0 to
+// serve samples until torn down.
+const fakeGPUStatsEnv = "WANDB_TEST_FAKE_GPU_STATS"
+
+func TestMain(m *testing.M) {
+	if samples, ok := os.LookupEnv(fakeGPUStatsEnv); ok {
+		runFakeGPUStatsBinary(samples)
+		return
+	}
+
+	os.Exit(m.Run())
+}
+
+// fakeGPUStatsServer serves canned metrics for one GPU.
+type fakeGPUStatsServer struct {
+	spb.UnimplementedSystemMonitorServer
+
+	// samples is the number of samples to serve before exiting, or 0.
+	samples int
+
+	// served counts the served samples.
+	served chan struct{}
+
+	// done is closed on TearDown.
+	done chan struct{}
+}
+
+func (s *fakeGPUStatsServer) GetStats(
+	ctx context.Context,
+	in *spb.GetStatsRequest,
+) (*spb.Record, error) {
+	s.served <- struct{}{}
+
+	return &spb.Record{RecordType: &spb.Record_Stats{
+		Stats: &spb.StatsRecord{Item: []*spb.StatsItem{
+			{Key: "gpu.0.temp", ValueJson: "60"},
+		}},
+	}}, nil
+}
+
+func (s *fakeGPUStatsServer) GetMetadata(
+	ctx context.Context,
+	in *spb.GetMetadataRequest,
+) (*spb.Record, error) {
+	return &spb.Record{}, nil
+}
+
+func (s *fakeGPUStatsServer) TearDown(
+	ctx context.Context,
+	in *emptypb.Empty,
+) (*emptypb.Empty, error) {
+	close(s.done)
+	return &emptypb.Empty{}, nil
+}
+
+// runFakeGPUStatsBinary implements the gpu_stats command-line interface,
+// serving canned metrics.
+func runFakeGPUStatsBinary(samples string) {
+	var portfile string
+	for i, arg := range os.Args {
+		if arg == "--portfile" && i+1 < len(os.Args) {
+			portfile = os.Args[i+1]
+		}
+	}
+
+	server := &fakeGPUStatsServer{
+		served: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	server.samples, _ = strconv.Atoi(samples)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	grpcServer := grpc.NewServer()
+	spb.RegisterSystemMonitorServer(grpcServer, server)
+	go func() { _ = grpcServer.Serve(listener) }()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	if err := os.WriteFile(portfile, []byte(strconv.Itoa(port)), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	for served := 0; server.samples == 0 || served < server.samples; {
+		select {
+		case <-server.served:
+			served++
+		case <-server.done:
+			grpcServer.GracefulStop()
+			return
+		}
+	}
+
+	// Give the last response time to be sent, then die as if crashed.
+	time.Sleep(100 * time.Millisecond)
+	os.Exit(1)
+}
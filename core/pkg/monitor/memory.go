@@ -35,6 +35,16 @@ func (m *Memory) Sample() (map[string]any, error) {
 		metrics["proc.memory.availableMB"] = float64(virtualMem.Available) / 1024 / 1024
 	}
 
+	swapMem, err := mem.SwapMemory()
+	if err != nil {
+		errs = append(errs, err)
+	} else if swapMem.Total > 0 {
+		// total system swap usage in percent
+		metrics["swap"] = swapMem.UsedPercent
+		// total system swap used in MB
+		metrics["swap.usedMB"] = float64(swapMem.Used) / 1024 / 1024
+	}
+
 	// process-related metrics
 	proc := process.Process{Pid: m.pid}
 	procMem, err := proc.MemoryInfo()
@@ -0,0 +1,34 @@
+package monitor_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wandb/wandb/core/pkg/monitor"
+)
+
+func TestMemorySample(t *testing.T) {
+	memory := monitor.NewMemory(int32(os.Getpid()))
+
+	metrics, err := memory.Sample()
+
+	require.NoError(t, err)
+	assert.Contains(t, metrics, "memory_percent")
+	assert.Contains(t, metrics, "proc.memory.availableMB")
+	assert.Contains(t, metrics, "proc.memory.rssMB")
+	assert.Contains(t, metrics, "proc.memory.percent")
+
+	swap, err := mem.SwapMemory()
+	require.NoError(t, err)
+	if swap.Total > 0 {
+		assert.Contains(t, metrics, "swap")
+		assert.Contains(t, metrics, "swap.usedMB")
+	} else {
+		assert.NotContains(t, metrics, "swap")
+		assert.NotContains(t, metrics, "swap.usedMB")
+	}
+	assert.NotContains(t, metrics, "proc.memory.swapUsedMB")
+}
@@ -0,0 +1,197 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Aggregation is how a metric's samples are combined into one value.
+type Aggregation int
+
+const (
+	// AggregationMean averages the samples. This is the default.
+	AggregationMean Aggregation = iota
+
+	// AggregationMax takes the largest sample, like for temperatures.
+	AggregationMax
+
+	// AggregationMin takes the smallest sample.
+	AggregationMin
+
+	// AggregationLast takes the most recent sample.
+	AggregationLast
+
+	// AggregationP95 takes the 95th percentile of the samples.
+	AggregationP95
+)
+
+// ParseAggregation parses an aggregation: "mean", "max", "min", "last"
+// or "p95".
+func ParseAggregation(value string) (Aggregation, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "mean":
+		return AggregationMean, nil
+	case "max":
+		return AggregationMax, nil
+	case "min":
+		return AggregationMin, nil
+	case "last":
+		return AggregationLast, nil
+	case "p95":
+		return AggregationP95, nil
+	default:
+		return AggregationMean, fmt.Errorf(
+			"monitor: invalid aggregation %q, expected mean, max, min, last or p95",
+			value,
+		)
+	}
+}
+
+// apply combines samples, ordered oldest first, into one value.
+//
+// There must be at least one sample.
+func (a Aggregation) apply(samples []float64) float64 {
+	switch a {
+	case AggregationMax:
+		return slices.Max(samples)
+	case AggregationMin:
+		return slices.Min(samples)
+	case AggregationLast:
+		return samples[len(samples)-1]
+	case AggregationP95:
+		return percentile(samples, 95)
+	default:
+		sum := 0.0
+		for _, sample := range samples {
+			sum += sample
+		}
+		return sum / float64(len(samples))
+	}
+}
+
+// percentile returns the nearest-rank percentile of the samples, which is
+// always one of the samples.
+//
+// There must be at least one sample.
+func percentile(samples []float64, p float64) float64 {
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// MetricStatistic is a statistic of a metric's samples that's reported
+// along with the metric, under the metric's key followed by a suffix, like
+// "gpu.0.gpu.max" or "gpu.0.gpu.p95".
+type MetricStatistic struct {
+	// Name is the suffix of the statistic's key, like "max" or "p95".
+	Name string
+
+	// Percentile is the nearest-rank percentile of the samples, greater
+	// than 0 and at most 100. The maximum is the 100th percentile.
+	Percentile float64
+}
+
+// ParseMetricStatistics parses a comma-separated list of statistics like
+// "max,p95". Each is either "max" or "p" followed by a percentile.
+func ParseMetricStatistics(spec string) ([]MetricStatistic, error) {
+	var statistics []MetricStatistic
+
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		if name == "max" {
+			statistics = append(statistics, MetricStatistic{name, 100})
+			continue
+		}
+
+		digits, ok := strings.CutPrefix(name, "p")
+		p, err := strconv.ParseFloat(digits, 64)
+		if !ok || err != nil || !(p > 0 && p <= 100) {
+			return nil, fmt.Errorf(
+				"monitor: invalid metric statistic %q, expected max or a"+
+					" percentile like p95",
+				name,
+			)
+		}
+		statistics = append(statistics, MetricStatistic{name, p})
+	}
+
+	return statistics, nil
+}
+
+// metricAggregationRule is the aggregation of the metrics whose keys
+// match a pattern.
+type metricAggregationRule struct {
+	pattern     string
+	aggregation Aggregation
+}
+
+// MetricAggregations selects how each metric is aggregated by its key.
+//
+// Patterns are globs as in MetricFilter. The first matching rule applies,
+// and metrics that match no rule are averaged.
+type MetricAggregations struct {
+	rules []metricAggregationRule
+}
+
+// ParseMetricAggregations parses a comma-separated list of rules like
+// "gpu.*.temp=max,gpu.*.powerWatts=max".
+func ParseMetricAggregations(spec string) (*MetricAggregations, error) {
+	aggregations := &MetricAggregations{}
+
+	for _, rule := range strings.Split(spec, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		pattern, name, ok := strings.Cut(rule, "=")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf(
+				"monitor: invalid metric aggregation %q, expected pattern=function",
+				rule,
+			)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf(
+				"monitor: invalid metric pattern %q: %v",
+				pattern,
+				err,
+			)
+		}
+		aggregation, err := ParseAggregation(name)
+		if err != nil {
+			return nil, err
+		}
+
+		aggregations.rules = append(aggregations.rules,
+			metricAggregationRule{pattern, aggregation})
+	}
+
+	return aggregations, nil
+}
+
+// For returns the aggregation of the metric with the given key.
+//
+// A nil MetricAggregations averages every metric.
+func (a *MetricAggregations) For(key string) Aggregation {
+	if a == nil {
+		return AggregationMean
+	}
+
+	for _, rule := range a.rules {
+		if ok, _ := path.Match(rule.pattern, key); ok {
+			return rule.aggregation
+		}
+	}
+	return AggregationMean
+}
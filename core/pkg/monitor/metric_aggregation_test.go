@@ -0,0 +1,163 @@
+package monitor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wandb/wandb/core/pkg/monitor"
+)
+
+// aggregateSamples aggregates a shared set of samples of two GPUs with
+// the given rules.
+func aggregateSamples(t *testing.T, spec string) map[string]any {
+	t.Helper()
+	aggregations, err := monitor.ParseMetricAggregations(spec)
+	require.NoError(t, err)
+	w := monitor.NewMetricWindow(time.Minute)
+	w.SetAggregations(aggregations)
+	start := time.Now()
+
+	samples := []map[string]any{
+		{"gpu.0.temp": 60.0, "gpu.0.gpu": 10.0, "gpu.1.temp": 40.0, "gpu.1.gpu": 90.0},
+		{"gpu.0.temp": 85.0, "gpu.0.gpu": 50.0, "gpu.1.temp": 45.0, "gpu.1.gpu": 70.0},
+		{"gpu.0.temp": 70.0, "gpu.0.gpu": 30.0, "gpu.1.temp": 50.0, "gpu.1.gpu": 20.0},
+	}
+	now := start
+	for i, sample := range samples {
+		now = start.Add(time.Duration(i) * time.Second)
+		w.Add(now, sample)
+	}
+
+	return w.Aggregate(now, map[string]any{
+		"gpu.0.temp": 70.0,
+		"gpu.0.gpu":  30.0,
+		"gpu.1.temp": 50.0,
+		"gpu.1.gpu":  20.0,
+	})
+}
+
+func TestMetricAggregations_PerPattern(t *testing.T) {
+	metrics := aggregateSamples(t, "gpu.*.temp=max, gpu.*.gpu=mean")
+
+	assert.Equal(t,
+		map[string]any{
+			"gpu.0.temp": 85.0,
+			"gpu.0.gpu":  30.0,
+			"gpu.1.temp": 50.0,
+			"gpu.1.gpu":  60.0,
+		},
+		metrics)
+}
+
+func TestMetricAggregations_DefaultsToMean(t *testing.T) {
+	metrics := aggregateSamples(t, "")
+
+	assert.Equal(t,
+		map[string]any{
+			"gpu.0.temp": 71.66666666666667,
+			"gpu.0.gpu":  30.0,
+			"gpu.1.temp": 45.0,
+			"gpu.1.gpu":  60.0,
+		},
+		metrics)
+}
+
+func TestMetricAggregations_FirstMatchApplies(t *testing.T) {
+	metrics := aggregateSamples(t, "gpu.0.*=last,gpu.*.gpu=min,gpu.*=p95")
+
+	assert.Equal(t,
+		map[string]any{
+			"gpu.0.temp": 70.0,
+			"gpu.0.gpu":  30.0,
+			"gpu.1.temp": 50.0,
+			"gpu.1.gpu":  20.0,
+		},
+		metrics)
+}
+
+func TestAggregation_P95(t *testing.T) {
+	aggregations, err := monitor.ParseMetricAggregations("x=p95")
+	require.NoError(t, err)
+	w := monitor.NewMetricWindow(time.Minute)
+	w.SetAggregations(aggregations)
+	now := time.Now()
+
+	for i := 1; i <= 20; i++ {
+		w.Add(now, map[string]any{"x": float64(i)})
+	}
+
+	assert.Equal(t, map[string]any{"x": 19.0}, w.Aggregate(now, map[string]any{"x": 0.0}))
+}
+
+func TestParseMetricAggregations_Invalid(t *testing.T) {
+	_, err := monitor.ParseMetricAggregations("gpu.*.temp")
+	assert.ErrorContains(t, err, "expected pattern=function")
+
+	_, err = monitor.ParseMetricAggregations("gpu.*.temp=median")
+	assert.ErrorContains(t, err, "invalid aggregation")
+
+	_, err = monitor.ParseMetricAggregations("gpu.[.temp=max")
+	assert.ErrorContains(t, err, "invalid metric pattern")
+}
+
+func TestMetricStatistics(t *testing.T) {
+	statistics, err := monitor.ParseMetricStatistics("max, p95, P50")
+	require.NoError(t, err)
+	w := monitor.NewMetricWindow(time.Minute)
+	w.SetStatistics(statistics)
+	now := time.Now()
+
+	// Two spikes to 100% among 20 samples average to 10%.
+	for i := 1; i <= 20; i++ {
+		utilization := 0.0
+		if i%10 == 0 {
+			utilization = 100.0
+		}
+		w.Add(now, map[string]any{"gpu.0.gpu": utilization, "gpu.0.temp": float64(i)})
+	}
+
+	assert.Equal(t,
+		map[string]any{
+			"gpu.0.gpu":       10.0,
+			"gpu.0.gpu.max":   100.0,
+			"gpu.0.gpu.p95":   100.0,
+			"gpu.0.gpu.p50":   0.0,
+			"gpu.0.temp":      10.5,
+			"gpu.0.temp.max":  20.0,
+			"gpu.0.temp.p95":  19.0,
+			"gpu.0.temp.p50":  10.0,
+			"gpu.0.memoryMHz": "n/a",
+		},
+		w.Aggregate(now, map[string]any{
+			"gpu.0.gpu":       0.0,
+			"gpu.0.temp":      0.0,
+			"gpu.0.memoryMHz": "n/a",
+		}))
+}
+
+func TestMetricStatistics_NoneByDefault(t *testing.T) {
+	statistics, err := monitor.ParseMetricStatistics("")
+	require.NoError(t, err)
+	assert.Empty(t, statistics)
+
+	w := monitor.NewMetricWindow(time.Minute)
+	w.SetStatistics(statistics)
+	now := time.Now()
+	w.Add(now, map[string]any{"gpu.0.gpu": 40.0})
+
+	assert.Equal(t,
+		map[string]any{"gpu.0.gpu": 40.0},
+		w.Aggregate(now, map[string]any{"gpu.0.gpu": 0.0}))
+}
+
+func TestParseMetricStatistics_Invalid(t *testing.T) {
+	for _, spec := range []string{"mean", "p0", "p101", "pmax", "95"} {
+		t.Run(spec, func(t *testing.T) {
+			_, err := monitor.ParseMetricStatistics(spec)
+
+			assert.ErrorContains(t, err, "invalid metric statistic")
+		})
+	}
+}
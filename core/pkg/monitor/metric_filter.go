@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"fmt"
+	"path"
+)
+
+// MetricFilter selects which metrics are reported by their keys.
+//
+// Patterns are globs as accepted by path.Match, such as "gpu.*.temp".
+// A '*' matches any sequence of characters, including dots, so "gpu.*"
+// matches every GPU metric.
+type MetricFilter struct {
+	// allow is the patterns of the metrics to keep.
+	//
+	// If empty, all metrics that aren't denied are kept.
+	allow []string
+
+	// deny is the patterns of the metrics to drop.
+	//
+	// It takes precedence over allow.
+	deny []string
+}
+
+// NewMetricFilter returns a filter that keeps metrics matching an allow
+// pattern and drops those matching a deny pattern.
+//
+// Returns an error if any pattern is malformed.
+func NewMetricFilter(allow, deny []string) (*MetricFilter, error) {
+	for _, pattern := range append(append([]string(nil), allow...), deny...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf(
+				"monitor: invalid metric pattern %q: %v",
+				pattern,
+				err,
+			)
+		}
+	}
+
+	return &MetricFilter{
+		allow: append([]string(nil), allow...),
+		deny:  append([]string(nil), deny...),
+	}, nil
+}
+
+// Keep reports whether the metric with the given key passes the filter.
+//
+// A nil filter keeps all metrics.
+func (f *MetricFilter) Keep(key string) bool {
+	if f == nil {
+		return true
+	}
+
+	if matchesAny(f.deny, key) {
+		return false
+	}
+	return len(f.allow) == 0 || matchesAny(f.allow, key)
+}
+
+// Filter removes the metrics that don't pass the filter.
+func (f *MetricFilter) Filter(metrics map[string]any) map[string]any {
+	if f == nil {
+		return metrics
+	}
+
+	for key := range metrics {
+		if !f.Keep(key) {
+			delete(metrics, key)
+		}
+	}
+	return metrics
+}
+
+// matchesAny reports whether the key matches one of the patterns.
+//
+// The patterns are validated by NewMetricFilter.
+func matchesAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package monitor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wandb/wandb/core/pkg/monitor"
+)
+
+func gpuMetrics() map[string]any {
+	return map[string]any{
+		"gpu.0.gpu":             50,
+		"gpu.0.memoryAllocated": 10.5,
+		"gpu.0.temp":            60,
+		"gpu.1.gpu":             70,
+		"gpu.1.temp":            65,
+		"gpu.process.0.temp":    60,
+	}
+}
+
+func TestMetricFilter_Allowlist(t *testing.T) {
+	f, err := monitor.NewMetricFilter(
+		[]string{"gpu.*.gpu", "gpu.*.memoryAllocated"},
+		nil,
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		map[string]any{
+			"gpu.0.gpu":             50,
+			"gpu.0.memoryAllocated": 10.5,
+			"gpu.1.gpu":             70,
+		},
+		f.Filter(gpuMetrics()))
+}
+
+func TestMetricFilter_Denylist(t *testing.T) {
+	f, err := monitor.NewMetricFilter(nil, []string{"gpu.*.temp"})
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		map[string]any{
+			"gpu.0.gpu":             50,
+			"gpu.0.memoryAllocated": 10.5,
+			"gpu.1.gpu":             70,
+		},
+		f.Filter(gpuMetrics()))
+}
+
+func TestMetricFilter_DenyTakesPrecedence(t *testing.T) {
+	f, err := monitor.NewMetricFilter([]string{"gpu.0.*"}, []string{"gpu.0.temp"})
+	require.NoError(t, err)
+
+	assert.True(t, f.Keep("gpu.0.gpu"))
+	assert.False(t, f.Keep("gpu.0.temp"))
+	assert.False(t, f.Keep("gpu.1.gpu"))
+}
+
+func TestMetricFilter_NilKeepsAll(t *testing.T) {
+	var f *monitor.MetricFilter
+
+	assert.Equal(t, gpuMetrics(), f.Filter(gpuMetrics()))
+}
+
+func TestMetricFilter_InvalidPattern(t *testing.T) {
+	_, err := monitor.NewMetricFilter([]string{"gpu.[0"}, nil)
+
+	assert.ErrorContains(t, err, "gpu.[0")
+}
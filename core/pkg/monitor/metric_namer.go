@@ -0,0 +1,107 @@
+package monitor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// gpuMetricKey splits keys like "gpu.0.temp" and "gpu.process.0.temp" into
+// the GPU part and the metric name.
+var gpuMetricKey = regexp.MustCompile(`^(gpu\.(?:process\.)?\d+\.)(.+)$`)
+
+// MetricNamer maps the keys of GPU metrics to the names they're reported
+// under, so that they can match existing dashboards.
+//
+// A metric's name is the part of its key after the GPU index, like "temp"
+// in "gpu.0.temp" and "gpu.process.0.temp". Renaming a name renames it for
+// every GPU, in both the device and the process variants. Derived metrics
+// like "gpu.0.powerWatts.histogram.0-50" are renamed along with their base
+// metric.
+type MetricNamer struct {
+	// prefix is prepended to every key, as in "system/".
+	prefix string
+
+	// renames maps metric names to their new names.
+	renames map[string]string
+}
+
+// NewMetricNamer returns a namer that renames metrics and then prepends
+// prefix to their keys.
+func NewMetricNamer(prefix string, renames map[string]string) *MetricNamer {
+	copied := make(map[string]string, len(renames))
+	for from, to := range renames {
+		copied[from] = to
+	}
+
+	return &MetricNamer{prefix: prefix, renames: copied}
+}
+
+// ParseMetricRenames parses a comma-separated list of renames like
+// "powerWatts=power_w,temp=temperature".
+func ParseMetricRenames(spec string) (map[string]string, error) {
+	renames := make(map[string]string)
+
+	for _, rule := range strings.Split(spec, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		from, to, ok := strings.Cut(rule, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf(
+				"monitor: invalid metric rename %q, expected name=new_name",
+				rule,
+			)
+		}
+		renames[from] = to
+	}
+
+	return renames, nil
+}
+
+// Key returns the key under which the metric is reported.
+//
+// A nil namer returns the key unchanged.
+func (n *MetricNamer) Key(key string) string {
+	if n == nil {
+		return key
+	}
+
+	if match := gpuMetricKey.FindStringSubmatch(key); match != nil {
+		key = match[1] + n.rename(match[2])
+	} else {
+		key = n.rename(key)
+	}
+
+	return n.prefix + key
+}
+
+// Apply renames the keys of a set of metrics.
+func (n *MetricNamer) Apply(metrics map[string]any) map[string]any {
+	if n == nil {
+		return metrics
+	}
+
+	renamed := make(map[string]any, len(metrics))
+	for key, value := range metrics {
+		renamed[n.Key(key)] = value
+	}
+	return renamed
+}
+
+// rename renames the first component of a metric name.
+func (n *MetricNamer) rename(name string) string {
+	base, rest, hasRest := strings.Cut(name, ".")
+
+	newBase, ok := n.renames[base]
+	if !ok {
+		return name
+	}
+	if hasRest {
+		return newBase + "." + rest
+	}
+	return newBase
+}
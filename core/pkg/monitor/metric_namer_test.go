@@ -0,0 +1,63 @@
+package monitor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wandb/wandb/core/pkg/monitor"
+)
+
+func TestMetricNamer_Prefix(t *testing.T) {
+	n := monitor.NewMetricNamer("system/", nil)
+
+	assert.Equal(t,
+		map[string]any{
+			"system/gpu.0.gpu":             50,
+			"system/gpu.0.memoryAllocated": 10.5,
+			"system/gpu.0.temp":            60,
+			"system/gpu.1.gpu":             70,
+			"system/gpu.1.temp":            65,
+			"system/gpu.process.0.temp":    60,
+		},
+		n.Apply(gpuMetrics()))
+}
+
+func TestMetricNamer_Rename(t *testing.T) {
+	n := monitor.NewMetricNamer("", map[string]string{"powerWatts": "power_w"})
+
+	assert.Equal(t, "gpu.0.power_w", n.Key("gpu.0.powerWatts"))
+	assert.Equal(t, "gpu.process.3.power_w", n.Key("gpu.process.3.powerWatts"))
+	assert.Equal(t,
+		"gpu.0.power_w.histogram.0-50",
+		n.Key("gpu.0.powerWatts.histogram.0-50"))
+	assert.Equal(t, "gpu.0.powerPercent", n.Key("gpu.0.powerPercent"))
+}
+
+func TestMetricNamer_PrefixAndRename(t *testing.T) {
+	n := monitor.NewMetricNamer("system/", map[string]string{"temp": "temperature"})
+
+	assert.Equal(t, "system/gpu.1.temperature", n.Key("gpu.1.temp"))
+	assert.Equal(t, "system/gpu.process.1.temperature", n.Key("gpu.process.1.temp"))
+}
+
+func TestMetricNamer_NilKeepsKeys(t *testing.T) {
+	var n *monitor.MetricNamer
+
+	assert.Equal(t, gpuMetrics(), n.Apply(gpuMetrics()))
+}
+
+func TestParseMetricRenames(t *testing.T) {
+	renames, err := monitor.ParseMetricRenames("powerWatts=power_w, temp = temperature,")
+
+	require.NoError(t, err)
+	assert.Equal(t,
+		map[string]string{"powerWatts": "power_w", "temp": "temperature"},
+		renames)
+}
+
+func TestParseMetricRenames_Invalid(t *testing.T) {
+	_, err := monitor.ParseMetricRenames("powerWatts")
+
+	assert.ErrorContains(t, err, `invalid metric rename "powerWatts"`)
+}
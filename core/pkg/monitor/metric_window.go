@@ -0,0 +1,139 @@
+package monitor
+
+import (
+	"maps"
+	"sync"
+	"time"
+)
+
+// timedValue is a metric sample and when it was taken.
+type timedValue struct {
+	time  time.Time
+	value float64
+}
+
+// MetricWindow aggregates each metric over its samples from a recent
+// window of time, like the last 15 seconds.
+//
+// Metrics are averaged unless their aggregation is configured otherwise
+// with SetAggregations. SetStatistics adds other statistics of the samples.
+//
+// This keeps a run's early samples, like those from while it was idle
+// before training started, from affecting the reported values for longer
+// than the window.
+type MetricWindow struct {
+	mu sync.Mutex
+
+	// window is how far back samples are included in the aggregate.
+	window time.Duration
+
+	// aggregations selects how each metric's samples are combined.
+	//
+	// If nil, they are averaged.
+	aggregations *MetricAggregations
+
+	// statistics are reported for each metric along with its aggregate.
+	statistics []MetricStatistic
+
+	// samples are each metric's samples within the window, oldest first.
+	samples map[string][]timedValue
+}
+
+// NewMetricWindow returns a rolling window of the given duration.
+func NewMetricWindow(window time.Duration) *MetricWindow {
+	return &MetricWindow{
+		window:  window,
+		samples: make(map[string][]timedValue),
+	}
+}
+
+// SetAggregations sets how each metric's samples are combined.
+func (w *MetricWindow) SetAggregations(aggregations *MetricAggregations) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.aggregations = aggregations
+}
+
+// SetStatistics sets the statistics reported for each metric along with
+// its aggregate.
+func (w *MetricWindow) SetStatistics(statistics []MetricStatistic) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.statistics = statistics
+}
+
+// Add records the numeric metrics sampled at the given time.
+//
+// Other metrics are ignored.
+func (w *MetricWindow) Add(now time.Time, metrics map[string]any) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for key, value := range metrics {
+		number, ok := value.(float64)
+		if !ok {
+			continue
+		}
+		w.samples[key] = append(w.samples[key], timedValue{now, number})
+	}
+	w.evict(now)
+}
+
+// Aggregate replaces each numeric metric by its aggregate over the window
+// ending at now, and adds the metric's statistics.
+//
+// Metrics without samples in the window are left unchanged.
+func (w *MetricWindow) Aggregate(
+	now time.Time,
+	metrics map[string]any,
+) map[string]any {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.evict(now)
+	statistics := make(map[string]any)
+	for key := range metrics {
+		samples := w.samples[key]
+		if len(samples) == 0 {
+			continue
+		}
+
+		values := make([]float64, len(samples))
+		for i, sample := range samples {
+			values[i] = sample.value
+		}
+		metrics[key] = w.aggregations.For(key).apply(values)
+
+		for _, statistic := range w.statistics {
+			statistics[key+"."+statistic.Name] = percentile(
+				values, statistic.Percentile)
+		}
+	}
+
+	maps.Copy(metrics, statistics)
+	return metrics
+}
+
+// evict drops the samples that are older than the window.
+//
+// The caller must hold mu.
+func (w *MetricWindow) evict(now time.Time) {
+	cutoff := now.Add(-w.window)
+
+	for key, samples := range w.samples {
+		first := 0
+		for first < len(samples) && samples[first].time.Before(cutoff) {
+			first++
+		}
+
+		switch {
+		case first == len(samples):
+			delete(w.samples, key)
+		case first > 0:
+			// Copy to release the evicted samples' memory over time.
+			w.samples[key] = append(samples[:0:0], samples[first:]...)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package monitor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wandb/wandb/core/pkg/monitor"
+)
+
+func TestMetricWindow_ExcludesOldSamples(t *testing.T) {
+	w := monitor.NewMetricWindow(15 * time.Second)
+	start := time.Now()
+
+	// Idle samples before training started.
+	w.Add(start, map[string]any{"gpu.0.gpu": 0.0})
+	w.Add(start.Add(5*time.Second), map[string]any{"gpu.0.gpu": 0.0})
+	// Busy samples.
+	w.Add(start.Add(20*time.Second), map[string]any{"gpu.0.gpu": 90.0})
+	now := start.Add(25 * time.Second)
+	w.Add(now, map[string]any{"gpu.0.gpu": 100.0})
+
+	assert.Equal(t,
+		map[string]any{"gpu.0.gpu": 95.0},
+		w.Aggregate(now, map[string]any{"gpu.0.gpu": 100.0}))
+}
+
+func TestMetricWindow_AveragesSamplesInWindow(t *testing.T) {
+	w := monitor.NewMetricWindow(time.Minute)
+	start := time.Now()
+
+	w.Add(start, map[string]any{"gpu.0.temp": 60.0, "gpu.0.name": "A100"})
+	w.Add(start.Add(time.Second), map[string]any{"gpu.0.temp": 70.0})
+
+	assert.Equal(t,
+		map[string]any{"gpu.0.temp": 65.0, "gpu.0.name": "A100"},
+		w.Aggregate(
+			start.Add(time.Second),
+			map[string]any{"gpu.0.temp": 70.0, "gpu.0.name": "A100"},
+		))
+}
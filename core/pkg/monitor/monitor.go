@@ -12,6 +12,7 @@ import (
 
 	"github.com/wandb/wandb/core/internal/observability"
 	"github.com/wandb/wandb/core/internal/runwork"
+	wbsettings "github.com/wandb/wandb/core/internal/settings"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	spb "github.com/wandb/wandb/core/pkg/service_go_proto"
@@ -19,6 +20,10 @@ import (
 
 const (
 	defaultSamplingInterval = 10.0 * time.Second
+
+	// DefaultPrometheusExporterAddress is where the Prometheus exporter
+	// serves metrics if it's enabled without an address.
+	DefaultPrometheusExporterAddress = "127.0.0.1:9464"
 )
 
 // State definitions for the SystemMonitor.
@@ -36,6 +41,30 @@ type Asset interface {
 	Probe() *spb.MetadataRequest
 }
 
+// IntervalAsset is an Asset with its own sampling interval.
+//
+// Assets that don't implement it, or that return a non-positive interval,
+// are sampled at the monitor's sampling interval.
+type IntervalAsset interface {
+	Asset
+	SampleInterval() time.Duration
+}
+
+// MetricKeysAsset is an Asset that can list the keys of the metrics it
+// reports before taking any samples, for example so that dashboard panels
+// can be created ahead of time.
+type MetricKeysAsset interface {
+	Asset
+	MetricKeys() []string
+}
+
+// MetricUnitsAsset is an Asset that can tell the unit of each metric it
+// reports, like "watts", so that consumers can render axes correctly.
+type MetricUnitsAsset interface {
+	Asset
+	MetricUnits() map[string]string
+}
+
 // SystemMonitor is responsible for monitoring system metrics across various assets.
 type SystemMonitor struct {
 	// The context for the system monitor
@@ -65,6 +94,20 @@ type SystemMonitor struct {
 
 	// A logger for internal debug logging.
 	logger *observability.CoreLogger
+
+	// exporter, if set, receives every sample for scraping by Prometheus.
+	exporter *PrometheusExporter
+
+	// exporterAddr, if set, is the address at which the monitor serves the
+	// exporter's metrics while it runs.
+	exporterAddr string
+
+	// pending holds the samples that may have been dropped because the
+	// monitor was stopped while handing them to the run.
+	pending pendingSamples
+
+	// onFlush, if set, receives the final flush when the monitor stops.
+	onFlush func(map[string]float64)
 }
 
 // NewSystemMonitor initializes and returns a new SystemMonitor instance.
@@ -104,57 +147,34 @@ func NewSystemMonitor(
 		return sm
 	}
 
+	monitorSettings := wbsettings.From(settings)
+
 	// Initialize the assets to monitor
-	sm.InitializeAssets(settings)
+	sm.InitializeAssets(settings, monitorSettings.GetDisabledMonitors()...)
+
+	if monitorSettings.GetPrometheusExporter() {
+		sm.exporter = NewPrometheusExporter()
+		sm.exporterAddr = monitorSettings.GetPrometheusExporterAddress()
+		if sm.exporterAddr == "" {
+			sm.exporterAddr = DefaultPrometheusExporterAddress
+		}
+	}
 
 	return sm
 }
 
-// initializeAssets sets up the assets to be monitored based on the provided settings.
-func (sm *SystemMonitor) InitializeAssets(settings *spb.Settings) {
-	pid := settings.XStatsPid.GetValue()
-	diskPaths := settings.XStatsDiskPaths.GetValue()
-	samplingInterval := settings.XStatsSamplingInterval.GetValue()
-	neuronMonitorConfigPath := settings.XStatsNeuronMonitorConfigPath.GetValue()
-
-	// assets to be monitored.
-	if cpu := NewCPU(pid); cpu != nil {
-		sm.assets = append(sm.assets, cpu)
-	}
-	if disk := NewDisk(diskPaths); disk != nil {
-		sm.assets = append(sm.assets, disk)
-	}
-	if memory := NewMemory(pid); memory != nil {
-		sm.assets = append(sm.assets, memory)
-	}
-	if network := NewNetwork(); network != nil {
-		sm.assets = append(sm.assets, network)
-	}
-	if gpu := NewGPU(pid); gpu != nil {
-		sm.assets = append(sm.assets, gpu)
-	}
-	if gpu := NewGPUAMD(sm.logger); gpu != nil {
-		sm.assets = append(sm.assets, gpu)
-	}
-	if tpu := NewTPU(); tpu != nil {
-		sm.assets = append(sm.assets, tpu)
-	}
-	if slurm := NewSLURM(); slurm != nil {
-		sm.assets = append(sm.assets, slurm)
-	}
-	if trainium := NewTrainium(sm.logger, pid, samplingInterval, neuronMonitorConfigPath); trainium != nil {
-		sm.assets = append(sm.assets, trainium)
-	}
+// InitializeAssets sets up the assets to be monitored based on the provided settings.
+//
+// Assets named in disabled are not monitored.
+func (sm *SystemMonitor) InitializeAssets(settings *spb.Settings, disabled ...string) {
+	sm.assets = append(sm.assets, NewAssetSet(sm.logger, settings, disabled)...)
+}
 
-	// OpenMetrics endpoints to monitor.
-	if endpoints := settings.XStatsOpenMetricsEndpoints.GetValue(); endpoints != nil {
-		for name, url := range endpoints {
-			filters := settings.XStatsOpenMetricsFilters
-			if om := NewOpenMetrics(sm.logger, name, url, filters, nil); om != nil {
-				sm.assets = append(sm.assets, om)
-			}
-		}
-	}
+// AddAsset adds an asset to monitor.
+//
+// It must be called before Start.
+func (sm *SystemMonitor) AddAsset(asset Asset) {
+	sm.assets = append(sm.assets, asset)
 }
 
 // makeStatsRecord constructs a StatsRecord protobuf message from the provided stats map and timestamp.
@@ -196,6 +216,21 @@ func makeMetadataRecord(metadata *spb.MetadataRequest) *spb.Record {
 	}
 }
 
+// ExportTo makes the monitor report every sample to the exporter.
+//
+// It must be called before Start.
+func (sm *SystemMonitor) ExportTo(exporter *PrometheusExporter) {
+	sm.exporter = exporter
+}
+
+// FlushTo makes the monitor pass the result of FlushFinal to the callback
+// when it stops, if there is anything to flush.
+//
+// It must be called before Start.
+func (sm *SystemMonitor) FlushTo(callback func(map[string]float64)) {
+	sm.onFlush = callback
+}
+
 // GetState returns the current state of the SystemMonitor.
 func (sm *SystemMonitor) GetState() int32 {
 	return sm.state.Load()
@@ -234,6 +269,11 @@ func (sm *SystemMonitor) Start() {
 	}
 
 	sm.logger.Info("Starting system monitor")
+	if sm.exporterAddr != "" {
+		if err := sm.exporter.Start(sm.exporterAddr); err != nil {
+			sm.logger.Warn("monitor: not exporting metrics", "error", err)
+		}
+	}
 	// start monitoring the assets
 	for _, asset := range sm.assets {
 		sm.wg.Add(1)
@@ -296,8 +336,13 @@ func (sm *SystemMonitor) monitorAsset(asset Asset) {
 		}
 	}()
 
-	// Create a ticker that fires every `samplingInterval` seconds
-	ticker := time.NewTicker(sm.samplingInterval)
+	// Create a ticker that fires every `samplingInterval` seconds, unless
+	// the asset has its own interval
+	samplingInterval := sm.samplingInterval
+	if a, ok := asset.(IntervalAsset); ok && a.SampleInterval() > 0 {
+		samplingInterval = a.SampleInterval()
+	}
+	ticker := time.NewTicker(samplingInterval)
 	defer ticker.Stop()
 
 	for {
@@ -337,6 +382,10 @@ func (sm *SystemMonitor) monitorAsset(asset Asset) {
 				}
 			}
 
+			if sm.exporter != nil {
+				sm.exporter.Update(metrics)
+			}
+
 			// publish metrics
 			sm.extraWork.AddWorkOrCancel(
 				sm.ctx.Done(),
@@ -344,6 +393,14 @@ func (sm *SystemMonitor) monitorAsset(asset Asset) {
 					makeStatsRecord(metrics, ts),
 				),
 			)
+
+			// The record is dropped if the monitor was stopped before the
+			// run accepted it, in which case it's kept for FlushFinal.
+			// It may also have been accepted just before, so a flushed
+			// sample can be a duplicate.
+			if sm.ctx.Err() != nil {
+				sm.pending.Add(metrics)
+			}
 		}
 	}
 
@@ -360,6 +417,20 @@ func (sm *SystemMonitor) GetBuffer() map[string][]Measurement {
 	return sm.buffer.GetMeasurements()
 }
 
+// FlushFinal returns the average of each numeric metric over the samples
+// that may not have reached the run because the monitor was stopped while
+// publishing them, like on SIGTERM, and forgets them.
+//
+// It doesn't block, so it can be called from a signal handler. Samples are
+// only complete once Finish returns. Returns nil if there is nothing to
+// flush.
+func (sm *SystemMonitor) FlushFinal() map[string]float64 {
+	if sm == nil {
+		return nil
+	}
+	return sm.pending.AggregateAndClear()
+}
+
 // Finish stops the monitoring process and performs necessary cleanup.
 //
 // NOTE: asset.Close is a potentially expensive operation.
@@ -377,6 +448,16 @@ func (sm *SystemMonitor) Finish() {
 	sm.cancel()
 	// wait for all assets to stop monitoring
 	sm.wg.Wait()
+	// stop serving metrics if the monitor started the exporter
+	if sm.exporterAddr != "" {
+		sm.exporter.Close()
+	}
+	// hand over the samples that couldn't be published
+	if sm.onFlush != nil {
+		if final := sm.FlushFinal(); len(final) > 0 {
+			sm.onFlush(final)
+		}
+	}
 	// close the assets, if they require any cleanup
 	for _, asset := range sm.assets {
 		if closer, ok := asset.(interface{ Close() }); ok {
@@ -1,13 +1,23 @@
 package monitor_test
 
 import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/wandb/wandb/core/internal/observability"
+	"github.com/wandb/wandb/core/internal/runwork"
 	"github.com/wandb/wandb/core/internal/runworktest"
 	"github.com/wandb/wandb/core/pkg/monitor"
 	spb "github.com/wandb/wandb/core/pkg/service_go_proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func newTestSystemMonitor() *monitor.SystemMonitor {
@@ -37,6 +47,98 @@ func TestSystemMonitor_BasicStateTransitions(t *testing.T) {
 	assert.Equal(t, monitor.StateStopped, sm.GetState())
 }
 
+func TestSystemMonitor_DisabledMonitors(t *testing.T) {
+	t.Setenv("WANDB_DISABLED_MONITORS", "cpu,disk,network,gpu,tpu,slurm,trainium")
+	sm := monitor.NewSystemMonitor(
+		observability.NewNoOpLogger(),
+		&spb.Settings{
+			XStatsPid:              wrapperspb.Int32(int32(os.Getpid())),
+			XStatsSamplingInterval: wrapperspb.Double(0.01),
+			XStatsBufferSize:       wrapperspb.Int32(-1),
+		},
+		runworktest.New(),
+	)
+
+	sm.Start()
+	assert.Eventually(t,
+		func() bool { return len(sm.GetBuffer()) > 0 },
+		time.Second, time.Millisecond)
+	sm.Finish()
+
+	buffer := sm.GetBuffer()
+	assert.Contains(t, buffer, "memory_percent")
+	assert.NotContains(t, buffer, "cpu")
+	assert.NotContains(t, buffer, "disk./.usagePercent")
+}
+
+func TestSystemMonitor_PrometheusExporter(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	t.Setenv("WANDB_PROMETHEUS_EXPORTER", "true")
+	t.Setenv("WANDB_PROMETHEUS_EXPORTER_ADDRESS", addr)
+	t.Setenv("WANDB_DISABLED_MONITORS", "cpu,disk,network,gpu,tpu,slurm,trainium")
+	sm := monitor.NewSystemMonitor(
+		observability.NewNoOpLogger(),
+		&spb.Settings{
+			XStatsPid:              wrapperspb.Int32(int32(os.Getpid())),
+			XStatsSamplingInterval: wrapperspb.Double(0.01),
+		},
+		runworktest.New(),
+	)
+
+	sm.Start()
+	scrape := func() (string, error) {
+		resp, err := http.Get("http://" + addr + "/metrics")
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+	assert.EventuallyWithT(t, func(t *assert.CollectT) {
+		body, err := scrape()
+		assert.NoError(t, err)
+		assert.Contains(t, body, "memory_percent ")
+	}, time.Second, 10*time.Millisecond)
+	sm.Finish()
+
+	_, err = scrape()
+	assert.Error(t, err)
+}
+
+func TestSystemMonitor_ResumesGPUSamplingAfterRestart(t *testing.T) {
+	// The test binary acts as a gpu_stats binary that exits after each
+	// sample.
+	t.Setenv("WANDB_TEST_FAKE_GPU_STATS", "1")
+	executable, err := os.Executable()
+	require.NoError(t, err)
+	gpu := monitor.NewGPU(observability.NewNoOpLogger(), 0, executable)
+	require.NotNil(t, gpu)
+	gpu.RestartDelay = 200 * time.Millisecond
+	sm := monitor.NewSystemMonitor(
+		observability.NewNoOpLogger(),
+		&spb.Settings{
+			XDisableStats:          wrapperspb.Bool(true),
+			XStatsSamplingInterval: wrapperspb.Double(0.02),
+			XStatsBufferSize:       wrapperspb.Int32(-1),
+		},
+		runworktest.New(),
+	)
+	sm.AddAsset(gpu)
+
+	sm.Start()
+	defer sm.Finish()
+
+	assert.Eventually(t,
+		func() bool { return len(sm.GetBuffer()["gpu.0.temp"]) >= 3 },
+		5*time.Second,
+		10*time.Millisecond,
+	)
+}
+
 func TestSystemMonitor_RepeatedCalls(t *testing.T) {
 	sm := newTestSystemMonitor()
 
@@ -130,3 +232,121 @@ func TestSystemMonitor_FullCycle(t *testing.T) {
 	sm.Finish()
 	assert.Equal(t, monitor.StateStopped, sm.GetState())
 }
+
+// countingAsset counts how often it is sampled.
+type countingAsset struct {
+	name     string
+	interval time.Duration
+	samples  atomic.Int32
+}
+
+func (a *countingAsset) Name() string { return a.name }
+
+func (a *countingAsset) Sample() (map[string]any, error) {
+	a.samples.Add(1)
+	return map[string]any{a.name: 1.0}, nil
+}
+
+func (a *countingAsset) IsAvailable() bool { return true }
+
+func (a *countingAsset) Probe() *spb.MetadataRequest { return nil }
+
+func (a *countingAsset) SampleInterval() time.Duration { return a.interval }
+
+func TestSystemMonitor_PerAssetSamplingInterval(t *testing.T) {
+	sm := monitor.NewSystemMonitor(
+		observability.NewNoOpLogger(),
+		&spb.Settings{
+			XDisableStats:          wrapperspb.Bool(true),
+			XStatsSamplingInterval: wrapperspb.Double(0.03),
+		},
+		runworktest.New(),
+	)
+	fast := &countingAsset{name: "fast"}
+	slow := &countingAsset{name: "slow", interval: 90 * time.Millisecond}
+	sm.AddAsset(fast)
+	sm.AddAsset(slow)
+
+	sm.Start()
+	time.Sleep(1800 * time.Millisecond)
+	sm.Finish()
+
+	// Over 1.8s, the fast asset should be sampled about 60 times and the
+	// slow one about 20 times.
+	ratio := float64(fast.samples.Load()) / float64(slow.samples.Load())
+	assert.InDelta(t, 3.0, ratio, 0.6)
+}
+
+// stuckRunWork never accepts work, like a run that's backed up when the
+// process is asked to stop.
+type stuckRunWork struct{}
+
+func (stuckRunWork) AddWork(runwork.Work) {}
+
+func (stuckRunWork) AddWorkOrCancel(done <-chan struct{}, _ runwork.Work) {
+	<-done
+}
+
+func (stuckRunWork) BeforeEndCtx() context.Context { return context.Background() }
+
+// startStuckSystemMonitor starts a monitor whose samples never reach the
+// run and waits until it has taken one.
+func startStuckSystemMonitor(
+	t *testing.T,
+	flushTo func(map[string]float64),
+) *monitor.SystemMonitor {
+	t.Helper()
+	sm := monitor.NewSystemMonitor(
+		observability.NewNoOpLogger(),
+		&spb.Settings{
+			XDisableStats:          wrapperspb.Bool(true),
+			XStatsSamplingInterval: wrapperspb.Double(0.01),
+		},
+		stuckRunWork{},
+	)
+	asset := &countingAsset{name: "gpu.0.temp"}
+	sm.AddAsset(asset)
+	if flushTo != nil {
+		sm.FlushTo(flushTo)
+	}
+
+	sm.Start()
+	assert.Eventually(t,
+		func() bool { return asset.samples.Load() > 0 },
+		time.Second, time.Millisecond)
+	return sm
+}
+
+func TestSystemMonitor_FlushFinal(t *testing.T) {
+	sm := startStuckSystemMonitor(t, nil)
+
+	assert.Nil(t, sm.FlushFinal())
+	sm.Finish()
+
+	assert.Equal(t, map[string]float64{"gpu.0.temp": 1.0}, sm.FlushFinal())
+	assert.Nil(t, sm.FlushFinal())
+}
+
+func TestSystemMonitor_FlushTo(t *testing.T) {
+	var flushed map[string]float64
+	sm := startStuckSystemMonitor(t, func(final map[string]float64) {
+		flushed = final
+	})
+
+	sm.Finish()
+
+	assert.Equal(t, map[string]float64{"gpu.0.temp": 1.0}, flushed)
+	assert.Nil(t, sm.FlushFinal())
+}
+
+func TestSystemMonitor_FlushFinal_NothingPending(t *testing.T) {
+	flushed := false
+	sm := newTestSystemMonitor()
+	sm.FlushTo(func(map[string]float64) { flushed = true })
+
+	sm.Start()
+	sm.Finish()
+
+	assert.Nil(t, sm.FlushFinal())
+	assert.False(t, flushed)
+}
@@ -1,14 +1,31 @@
 package monitor
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
 	"github.com/shirou/gopsutil/v4/net"
 	spb "github.com/wandb/wandb/core/pkg/service_go_proto"
 )
 
+// loopbackInterface matches the names of loopback interfaces on Linux
+// ("lo") and macOS ("lo0").
+var loopbackInterface = regexp.MustCompile(`^lo\d*$`)
+
 type Network struct {
 	name     string
 	sentInit int
 	recvInit int
+
+	// ExcludeLoopback excludes loopback interfaces from the transfer rates.
+	ExcludeLoopback bool
+
+	// I/O counters per interface from the previous sample, used to compute
+	// transfer rates.
+	lastIO     map[string]net.IOCountersStat
+	lastIOTime time.Time
 }
 
 func NewNetwork() *Network {
@@ -20,6 +37,10 @@ func NewNetwork() *Network {
 		nw.recvInit = int(netIOCounters[0].BytesRecv)
 	}
 
+	if perInterface, err := net.IOCounters(true); err == nil {
+		nw.IORates(perInterface, time.Now())
+	}
+
 	return nw
 }
 
@@ -34,9 +55,77 @@ func (n *Network) Sample() (map[string]any, error) {
 	metrics["network.sent"] = float64(int(netIOCounters[0].BytesSent) - n.sentInit)
 	metrics["network.recv"] = float64(int(netIOCounters[0].BytesRecv) - n.recvInit)
 
+	perInterface, err := net.IOCounters(true)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range n.IORates(perInterface, time.Now()) {
+		metrics[k] = v
+	}
+
 	return metrics, nil
 }
 
+// IORates returns the send and receive rates since the previous call, both
+// per interface and summed over all interfaces, and records the counters
+// for the next call.
+//
+// Interfaces that appeared since the previous call have no rate until the
+// next call. If a counter went backwards, because it wrapped around or was
+// reset, its rate is reported as zero.
+func (n *Network) IORates(
+	ioCounters []net.IOCountersStat,
+	now time.Time,
+) map[string]any {
+	metrics := make(map[string]any)
+	current := make(map[string]net.IOCountersStat, len(ioCounters))
+
+	elapsed := now.Sub(n.lastIOTime).Seconds()
+	var sentTotal, recvTotal float64
+	for _, curr := range ioCounters {
+		if n.ExcludeLoopback && isLoopback(curr.Name) {
+			continue
+		}
+		current[curr.Name] = curr
+
+		prev, ok := n.lastIO[curr.Name]
+		if !ok || elapsed <= 0 {
+			continue
+		}
+
+		sent := counterDelta(prev.BytesSent, curr.BytesSent) / elapsed
+		recv := counterDelta(prev.BytesRecv, curr.BytesRecv) / elapsed
+		metrics[fmt.Sprintf("network.%s.sentBytesPerSec", curr.Name)] = sent
+		metrics[fmt.Sprintf("network.%s.recvBytesPerSec", curr.Name)] = recv
+		sentTotal += sent
+		recvTotal += recv
+	}
+
+	if len(metrics) > 0 {
+		metrics["network.sentBytesPerSec"] = sentTotal
+		metrics["network.recvBytesPerSec"] = recvTotal
+	}
+
+	n.lastIO = current
+	n.lastIOTime = now
+	return metrics
+}
+
+// counterDelta returns how much a counter increased, or zero if it was
+// reset or wrapped around.
+func counterDelta(prev, curr uint64) float64 {
+	if curr < prev {
+		return 0
+	}
+	return float64(curr - prev)
+}
+
+// isLoopback reports whether the named interface is a loopback interface.
+func isLoopback(name string) bool {
+	return loopbackInterface.MatchString(name) ||
+		strings.HasPrefix(strings.ToLower(name), "loopback")
+}
+
 func (n *Network) IsAvailable() bool { return true }
 
 func (n *Network) Probe() *spb.MetadataRequest {
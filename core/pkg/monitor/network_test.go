@@ -0,0 +1,78 @@
+package monitor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/stretchr/testify/assert"
+	"github.com/wandb/wandb/core/pkg/monitor"
+)
+
+func TestNetworkIORates(t *testing.T) {
+	n := &monitor.Network{}
+	start := time.Unix(1000, 0)
+
+	first := n.IORates(
+		[]net.IOCountersStat{
+			{Name: "eth0", BytesSent: 1000, BytesRecv: 2000},
+			{Name: "eth1", BytesSent: 5000, BytesRecv: 5000},
+		},
+		start,
+	)
+	assert.Empty(t, first)
+
+	second := n.IORates(
+		[]net.IOCountersStat{
+			{Name: "eth0", BytesSent: 3000, BytesRecv: 6000},
+			// eth1's counters were reset
+			{Name: "eth1", BytesSent: 10, BytesRecv: 10},
+		},
+		start.Add(2*time.Second),
+	)
+	assert.Equal(t,
+		map[string]any{
+			"network.eth0.sentBytesPerSec": 1000.0,
+			"network.eth0.recvBytesPerSec": 2000.0,
+			"network.eth1.sentBytesPerSec": 0.0,
+			"network.eth1.recvBytesPerSec": 0.0,
+			"network.sentBytesPerSec":      1000.0,
+			"network.recvBytesPerSec":      2000.0,
+		},
+		second,
+	)
+
+	third := n.IORates(
+		[]net.IOCountersStat{
+			{Name: "eth0", BytesSent: 3000, BytesRecv: 6000},
+			{Name: "eth1", BytesSent: 110, BytesRecv: 10},
+		},
+		start.Add(3*time.Second),
+	)
+	assert.Equal(t, 100.0, third["network.eth1.sentBytesPerSec"])
+	assert.Equal(t, 100.0, third["network.sentBytesPerSec"])
+}
+
+func TestNetworkIORatesExcludeLoopback(t *testing.T) {
+	n := &monitor.Network{ExcludeLoopback: true}
+	start := time.Unix(1000, 0)
+
+	n.IORates(
+		[]net.IOCountersStat{
+			{Name: "lo", BytesSent: 0, BytesRecv: 0},
+			{Name: "eth0", BytesSent: 0, BytesRecv: 0},
+		},
+		start,
+	)
+	rates := n.IORates(
+		[]net.IOCountersStat{
+			{Name: "lo", BytesSent: 1000, BytesRecv: 1000},
+			{Name: "eth0", BytesSent: 10, BytesRecv: 20},
+		},
+		start.Add(time.Second),
+	)
+
+	assert.NotContains(t, rates, "network.lo.sentBytesPerSec")
+	assert.Equal(t, 10.0, rates["network.sentBytesPerSec"])
+	assert.Equal(t, 20.0, rates["network.recvBytesPerSec"])
+}
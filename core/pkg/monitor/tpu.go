@@ -8,11 +8,14 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/wandb/wandb/core/pkg/monitor/tpuproto"
 	spb "github.com/wandb/wandb/core/pkg/service_go_proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/local"
+	"google.golang.org/grpc/status"
 )
 
 // TPUMetricName represents a TPU metric name for querying on the gRPC server exposed by the TPU runtime.
@@ -27,6 +30,10 @@ const (
 	// See https://github.com/google/cloud-accelerator-diagnostics/tree/main/tpu_info for more details.
 	grpcAddr = "localhost:8431"
 
+	// tpuMetricsTimeout limits how long a request to the TPU runtime's
+	// metrics server may take.
+	tpuMetricsTimeout = 5 * time.Second
+
 	// TPUTotalMemory is the total High Bandwidth Memory in bytes.
 	TPUTotalMemory TPUMetricName = "tpu.runtime.hbm.memory.total.bytes"
 	// TPUMemoryUsage is the current High Bandwidth Memory usage in bytes.
@@ -107,6 +114,12 @@ func (t *TPU) SetClient(client RuntimeMetricServiceClient) {
 }
 
 // Sample returns TPU metrics such as memory usage in % and in bytes, and duty cycle.
+//
+// The TPU runtime serves metrics only while a program uses the TPU, so
+// there are none before the program initializes it, like when a run
+// starts before JAX does. If the metrics server can't be reached, no
+// metrics are returned rather than an error, which would stop monitoring
+// for the rest of the run.
 func (t *TPU) Sample() (map[string]any, error) {
 	if t.client == nil || t.chip == nil {
 		return nil, nil
@@ -114,16 +127,25 @@ func (t *TPU) Sample() (map[string]any, error) {
 
 	// Total memory per TPU core [bytes]
 	totals, err := t.getMetrics(TPUTotalMemory)
+	if isTPURuntimeUnreachable(err) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 	// Memory usage per TPU core [bytes]
 	usages, err := t.getMetrics(TPUMemoryUsage)
+	if isTPURuntimeUnreachable(err) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 	// Duty cycle per TPU device [%]
 	dutyCycles, err := t.getMetrics(TPUDutyCyclePct)
+	if isTPURuntimeUnreachable(err) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -152,8 +174,9 @@ func (t *TPU) Sample() (map[string]any, error) {
 	for _, duty := range dutyCycles {
 		chipID := duty.GetAttribute().GetValue().GetIntAttr()
 		dutyCycle := duty.GetGauge().GetAsDouble()
-		dutyCyclesPerCore[chipID*int64(t.chip.DevicesPerChip)] = dutyCycle
-		dutyCyclesPerCore[chipID*int64(t.chip.DevicesPerChip)+1] = dutyCycle
+		for core := range int64(t.chip.DevicesPerChip) {
+			dutyCyclesPerCore[chipID*int64(t.chip.DevicesPerChip)+core] = dutyCycle
+		}
 	}
 
 	data := make(map[string]any)
@@ -178,11 +201,14 @@ func (t *TPU) Sample() (map[string]any, error) {
 		memoryUsageKey := fmt.Sprintf("%s.%d.memoryUsage", t.Name(), deviceID)
 		// Memory usage [bytes]
 		memoryUsageBytesKey := fmt.Sprintf("%s.%d.memoryUsageBytes", t.Name(), deviceID)
+		// Total memory [bytes]
+		memoryTotalBytesKey := fmt.Sprintf("%s.%d.memoryTotalBytes", t.Name(), deviceID)
 		// Duty cycle [%]
 		dutyCycleKey := fmt.Sprintf("%s.%d.dutyCycle", t.Name(), deviceID)
 
 		data[memoryUsageKey] = float64(memoryUsage) / float64(totalMemory) * 100
 		data[memoryUsageBytesKey] = memoryUsage
+		data[memoryTotalBytesKey] = totalMemory
 		data[dutyCycleKey] = dutyCycle
 	}
 
@@ -286,7 +312,9 @@ func tpuChipFromPCIDeviceID(deviceID, subsystemID string) (*TPUChip, error) {
 func (t *TPU) getMetrics(metricName TPUMetricName) ([]*tpuproto.Metric, error) {
 	req := &tpuproto.MetricRequest{MetricName: string(metricName)}
 
-	resp, err := t.client.GetRuntimeMetric(context.Background(), req)
+	ctx, cancel := context.WithTimeout(context.Background(), tpuMetricsTimeout)
+	defer cancel()
+	resp, err := t.client.GetRuntimeMetric(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -295,6 +323,17 @@ func (t *TPU) getMetrics(metricName TPUMetricName) ([]*tpuproto.Metric, error) {
 	return metrics, nil
 }
 
+// isTPURuntimeUnreachable returns whether err means that the TPU runtime's
+// metrics server isn't running or didn't respond.
+func isTPURuntimeUnreachable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
 // Probe returns the TPU metadata.
 func (t *TPU) Probe() *spb.MetadataRequest {
 	if t.chip == nil {
@@ -7,9 +7,13 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/wandb/wandb/core/pkg/monitor"
 	"github.com/wandb/wandb/core/pkg/monitor/tpuproto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type mockRuntimeMetricServiceClient struct {
@@ -115,3 +119,85 @@ func TestTPUSample(t *testing.T) {
 		t.Errorf("Expected duty cycle 75.0, got %v", data[expectedDutyCycleKey])
 	}
 }
+
+// intAttrGauge returns a TPU metric for the device or chip with the given
+// ID.
+func intAttrGauge(id int64, gauge *tpuproto.Gauge) *tpuproto.Metric {
+	return &tpuproto.Metric{
+		Attribute: &tpuproto.Attribute{
+			Value: &tpuproto.AttrValue{
+				Attr: &tpuproto.AttrValue_IntAttr{IntAttr: id},
+			},
+		},
+		Measure: &tpuproto.Metric_Gauge{Gauge: gauge},
+	}
+}
+
+func intGauge(value int64) *tpuproto.Gauge {
+	return &tpuproto.Gauge{Value: &tpuproto.Gauge_AsInt{AsInt: value}}
+}
+
+func doubleGauge(value float64) *tpuproto.Gauge {
+	return &tpuproto.Gauge{Value: &tpuproto.Gauge_AsDouble{AsDouble: value}}
+}
+
+func TestTPUSample_MultipleDevicesPerChip(t *testing.T) {
+	mockClient := &mockRuntimeMetricServiceClient{
+		metrics: map[monitor.TPUMetricName][]*tpuproto.Metric{
+			monitor.TPUTotalMemory: {
+				intAttrGauge(0, intGauge(16e9)),
+				intAttrGauge(1, intGauge(16e9)),
+				intAttrGauge(2, intGauge(16e9)),
+				intAttrGauge(3, intGauge(16e9)),
+			},
+			monitor.TPUMemoryUsage: {
+				intAttrGauge(0, intGauge(4e9)),
+				intAttrGauge(1, intGauge(8e9)),
+				intAttrGauge(2, intGauge(12e9)),
+				intAttrGauge(3, intGauge(16e9)),
+			},
+			monitor.TPUDutyCyclePct: {
+				intAttrGauge(0, doubleGauge(30)),
+				intAttrGauge(1, doubleGauge(60)),
+			},
+		},
+	}
+	tpu := &monitor.TPU{}
+	tpu.SetName("tpu")
+	tpu.SetClient(mockClient)
+	tpu.SetChip(&monitor.TPUChip{Name: "v4", HbmGiB: 32, DevicesPerChip: 2}, 4)
+
+	data, err := tpu.Sample()
+
+	require.NoError(t, err)
+	assert.Equal(t, 30.0, data["tpu.1.dutyCycle"])
+	assert.Equal(t, 60.0, data["tpu.2.dutyCycle"])
+	assert.Equal(t, 60.0, data["tpu.3.dutyCycle"])
+	assert.Equal(t, int64(12e9), data["tpu.2.memoryUsageBytes"])
+	assert.Equal(t, int64(16e9), data["tpu.2.memoryTotalBytes"])
+	assert.Equal(t, 75.0, data["tpu.2.memoryUsage"])
+}
+
+// unreachableRuntimeMetricServiceClient fails like the TPU runtime's
+// metrics server before a program uses the TPU.
+type unreachableRuntimeMetricServiceClient struct{}
+
+func (unreachableRuntimeMetricServiceClient) GetRuntimeMetric(
+	ctx context.Context,
+	in *tpuproto.MetricRequest,
+	opts ...grpc.CallOption,
+) (*tpuproto.MetricResponse, error) {
+	return nil, status.Error(codes.Unavailable, "connection refused")
+}
+
+func TestTPUSample_RuntimeUnreachable(t *testing.T) {
+	tpu := &monitor.TPU{}
+	tpu.SetName("tpu")
+	tpu.SetClient(unreachableRuntimeMetricServiceClient{})
+	tpu.SetChip(&monitor.TPUChip{Name: "v4", HbmGiB: 32, DevicesPerChip: 2}, 4)
+
+	data, err := tpu.Sample()
+
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+}
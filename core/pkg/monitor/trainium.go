@@ -4,8 +4,10 @@ package monitor
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -115,8 +117,8 @@ func NewTrainium(
 		shutdownEvent:           make(chan struct{}),
 	}
 
-	// check if the neuron-monitor command is available
-	if _, err := getNeuronMonitorCmdPath(); err != nil {
+	// check if the neuron-monitor command and devices are available
+	if !t.IsAvailable() {
 		return nil
 	}
 
@@ -214,27 +216,56 @@ func (t *Trainium) Start() error {
 	t.SetRunningState(true)
 
 	go func() {
-		scanner := bufio.NewScanner(stdout)
-		for {
-			select {
-			case <-t.shutdownEvent:
-				return
-			default:
-				if scanner.Scan() {
-					rawStats := make(map[string]any)
-					if err := json.Unmarshal(scanner.Bytes(), &rawStats); err != nil {
-						t.logger.CaptureError(fmt.Errorf("trainium: failed to parse JSON: %v", err))
-						continue
-					}
-					t.SetRawStats(rawStats)
-				}
-			}
+		if err := t.ReadStats(stdout); err != nil {
+			t.logger.CaptureError(fmt.Errorf("trainium: failed to read neuron-monitor output: %v", err))
 		}
 	}()
 
 	return nil
 }
 
+// maxNeuronMonitorLineSize is the longest line of neuron-monitor output
+// that can be parsed.
+//
+// Each line is a full report, which grows with the number of devices and
+// runtimes, so it can exceed bufio.Scanner's default limit.
+const maxNeuronMonitorLineSize = 16 * 1024 * 1024
+
+// ReadStats reads neuron-monitor's output, one JSON report per line, and
+// keeps the latest report as the raw stats.
+//
+// It returns when the output ends or the monitor is closed. Blank and
+// malformed lines are skipped, since the output may be interrupted
+// mid-line, for example when neuron-monitor restarts.
+func (t *Trainium) ReadStats(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNeuronMonitorLineSize)
+
+	for scanner.Scan() {
+		select {
+		case <-t.shutdownEvent:
+			return nil
+		default:
+		}
+
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		rawStats := make(map[string]any)
+		if err := json.Unmarshal(line, &rawStats); err != nil {
+			if t.logger != nil {
+				t.logger.Warn("trainium: skipping malformed neuron-monitor output", "error", err)
+			}
+			continue
+		}
+		t.SetRawStats(rawStats)
+	}
+
+	return scanner.Err()
+}
+
 // isMatchingEntry checks if an entry in neuronRuntimeData should be saved.
 //
 // Checks if the pid in the entry matches the pid of the process.
@@ -430,8 +461,26 @@ func (t *Trainium) Name() string {
 	return t.name
 }
 
+// IsAvailable returns whether neuron-monitor is installed and there are
+// Neuron devices to monitor.
+//
+// The Neuron tools may be installed on hosts without Trainium or
+// Inferentia devices, like when using a Neuron machine image on a CPU
+// instance.
 func (t *Trainium) IsAvailable() bool {
-	_, err := getNeuronMonitorCmdPath()
+	if _, err := getNeuronMonitorCmdPath(); err != nil {
+		return false
+	}
+	return neuronDevicesPresent()
+}
+
+// neuronDevicesPresent returns whether the Neuron driver exposes any
+// devices, in /dev or in sysfs.
+func neuronDevicesPresent() bool {
+	if devices, _ := filepath.Glob("/dev/neuron[0-9]*"); len(devices) > 0 {
+		return true
+	}
+	_, err := os.Stat("/sys/devices/virtual/neuron_device")
 	return err == nil
 }
 
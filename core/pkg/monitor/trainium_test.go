@@ -3,9 +3,12 @@
 package monitor_test
 
 import (
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/wandb/wandb/core/pkg/monitor"
 )
 
@@ -72,3 +75,37 @@ func TestTrainiumSample(t *testing.T) {
 		assert.True(t, len(key) > 4 && key[:4] == "trn.")
 	}
 }
+
+func TestTrainiumReadStats_NeuronMonitorOutput(t *testing.T) {
+	t.Setenv("LOCAL_RANK", "0")
+	output, err := os.Open("testdata/neuron_monitor_output.jsonl")
+	require.NoError(t, err)
+	defer output.Close()
+	trainium := monitor.Trainium{}
+
+	// The captured output has a blank line and truncated reports, which
+	// are skipped.
+	require.NoError(t, trainium.ReadStats(output))
+	trainium.SetRunningState(true)
+	sample, err := trainium.Sample()
+
+	require.NoError(t, err)
+	assert.Equal(t, 87.5, sample["trn.0.neuroncore_utilization"])
+	assert.NotContains(t, sample, "trn.1.neuroncore_utilization",
+		"only the local rank's core is reported")
+	assert.Equal(t, float64(620000000), sample["trn.host_total_memory_usage"])
+	assert.Equal(t, float64(204596656), sample["trn.neuron_device_total_memory_usage"])
+	assert.Equal(t, float64(202250688), sample["trn.0.neuroncore_memory_usage.model_code"])
+	assert.Equal(t, float64(943608), sample["trn.0.neuroncore_memory_usage.tensors"])
+}
+
+func TestTrainiumReadStats_NoValidReports(t *testing.T) {
+	trainium := monitor.Trainium{}
+
+	require.NoError(t, trainium.ReadStats(strings.NewReader("not json\n{\"a\":\n")))
+	trainium.SetRunningState(true)
+	sample, err := trainium.Sample()
+
+	assert.NoError(t, err)
+	assert.Empty(t, sample)
+}